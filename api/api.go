@@ -1,69 +1,126 @@
 package api
 
 import (
-	"database/sql"
+	"context"
+	"errors"
 	"log"
+	"net"
 	"net/http"
+	"time"
 
-	"github.com/HappYness-Project/ChatBackendServer/common"
+	"github.com/HappYness-Project/ChatBackendServer/dbs"
 	chatRepo "github.com/HappYness-Project/ChatBackendServer/internal/chat/repository"
 	chatRoute "github.com/HappYness-Project/ChatBackendServer/internal/chat/route"
+	"github.com/HappYness-Project/ChatBackendServer/internal/linkpreview"
 	messageRepo "github.com/HappYness-Project/ChatBackendServer/internal/message/repository"
 	messageRoute "github.com/HappYness-Project/ChatBackendServer/internal/message/route"
+	"github.com/HappYness-Project/ChatBackendServer/internal/storage"
 
 	"github.com/HappYness-Project/ChatBackendServer/loggers"
 	"github.com/go-chi/chi/v5"
 )
 
+// shutdownTimeout is how long Run waits for in-flight requests once the server context is cancelled.
+const shutdownTimeout = 10 * time.Second
+
 type ApiServer struct {
-	addr      string
-	secretKey string
-	db        *sql.DB
-	logger    *loggers.AppLogger
+	addr           string
+	secretKey      string
+	webhookSecret  string
+	chatRepo       chatRepo.ChatRepository
+	messageRepo    messageRepo.MessageRepository
+	unitOfWork     dbs.UnitOfWork
+	database       DatabaseMonitor
+	blobStore      storage.BlobStore
+	linkFetcher    linkpreview.Fetcher
+	requestTimeout time.Duration
+	logger         *loggers.AppLogger
 }
 
-func NewApiServer(addr string, secretKey string, db *sql.DB, logger *loggers.AppLogger) *ApiServer {
+// NewApiServer serves the given repositories, the Postgres ones or the in-memory ones for running without a database.
+// unitOfWork makes operations spanning both repositories atomic and must belong to the same store. database is
+// nil for the in-memory store.
+func NewApiServer(addr string, secretKey string, webhookSecret string, chatRepository chatRepo.ChatRepository,
+	messageRepository messageRepo.MessageRepository, unitOfWork dbs.UnitOfWork, database DatabaseMonitor, blobStore storage.BlobStore, linkFetcher linkpreview.Fetcher,
+	requestTimeout time.Duration, logger *loggers.AppLogger) *ApiServer {
 
 	return &ApiServer{
-		addr:      addr,
-		secretKey: secretKey,
-		db:        db,
-		logger:    logger,
+		addr:           addr,
+		secretKey:      secretKey,
+		webhookSecret:  webhookSecret,
+		chatRepo:       chatRepository,
+		messageRepo:    messageRepository,
+		unitOfWork:     unitOfWork,
+		database:       database,
+		blobStore:      blobStore,
+		linkFetcher:    linkFetcher,
+		requestTimeout: requestTimeout,
+		logger:         logger,
 	}
 }
 
-func (s *ApiServer) Setup() *chi.Mux {
+// Setup builds the router. Background jobs and websocket connections run until ctx is cancelled.
+func (s *ApiServer) Setup(ctx context.Context) *chi.Mux {
 	mux := chi.NewRouter()
 
-	msgRepo := messageRepo.NewRepository(s.db)
-	chatRepo := chatRepo.NewRepository(s.db)
+	wsManager := messageRoute.NewWebSocketManager(s.logger)
+	msgHandler := messageRoute.NewHandler(ctx, s.logger, s.messageRepo, s.chatRepo, s.unitOfWork, wsManager, s.blobStore, s.linkFetcher, s.secretKey,
+		s.requestTimeout)
+	chatHandler := chatRoute.NewHandler(s.logger, s.chatRepo, s.messageRepo, s.unitOfWork, wsManager, s.secretKey, s.webhookSecret)
 
 	mux.Get("/", Home)
-	mux.Get("/health", Home)
-	msgHandler := messageRoute.NewHandler(s.logger, *msgRepo, *chatRepo, s.secretKey)
-	chatHandler := chatRoute.NewHandler(s.logger, *chatRepo, s.secretKey)
-
+	mux.Get("/health", s.Health)
+	mux.Get("/healthz", Liveness)
+	mux.Get("/readyz", s.Readiness(msgHandler, wsManager))
+	msgHandler.RegisterStreamingRoutes(mux)
 	mux.Group(func(r chi.Router) {
+		r.Use(withTimeout(s.requestTimeout))
 		msgHandler.RegisterRoutes(r)
 		chatHandler.RegisterRoutes(r)
 	})
-	go msgHandler.HandleMessages()
 	return mux
 }
 
-func (s *ApiServer) Run(mux *chi.Mux) error {
-	log.Println("Listening on ", s.addr)
-	return http.ListenAndServe(s.addr, mux)
+// Run serves until ctx is cancelled and then shuts down gracefully. Request contexts derive from ctx, so
+// queries still running are cancelled as well.
+func (s *ApiServer) Run(ctx context.Context, mux *chi.Mux) error {
+	server := &http.Server{
+		Addr:        s.addr,
+		Handler:     mux,
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		log.Println("Listening on ", s.addr)
+		serveErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
 }
-func Home(w http.ResponseWriter, r *http.Request) {
-	var payload = struct {
-		Status  string `json:"status"`
-		Message string `json:"message"`
-		Version string `json:"version"`
-	}{
-		Status:  "active",
-		Message: "Message Service server",
-		Version: "1.0.0",
+
+// withTimeout bounds the context of each request, so its queries stop once the client goes away or the
+// timeout passes.
+func withTimeout(timeout time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
 	}
-	common.WriteJsonWithEncode(w, http.StatusOK, payload)
 }
@@ -0,0 +1,152 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/HappYness-Project/ChatBackendServer/buildinfo"
+	"github.com/HappYness-Project/ChatBackendServer/common"
+	"github.com/HappYness-Project/ChatBackendServer/dbs"
+	messageRoute "github.com/HappYness-Project/ChatBackendServer/internal/message/route"
+)
+
+// readinessTimeout bounds the checks of one readiness probe, a database that answers slower counts as down.
+const readinessTimeout = 2 * time.Second
+
+// DatabaseMonitor reports on the database behind the repositories for the health endpoints.
+type DatabaseMonitor interface {
+	Ping(ctx context.Context) error
+	SchemaVersion(ctx context.Context) (applied int64, latest int64, err error)
+	PoolStats() dbs.PoolStats
+}
+
+// Broadcaster is the loop storing and sending the messages posted by websocket clients.
+type Broadcaster interface {
+	Broadcasting() bool
+}
+
+// ConnectionCounter counts the open websocket connections.
+type ConnectionCounter interface {
+	Stats() messageRoute.ConnectionStats
+}
+
+// Home keeps reporting the API version, the build of the binary is reported by the health endpoints.
+func Home(w http.ResponseWriter, r *http.Request) {
+	var payload = struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+		Version string `json:"version"`
+	}{
+		Status:  "active",
+		Message: "Message Service server",
+		Version: "1.0.0",
+	}
+	common.WriteJsonWithEncode(w, http.StatusOK, payload)
+}
+
+// Health reports the server as active, with the state of the database connection pool when there is one.
+func (s *ApiServer) Health(w http.ResponseWriter, r *http.Request) {
+	var payload = struct {
+		Status       string         `json:"status"`
+		Message      string         `json:"message"`
+		Version      string         `json:"version"`
+		DatabasePool *dbs.PoolStats `json:"database_pool,omitempty"`
+	}{
+		Status:  "active",
+		Message: "Message Service server",
+		Version: buildinfo.Version,
+	}
+	if s.database != nil {
+		stats := s.database.PoolStats()
+		payload.DatabasePool = &stats
+	}
+	common.WriteJsonWithEncode(w, http.StatusOK, payload)
+}
+
+// Liveness answers as long as the process serves requests. It checks no dependency, a database outage must not
+// get the server restarted.
+func Liveness(w http.ResponseWriter, r *http.Request) {
+	common.WriteJsonWithEncode(w, http.StatusOK, struct {
+		Status string         `json:"status"`
+		Build  buildinfo.Info `json:"build"`
+	}{
+		Status: "alive",
+		Build:  buildinfo.Get(),
+	})
+}
+
+type check struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type databaseCheck struct {
+	check
+	Store            string         `json:"store"`
+	MigrationVersion *int64         `json:"migration_version,omitempty"`
+	LatestMigration  *int64         `json:"latest_migration,omitempty"`
+	Pool             *dbs.PoolStats `json:"pool,omitempty"`
+}
+
+type readinessReport struct {
+	Status      string                       `json:"status"`
+	Build       buildinfo.Info               `json:"build"`
+	Database    databaseCheck                `json:"database"`
+	Broadcaster check                        `json:"broadcaster"`
+	Connections messageRoute.ConnectionStats `json:"connections"`
+}
+
+// Readiness reports whether the server can take traffic: the database answers and has every migration the binary
+// knows of, and the broadcaster runs. It answers 503 otherwise, so load balancers stop routing to the instance.
+func (s *ApiServer) Readiness(broadcaster Broadcaster, connections ConnectionCounter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+		defer cancel()
+
+		report := readinessReport{
+			Status:      "ready",
+			Build:       buildinfo.Get(),
+			Database:    s.checkDatabase(ctx),
+			Broadcaster: check{Status: "up"},
+			Connections: connections.Stats(),
+		}
+		if !broadcaster.Broadcasting() {
+			report.Broadcaster = check{Status: "down", Error: "the broadcaster is not running"}
+		}
+
+		status := http.StatusOK
+		if report.Database.Status != "up" || report.Broadcaster.Status != "up" {
+			report.Status = "not ready"
+			status = http.StatusServiceUnavailable
+		}
+		common.WriteJsonWithEncode(w, status, report)
+	}
+}
+
+func (s *ApiServer) checkDatabase(ctx context.Context) databaseCheck {
+	if s.database == nil {
+		return databaseCheck{check: check{Status: "up"}, Store: "memory"}
+	}
+
+	result := databaseCheck{check: check{Status: "up"}, Store: "postgres"}
+	stats := s.database.PoolStats()
+	result.Pool = &stats
+	if err := s.database.Ping(ctx); err != nil {
+		s.logger.Error().Err(err).Msg("Readiness check could not reach the database")
+		result.check = check{Status: "down", Error: "the database is unreachable"}
+		return result
+	}
+
+	applied, latest, err := s.database.SchemaVersion(ctx)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Readiness check could not read the migration version")
+		result.check = check{Status: "down", Error: "the migration version could not be read"}
+		return result
+	}
+	result.MigrationVersion, result.LatestMigration = &applied, &latest
+	if applied < latest {
+		result.check = check{Status: "down", Error: "the database has pending migrations"}
+	}
+	return result
+}
@@ -0,0 +1,21 @@
+package buildinfo
+
+import "runtime"
+
+// Set at link time by the Makefile and the Dockerfile, for example
+// -ldflags "-X github.com/HappYness-Project/ChatBackendServer/buildinfo.Version=$(git rev-parse --short HEAD)".
+var (
+	Version   = "dev"
+	BuildTime = "unknown"
+)
+
+// Info describes the running binary in the health endpoints.
+type Info struct {
+	Version   string `json:"version"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+}
+
+func Get() Info {
+	return Info{Version: Version, BuildTime: BuildTime, GoVersion: runtime.Version()}
+}
@@ -0,0 +1,52 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var ErrMissingToken = errors.New("missing jwt token")
+
+// TokenFromRequest returns the bearer token from the Authorization header,
+// falling back to the "token" query parameter used by websocket clients.
+func TokenFromRequest(r *http.Request) string {
+	if authHeader := r.Header.Get("Authorization"); authHeader != "" {
+		if token, ok := strings.CutPrefix(authHeader, "Bearer "); ok {
+			return strings.TrimSpace(token)
+		}
+	}
+	return r.URL.Query().Get("token")
+}
+
+// UserIdFromToken validates an HS512 signed token and returns the user it was issued for.
+// The user id is read from the "user_id" claim, then from the standard "sub" claim.
+func UserIdFromToken(tokenString string, secret []byte) (string, error) {
+	if tokenString == "" {
+		return "", ErrMissingToken
+	}
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if token.Method != jwt.SigningMethodHS512 {
+			return nil, fmt.Errorf("unexpected signing method: %v, expected HS512", token.Header["alg"])
+		}
+		return secret, nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return "", errors.New("invalid jwt token claims")
+	}
+	if userId, ok := claims["user_id"].(string); ok && userId != "" {
+		return userId, nil
+	}
+	if sub, err := claims.GetSubject(); err == nil && sub != "" {
+		return sub, nil
+	}
+	return "", errors.New("jwt token does not contain a user id")
+}
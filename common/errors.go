@@ -0,0 +1,133 @@
+package common
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Kinds of failures shared by the domain and repository layers. Errors wrap one of them so WriteError can pick
+// the response status without the handlers knowing every error that a call may return.
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrGone         = errors.New("gone")
+	ErrForbidden    = errors.New("forbidden")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrValidation   = errors.New("validation failed")
+)
+
+// AppError is a failure of one of the kinds above, with the error code and detail reported to clients.
+// Err keeps the underlying error for logging, it is never sent to the client.
+type AppError struct {
+	Kind   error
+	Code   string
+	Detail string
+	Err    error
+}
+
+func NotFound(code, detail string) *AppError {
+	return &AppError{Kind: ErrNotFound, Code: code, Detail: detail}
+}
+
+func Conflict(code, detail string) *AppError {
+	return &AppError{Kind: ErrConflict, Code: code, Detail: detail}
+}
+
+func Gone(code, detail string) *AppError {
+	return &AppError{Kind: ErrGone, Code: code, Detail: detail}
+}
+
+func Forbidden(code, detail string) *AppError {
+	return &AppError{Kind: ErrForbidden, Code: code, Detail: detail}
+}
+
+func Unauthorized(code, detail string) *AppError {
+	return &AppError{Kind: ErrUnauthorized, Code: code, Detail: detail}
+}
+
+func Invalid(code, detail string) *AppError {
+	return &AppError{Kind: ErrValidation, Code: code, Detail: detail}
+}
+
+func (e *AppError) Error() string {
+	if e.Err != nil {
+		return e.Detail + ": " + e.Err.Error()
+	}
+	return e.Detail
+}
+
+func (e *AppError) ErrorCode() string {
+	return e.Code
+}
+
+func (e *AppError) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Kind}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+// Is matches errors with the same kind and code, so a sentinel still matches after Wrap added a cause.
+func (e *AppError) Is(target error) bool {
+	other, ok := target.(*AppError)
+	return ok && other.Kind == e.Kind && other.Code == e.Code
+}
+
+// Wrap returns a copy of the error caused by err.
+func (e *AppError) Wrap(err error) *AppError {
+	wrapped := *e
+	wrapped.Err = err
+	return &wrapped
+}
+
+// StatusCode maps the kind of err onto an HTTP status, errors of no known kind are internal errors.
+func StatusCode(err error) int {
+	switch {
+	case errors.Is(err, ErrValidation):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, ErrGone):
+		return http.StatusGone
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// ProblemFromError builds the problem details for err. The error code is taken from the first error in the
+// chain that has one. Internal errors get a generic detail, their message may expose queries or infrastructure.
+func ProblemFromError(err error) (int, ProblemDetails) {
+	status := StatusCode(err)
+	problem := ProblemDetails{Title: http.StatusText(status)}
+	if status == http.StatusInternalServerError {
+		problem.ErrorCode = "InternalError"
+		problem.Detail = "An unexpected error occurred"
+		return status, problem
+	}
+
+	var appErr *AppError
+	var coded interface{ ErrorCode() string }
+	switch {
+	case errors.As(err, &appErr):
+		problem.ErrorCode = appErr.Code
+		problem.Detail = appErr.Detail
+	case errors.As(err, &coded):
+		problem.ErrorCode = coded.ErrorCode()
+		problem.Detail = err.Error()
+	default:
+		problem.Detail = err.Error()
+	}
+	return status, problem
+}
+
+// WriteError answers the request with the problem details of err.
+func WriteError(w http.ResponseWriter, err error) {
+	status, problem := ProblemFromError(err)
+	ErrorResponse(w, status, problem)
+}
@@ -0,0 +1,39 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+var validate = validator.New(validator.WithRequiredStructEnabled())
+
+// ValidateStruct checks the `validate` tags of a request struct.
+func ValidateStruct(v any) error {
+	return validate.Struct(v)
+}
+
+// ValidationErrorDetail turns validator errors into a short message suitable for ProblemDetails.Detail.
+func ValidationErrorDetail(err error) string {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return err.Error()
+	}
+
+	messages := make([]string, 0, len(validationErrors))
+	for _, fieldErr := range validationErrors {
+		switch fieldErr.Tag() {
+		case "required":
+			messages = append(messages, fmt.Sprintf("%s is required", fieldErr.Field()))
+		case "oneof":
+			messages = append(messages, fmt.Sprintf("%s must be one of: %s", fieldErr.Field(), fieldErr.Param()))
+		case "min", "max":
+			messages = append(messages, fmt.Sprintf("%s must satisfy %s=%s", fieldErr.Field(), fieldErr.Tag(), fieldErr.Param()))
+		default:
+			messages = append(messages, fmt.Sprintf("%s is not a valid %s", fieldErr.Field(), fieldErr.Tag()))
+		}
+	}
+	return strings.Join(messages, "; ")
+}
@@ -3,16 +3,21 @@ package configs
 import (
 	"log"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/spf13/viper"
 )
 
+// defaultContextTimeout applies when CONTEXT_TIMEOUT is not set.
+const defaultContextTimeout = 10 * time.Second
+
 type Env struct {
 	AppEnv         string `mapstructure:"APP_ENV"`
 	ServerAddress  string `mapstructure:"SERVER_ADDRESS"`
 	Port           string `mapstructure:"PORT"`
 	ContextTimeout int    `mapstructure:"CONTEXT_TIMEOUT"`
-	LogLevel       string `mapstructure:"LOG_LEVEL`
+	LogLevel       string `mapstructure:"LOG_LEVEL"`
 	Host           string `mapstructure:"HOST"`
 
 	DBHost string `mapstructure:"DB_HOST"`
@@ -20,9 +25,31 @@ type Env struct {
 	DBUser string `mapstructure:"DB_USER"`
 	DBPwd  string `mapstructure:"DB_PWD"`
 	DBName string `mapstructure:"DB_NAME"`
+	// DataStore is "postgres" (the default) or "memory", which keeps all data in process and needs no database.
+	DataStore string `mapstructure:"DATA_STORE"`
+	// Migrations run at startup unless skipped, seeding loads the development data afterwards.
+	DBSkipMigrations bool `mapstructure:"DB_SKIP_MIGRATIONS"`
+	DBSeed           bool `mapstructure:"DB_SEED"`
+	// Connection pool settings, unset ones get the defaults of package dbs. Lifetimes are in seconds.
+	DBMaxOpenConns    int `mapstructure:"DB_MAX_OPEN_CONNS"`
+	DBMaxIdleConns    int `mapstructure:"DB_MAX_IDLE_CONNS"`
+	DBConnMaxLifetime int `mapstructure:"DB_CONN_MAX_LIFETIME"`
+	DBConnMaxIdleTime int `mapstructure:"DB_CONN_MAX_IDLE_TIME"`
+	DBConnectAttempts int `mapstructure:"DB_CONNECT_ATTEMPTS"`
 
 	AccessTokenSecret  string `mapstructure:"ACCESS_TOKEN_SECRET"`
 	RefreshTokenSecret string `mapstructure:"REFRESH_TOKEN_SECRET"`
+	WebhookSecret      string `mapstructure:"WEBHOOK_SECRET"`
+
+	BlobStore    string `mapstructure:"BLOB_STORE"`
+	BlobLocalDir string `mapstructure:"BLOB_LOCAL_DIR"`
+	S3Endpoint   string `mapstructure:"S3_ENDPOINT"`
+	S3Region     string `mapstructure:"S3_REGION"`
+	S3Bucket     string `mapstructure:"S3_BUCKET"`
+	S3AccessKey  string `mapstructure:"S3_ACCESS_KEY"`
+	S3SecretKey  string `mapstructure:"S3_SECRET_KEY"`
+
+	LinkPreviews string `mapstructure:"LINK_PREVIEWS"`
 }
 
 func InitConfig(envString string) Env {
@@ -37,13 +64,31 @@ func InitConfig(envString string) Env {
 		env.AppEnv = envString
 		env.Host = "0.0.0.0"
 		env.Port = os.Getenv("PORT")
+		env.ContextTimeout, _ = strconv.Atoi(os.Getenv("CONTEXT_TIMEOUT"))
 		env.DBHost = os.Getenv("DB_HOST")
 		env.DBName = os.Getenv("DB_NAME")
 		env.DBPort = os.Getenv("DB_PORT")
 		env.DBUser = os.Getenv("DB_USER")
 		env.DBPwd = os.Getenv("DB_PWD")
+		env.DataStore = os.Getenv("DATA_STORE")
+		env.DBSkipMigrations = os.Getenv("DB_SKIP_MIGRATIONS") == "true"
+		env.DBSeed = os.Getenv("DB_SEED") == "true"
+		env.DBMaxOpenConns, _ = strconv.Atoi(os.Getenv("DB_MAX_OPEN_CONNS"))
+		env.DBMaxIdleConns, _ = strconv.Atoi(os.Getenv("DB_MAX_IDLE_CONNS"))
+		env.DBConnMaxLifetime, _ = strconv.Atoi(os.Getenv("DB_CONN_MAX_LIFETIME"))
+		env.DBConnMaxIdleTime, _ = strconv.Atoi(os.Getenv("DB_CONN_MAX_IDLE_TIME"))
+		env.DBConnectAttempts, _ = strconv.Atoi(os.Getenv("DB_CONNECT_ATTEMPTS"))
 		env.AccessTokenSecret = os.Getenv("ACCESS_TOKEN_SECRET")
 		env.RefreshTokenSecret = os.Getenv("ACCESS_TOKEN_SECRET")
+		env.WebhookSecret = os.Getenv("WEBHOOK_SECRET")
+		env.BlobStore = os.Getenv("BLOB_STORE")
+		env.BlobLocalDir = os.Getenv("BLOB_LOCAL_DIR")
+		env.S3Endpoint = os.Getenv("S3_ENDPOINT")
+		env.S3Region = os.Getenv("S3_REGION")
+		env.S3Bucket = os.Getenv("S3_BUCKET")
+		env.S3AccessKey = os.Getenv("S3_ACCESS_KEY")
+		env.S3SecretKey = os.Getenv("S3_SECRET_KEY")
+		env.LinkPreviews = os.Getenv("LINK_PREVIEWS")
 		return env
 	}
 	err := viper.ReadInConfig()
@@ -61,3 +106,11 @@ func InitConfig(envString string) Env {
 	}
 	return env
 }
+
+// RequestTimeout bounds the database work of one request or background operation. CONTEXT_TIMEOUT is in seconds.
+func (e Env) RequestTimeout() time.Duration {
+	if e.ContextTimeout <= 0 {
+		return defaultContextTimeout
+	}
+	return time.Duration(e.ContextTimeout) * time.Second
+}
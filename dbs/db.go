@@ -1,33 +1,149 @@
 package dbs
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 	"log"
+	"time"
 
 	_ "github.com/jackc/pgconn"
 	_ "github.com/jackc/pgx/v5"
 	_ "github.com/jackc/pgx/v5/stdlib"
 )
 
-func openDb(dsn string) (*sql.DB, error) {
-	db, err := sql.Open("pgx", dsn)
-	if err != nil {
-		return nil, err
+// Pool defaults, used for the settings of PoolConfig left at zero.
+const (
+	defaultMaxOpenConns    = 20
+	defaultMaxIdleConns    = 10
+	defaultConnMaxLifetime = 30 * time.Minute
+	defaultConnMaxIdleTime = 5 * time.Minute
+	defaultConnectAttempts = 5
+
+	initialConnectBackoff = 500 * time.Millisecond
+	maxConnectBackoff     = 10 * time.Second
+)
+
+// PoolConfig sizes the connection pool and bounds how long connections are reused.
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+	// ConnectAttempts is how often connecting is tried before giving up. The wait between attempts doubles
+	// after each failure, so the server can start before the database is up.
+	ConnectAttempts int
+}
+
+func (c PoolConfig) withDefaults() PoolConfig {
+	if c.MaxOpenConns <= 0 {
+		c.MaxOpenConns = defaultMaxOpenConns
 	}
+	if c.MaxIdleConns <= 0 {
+		c.MaxIdleConns = min(defaultMaxIdleConns, c.MaxOpenConns)
+	}
+	if c.ConnMaxLifetime <= 0 {
+		c.ConnMaxLifetime = defaultConnMaxLifetime
+	}
+	if c.ConnMaxIdleTime <= 0 {
+		c.ConnMaxIdleTime = defaultConnMaxIdleTime
+	}
+	if c.ConnectAttempts <= 0 {
+		c.ConnectAttempts = defaultConnectAttempts
+	}
+	return c
+}
 
-	err = db.Ping()
+// Connect opens a pool configured by config and waits until the database answers, retrying with backoff
+// until the attempts run out or ctx is cancelled.
+func Connect(ctx context.Context, connStr string, config PoolConfig) (*sql.DB, error) {
+	config = config.withDefaults()
+	db, err := sql.Open("pgx", connStr)
 	if err != nil {
 		return nil, err
 	}
+	db.SetMaxOpenConns(config.MaxOpenConns)
+	db.SetMaxIdleConns(config.MaxIdleConns)
+	db.SetConnMaxLifetime(config.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(config.ConnMaxIdleTime)
+
+	backoff := initialConnectBackoff
+	attempt := 1
+	for {
+		if err = db.PingContext(ctx); err == nil {
+			log.Println("Connected to Postgres")
+			return db, nil
+		}
+		if attempt == config.ConnectAttempts || ctx.Err() != nil {
+			break
+		}
+		log.Printf("Unable to connect to Postgres (attempt %d of %d), retrying in %s: %v", attempt, config.ConnectAttempts, backoff, err)
 
-	return db, nil
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			err = ctx.Err()
+		case <-timer.C:
+			attempt++
+			backoff = min(2*backoff, maxConnectBackoff)
+			continue
+		}
+		break
+	}
+	db.Close()
+	return nil, fmt.Errorf("connecting to Postgres failed after %d attempts: %w", attempt, err)
 }
 
+// ConnectToDb opens a pool with the default settings, trying to connect once.
 func ConnectToDb(connStr string) (*sql.DB, error) {
-	conn, err := openDb(connStr)
-	if err != nil {
-		return nil, err
+	return Connect(context.Background(), connStr, PoolConfig{ConnectAttempts: 1})
+}
+
+// PoolStats is a snapshot of the connection pool reported by the health endpoint.
+type PoolStats struct {
+	MaxOpenConnections int   `json:"max_open_connections"`
+	OpenConnections    int   `json:"open_connections"`
+	InUse              int   `json:"in_use"`
+	Idle               int   `json:"idle"`
+	WaitCount          int64 `json:"wait_count"`
+	WaitDurationMs     int64 `json:"wait_duration_ms"`
+	MaxIdleClosed      int64 `json:"max_idle_closed"`
+	MaxIdleTimeClosed  int64 `json:"max_idle_time_closed"`
+	MaxLifetimeClosed  int64 `json:"max_lifetime_closed"`
+}
+
+// Monitor reports on the database behind the repositories.
+type Monitor struct {
+	db       *sql.DB
+	migrator *Migrator
+}
+
+func NewMonitor(db *sql.DB, migrator *Migrator) *Monitor {
+	return &Monitor{db: db, migrator: migrator}
+}
+
+// Ping checks that a connection to the database can be used.
+func (m *Monitor) Ping(ctx context.Context) error {
+	return m.db.PingContext(ctx)
+}
+
+// SchemaVersion returns the applied and the latest known migration version, see Migrator.SchemaVersion.
+func (m *Monitor) SchemaVersion(ctx context.Context) (applied int64, latest int64, err error) {
+	return m.migrator.SchemaVersion(ctx)
+}
+
+func (m *Monitor) PoolStats() PoolStats {
+	stats := m.db.Stats()
+	return PoolStats{
+		MaxOpenConnections: stats.MaxOpenConnections,
+		OpenConnections:    stats.OpenConnections,
+		InUse:              stats.InUse,
+		Idle:               stats.Idle,
+		WaitCount:          stats.WaitCount,
+		WaitDurationMs:     stats.WaitDuration.Milliseconds(),
+		MaxIdleClosed:      stats.MaxIdleClosed,
+		MaxIdleTimeClosed:  stats.MaxIdleTimeClosed,
+		MaxLifetimeClosed:  stats.MaxLifetimeClosed,
 	}
-	log.Println("Connected to Postgres")
-	return conn, nil
 }
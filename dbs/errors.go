@@ -0,0 +1,42 @@
+package dbs
+
+import (
+	"errors"
+
+	"github.com/HappYness-Project/ChatBackendServer/common"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// SQLSTATE codes of the violations that are caused by the request rather than by the database.
+const (
+	invalidTextRepresentation = "22P02"
+	notNullViolation          = "23502"
+	foreignKeyViolation       = "23503"
+	uniqueViolation           = "23505"
+	checkViolation            = "23514"
+)
+
+var (
+	ErrDuplicate         = common.Conflict("DuplicateResource", "The resource already exists")
+	ErrReferenceNotFound = common.Conflict("ReferenceViolation", "The resource refers to or is referred to by a missing or existing resource")
+	ErrInvalidValue      = common.Invalid("InvalidValue", "A value is not valid for the resource")
+)
+
+// TranslateError turns constraint violations reported by Postgres into the error kinds of package common, so they
+// are answered as conflicts or bad requests instead of internal errors. Other errors are returned unchanged.
+func TranslateError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+	switch pgErr.Code {
+	case uniqueViolation:
+		return ErrDuplicate.Wrap(err)
+	case foreignKeyViolation:
+		return ErrReferenceNotFound.Wrap(err)
+	case invalidTextRepresentation, notNullViolation, checkViolation:
+		return ErrInvalidValue.Wrap(err)
+	default:
+		return err
+	}
+}
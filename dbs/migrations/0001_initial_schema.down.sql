@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS public.message_attachment;
+DROP TABLE IF EXISTS public.scheduled_message;
+DROP TABLE IF EXISTS public.message_pin;
+DROP TABLE IF EXISTS public.chat_invitation;
+DROP TABLE IF EXISTS public.chat_participant;
+DROP TABLE IF EXISTS public.chat;
+DROP TABLE IF EXISTS public.message_mention;
+DROP TABLE IF EXISTS public.message_reaction;
+DROP TABLE IF EXISTS public.link_preview;
+DROP TABLE IF EXISTS public.message;
+DROP FUNCTION IF EXISTS public.uuid_generate_v7();
@@ -0,0 +1,185 @@
+-- Baseline schema. Statements are idempotent so databases created from the former create_tables.sql init script
+-- can adopt it: its message, chat and chat_participant tables are kept and get the columns added since.
+
+CREATE EXTENSION IF NOT EXISTS "uuid-ossp" SCHEMA public; -- To use uuid_generate_v7 custom function.
+
+-- Creating uuid generate v7 method - this feature is not implemented in postgres 17 yet.
+-- Will be implemented in 18.
+create or replace function public.uuid_generate_v7()
+returns uuid
+as $$
+begin
+  -- use random v4 uuid as starting point (which has the same variant we need)
+  -- then overlay timestamp
+  -- then set version 7 by flipping the 2 and 1 bit in the version 4 string
+  return encode(
+    set_bit(
+      set_bit(
+        overlay(uuid_send(gen_random_uuid())
+                placing substring(int8send(floor(extract(epoch from clock_timestamp()) * 1000)::bigint) from 3)
+                from 1 for 6
+        ),
+        52, 1
+      ),
+      53, 1
+    ),
+    'hex')::uuid;
+end
+$$
+language plpgsql
+volatile;
+
+-- Messages table - stores the actual chat messages
+CREATE TABLE IF NOT EXISTS public.message (
+    id UUID PRIMARY KEY DEFAULT public.uuid_generate_v7(),
+    chat_id UUID NOT NULL,
+    sender_id UUID NOT NULL,
+    content TEXT NOT NULL,
+    message_type VARCHAR(20) NOT NULL DEFAULT 'text' CHECK (message_type IN ('text', 'image', 'video', 'audio', 'file')),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    read_status BOOLEAN DEFAULT FALSE,
+    reply_to_id UUID REFERENCES public.message(id) ON DELETE SET NULL,
+    thread_root_id UUID REFERENCES public.message(id) ON DELETE CASCADE,
+    thread_reply_count INTEGER NOT NULL DEFAULT 0,
+    thread_last_reply_at TIMESTAMP WITH TIME ZONE,
+    mentions JSONB NOT NULL DEFAULT '[]'::jsonb,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    link_previews JSONB NOT NULL DEFAULT '[]'::jsonb,
+    content_tsv TSVECTOR GENERATED ALWAYS AS (to_tsvector('english', content)) STORED
+);
+
+ALTER TABLE public.message
+    ADD COLUMN IF NOT EXISTS reply_to_id UUID REFERENCES public.message(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS thread_root_id UUID REFERENCES public.message(id) ON DELETE CASCADE,
+    ADD COLUMN IF NOT EXISTS thread_reply_count INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS thread_last_reply_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN IF NOT EXISTS mentions JSONB NOT NULL DEFAULT '[]'::jsonb,
+    ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN IF NOT EXISTS link_previews JSONB NOT NULL DEFAULT '[]'::jsonb,
+    ADD COLUMN IF NOT EXISTS content_tsv TSVECTOR GENERATED ALWAYS AS (to_tsvector('english', content)) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_message_content_tsv ON public.message USING GIN (content_tsv);
+-- Messages sent with a TTL, looked up by the retention purge
+CREATE INDEX IF NOT EXISTS idx_message_expires_at ON public.message (expires_at) WHERE expires_at IS NOT NULL;
+
+-- Fetched link previews keyed by URL. found is false when the page had nothing to preview, so misses are cached too.
+CREATE TABLE IF NOT EXISTS public.link_preview (
+    url TEXT PRIMARY KEY,
+    found BOOLEAN NOT NULL,
+    title TEXT NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    image_url TEXT NOT NULL DEFAULT '',
+    site_name TEXT NOT NULL DEFAULT '',
+    fetched_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Emoji reactions, one row per user and emoji on a message
+CREATE TABLE IF NOT EXISTS public.message_reaction (
+    message_id UUID NOT NULL REFERENCES public.message(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL,
+    emoji VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT pk_message_reaction PRIMARY KEY (message_id, user_id, emoji)
+);
+
+-- Users notified by a message, mentioned directly or through @all / @admins
+CREATE TABLE IF NOT EXISTS public.message_mention (
+    message_id UUID NOT NULL REFERENCES public.message(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL,
+    mention_type VARCHAR(10) NOT NULL CHECK (mention_type IN ('user', 'all', 'admins')),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT pk_message_mention PRIMARY KEY (message_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_message_mention_user ON public.message_mention (user_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS public.chat (
+    id uuid NOT NULL,
+    type CHARACTER VARYING(20) NOT NULL CHECK (type IN ('private', 'group', 'container')),
+    usergroup_id bigint,
+    container_id uuid,
+    name CHARACTER VARYING(100) NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    avatar_url TEXT NOT NULL DEFAULT '',
+    settings JSONB NOT NULL DEFAULT '{}'::jsonb,
+    created_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT pk_chat PRIMARY KEY (id)
+);
+
+ALTER TABLE public.chat
+    ADD COLUMN IF NOT EXISTS name CHARACTER VARYING(100) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS description TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS avatar_url TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS settings JSONB NOT NULL DEFAULT '{}'::jsonb;
+
+CREATE TABLE IF NOT EXISTS public.chat_participant (
+    id UUID PRIMARY KEY DEFAULT public.uuid_generate_v7(),
+    chat_id UUID NOT NULL REFERENCES public.chat(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL,
+    joined_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    role VARCHAR(10) CHECK (role IN ('admin', 'member')) DEFAULT 'member',
+    status VARCHAR(20) NOT NULL DEFAULT 'active'
+        CHECK (status IN ('active', 'left', 'banned', 'muted', 'pending')),
+    left_at TIMESTAMP WITH TIME ZONE
+);
+
+ALTER TABLE public.chat_participant ADD COLUMN IF NOT EXISTS left_at TIMESTAMP WITH TIME ZONE;
+
+-- Invitation links for joining a chat. Redeeming one creates a pending participant awaiting admin approval.
+CREATE TABLE IF NOT EXISTS public.chat_invitation (
+    id UUID PRIMARY KEY DEFAULT public.uuid_generate_v7(),
+    chat_id UUID NOT NULL REFERENCES public.chat(id) ON DELETE CASCADE,
+    token VARCHAR(64) NOT NULL UNIQUE,
+    created_by UUID NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    max_uses INTEGER CHECK (max_uses IS NULL OR max_uses > 0),
+    uses INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    revoked_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Pinned messages per chat, listed by position
+CREATE TABLE IF NOT EXISTS public.message_pin (
+    chat_id UUID NOT NULL REFERENCES public.chat(id) ON DELETE CASCADE,
+    message_id UUID NOT NULL REFERENCES public.message(id) ON DELETE CASCADE,
+    pinned_by UUID NOT NULL,
+    pinned_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    position INTEGER NOT NULL,
+    CONSTRAINT pk_message_pin PRIMARY KEY (chat_id, message_id)
+);
+
+-- Messages composed for later delivery. The delivered message reuses the id of its schedule.
+CREATE TABLE IF NOT EXISTS public.scheduled_message (
+    id UUID PRIMARY KEY DEFAULT public.uuid_generate_v7(),
+    chat_id UUID NOT NULL REFERENCES public.chat(id) ON DELETE CASCADE,
+    sender_id UUID NOT NULL,
+    content TEXT NOT NULL,
+    message_type VARCHAR(20) NOT NULL DEFAULT 'text',
+    reply_to_id UUID,
+    thread_root_id UUID,
+    send_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'sending', 'sent', 'cancelled', 'failed')),
+    claimed_at TIMESTAMP WITH TIME ZONE,
+    failure_reason TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_message_due ON public.scheduled_message (send_at) WHERE status IN ('pending', 'sending');
+
+-- Uploaded files. The bytes live in the blob store under storage_key, message_id is set once a message references the upload.
+CREATE TABLE IF NOT EXISTS public.message_attachment (
+    id UUID PRIMARY KEY DEFAULT public.uuid_generate_v7(),
+    chat_id UUID NOT NULL REFERENCES public.chat(id) ON DELETE CASCADE,
+    message_id UUID REFERENCES public.message(id) ON DELETE CASCADE,
+    uploader_id UUID NOT NULL,
+    storage_key TEXT NOT NULL UNIQUE,
+    file_name VARCHAR(255) NOT NULL,
+    content_type VARCHAR(255) NOT NULL,
+    size_bytes BIGINT NOT NULL CHECK (size_bytes > 0),
+    checksum_sha256 CHAR(64) NOT NULL,
+    width INTEGER,
+    height INTEGER,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
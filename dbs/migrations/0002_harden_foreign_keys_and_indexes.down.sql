@@ -0,0 +1,14 @@
+DROP INDEX IF EXISTS public.idx_scheduled_message_sender;
+DROP INDEX IF EXISTS public.idx_message_pin_message;
+DROP INDEX IF EXISTS public.idx_message_attachment_chat;
+DROP INDEX IF EXISTS public.idx_message_attachment_message;
+DROP INDEX IF EXISTS public.idx_chat_invitation_chat;
+DROP INDEX IF EXISTS public.idx_chat_usergroup;
+DROP INDEX IF EXISTS public.idx_chat_participant_user;
+DROP INDEX IF EXISTS public.idx_message_reply_to;
+DROP INDEX IF EXISTS public.idx_message_thread_root;
+DROP INDEX IF EXISTS public.idx_message_chat_created;
+
+ALTER TABLE public.chat_participant DROP CONSTRAINT IF EXISTS uq_chat_participant_chat_user;
+ALTER TABLE public.message ALTER COLUMN created_at DROP NOT NULL;
+ALTER TABLE public.message DROP CONSTRAINT IF EXISTS fk_message_chat;
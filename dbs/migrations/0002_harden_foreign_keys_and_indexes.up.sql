@@ -0,0 +1,52 @@
+-- Messages belong to their chat and are deleted with it, like participants, invitations and attachments.
+-- Messages of chats that no longer exist are unreachable and removed before adding the constraint.
+DELETE FROM public.message m
+WHERE NOT EXISTS (SELECT 1 FROM public.chat c WHERE c.id = m.chat_id);
+
+ALTER TABLE public.message
+    ADD CONSTRAINT fk_message_chat FOREIGN KEY (chat_id) REFERENCES public.chat(id) ON DELETE CASCADE;
+
+UPDATE public.message SET created_at = CURRENT_TIMESTAMP WHERE created_at IS NULL;
+ALTER TABLE public.message ALTER COLUMN created_at SET NOT NULL;
+
+-- One participant row per user and chat; leaving and rejoining updates the row in place. Duplicates keep the
+-- row that restricts the user most (banned), then the most privileged one, then the oldest.
+DELETE FROM public.chat_participant cp
+USING (
+    SELECT id, ROW_NUMBER() OVER (
+        PARTITION BY chat_id, user_id
+        ORDER BY CASE status
+                WHEN 'banned' THEN 0
+                WHEN 'active' THEN 1
+                WHEN 'muted' THEN 2
+                WHEN 'pending' THEN 3
+                ELSE 4
+            END,
+            joined_at ASC
+    ) AS rank
+    FROM public.chat_participant
+) ranked
+WHERE cp.id = ranked.id AND ranked.rank > 1;
+
+ALTER TABLE public.chat_participant
+    ADD CONSTRAINT uq_chat_participant_chat_user UNIQUE (chat_id, user_id);
+
+-- Chat timeline and retention purge
+CREATE INDEX IF NOT EXISTS idx_message_chat_created ON public.message (chat_id, created_at);
+-- Thread listings, reply count recomputation and the thread_root_id cascade
+CREATE INDEX IF NOT EXISTS idx_message_thread_root ON public.message (thread_root_id, created_at) WHERE thread_root_id IS NOT NULL;
+-- The reply_to_id SET NULL on delete
+CREATE INDEX IF NOT EXISTS idx_message_reply_to ON public.message (reply_to_id) WHERE reply_to_id IS NOT NULL;
+
+-- Chats of a user, used by the user group and search queries
+CREATE INDEX IF NOT EXISTS idx_chat_participant_user ON public.chat_participant (user_id);
+CREATE INDEX IF NOT EXISTS idx_chat_usergroup ON public.chat (usergroup_id) WHERE usergroup_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_chat_invitation_chat ON public.chat_invitation (chat_id);
+
+CREATE INDEX IF NOT EXISTS idx_message_attachment_message ON public.message_attachment (message_id) WHERE message_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_message_attachment_chat ON public.message_attachment (chat_id);
+CREATE INDEX IF NOT EXISTS idx_message_pin_message ON public.message_pin (message_id);
+CREATE INDEX IF NOT EXISTS idx_scheduled_message_sender ON public.scheduled_message (chat_id, sender_id) WHERE status = 'pending';
+
+-- scheduled_message.reply_to_id and thread_root_id stay without foreign keys on purpose: a deleted target makes
+-- the delivery fail with a reason shown to the sender, instead of silently dropping or re-threading the message.
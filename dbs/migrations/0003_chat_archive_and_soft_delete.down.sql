@@ -0,0 +1,6 @@
+-- Chats pending purge would become visible again, so they are removed for good before dropping the column.
+DELETE FROM public.chat WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS public.idx_chat_deleted_at;
+ALTER TABLE public.chat DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE public.chat DROP COLUMN IF EXISTS archived_at;
@@ -0,0 +1,6 @@
+-- Archived chats are read-only and hidden from listings. Deleted chats can be restored until the purge job
+-- removes them, together with everything cascading from the chat, once the restore window has passed.
+ALTER TABLE public.chat ADD COLUMN IF NOT EXISTS archived_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE public.chat ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_chat_deleted_at ON public.chat (deleted_at) WHERE deleted_at IS NOT NULL;
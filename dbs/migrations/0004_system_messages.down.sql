@@ -0,0 +1,5 @@
+DELETE FROM public.message WHERE message_type = 'system';
+
+ALTER TABLE public.message DROP CONSTRAINT IF EXISTS message_message_type_check;
+ALTER TABLE public.message ADD CONSTRAINT message_message_type_check
+    CHECK (message_type IN ('text', 'image', 'video', 'audio', 'file'));
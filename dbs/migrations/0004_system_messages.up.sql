@@ -0,0 +1,5 @@
+-- System messages record changes to a chat, like members leaving, in its timeline. They are posted by the server
+-- only, clients cannot send the type.
+ALTER TABLE public.message DROP CONSTRAINT IF EXISTS message_message_type_check;
+ALTER TABLE public.message ADD CONSTRAINT message_message_type_check
+    CHECK (message_type IN ('text', 'image', 'video', 'audio', 'file', 'system'));
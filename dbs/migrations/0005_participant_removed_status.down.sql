@@ -0,0 +1,5 @@
+UPDATE public.chat_participant SET status = 'left', left_at = COALESCE(left_at, now()) WHERE status = 'removed';
+
+ALTER TABLE public.chat_participant DROP CONSTRAINT IF EXISTS chat_participant_status_check;
+ALTER TABLE public.chat_participant ADD CONSTRAINT chat_participant_status_check
+    CHECK (status IN ('active', 'left', 'banned', 'muted', 'pending'));
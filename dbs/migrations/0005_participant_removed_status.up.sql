@@ -0,0 +1,5 @@
+-- Removed members and rejected join requests keep their participant row with the 'removed' status instead of
+-- being deleted.
+ALTER TABLE public.chat_participant DROP CONSTRAINT IF EXISTS chat_participant_status_check;
+ALTER TABLE public.chat_participant ADD CONSTRAINT chat_participant_status_check
+    CHECK (status IN ('active', 'left', 'banned', 'muted', 'pending', 'removed'));
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS public.usergroup_event;
@@ -0,0 +1,13 @@
+-- User group events applied from the webhook. Redelivered events are recognised by their id, and events that
+-- arrive after newer ones for the same group or member by their occurred_at.
+CREATE TABLE IF NOT EXISTS public.usergroup_event (
+    event_id VARCHAR(255) PRIMARY KEY,
+    usergroup_id INTEGER NOT NULL,
+    -- NULL for events about the group itself.
+    user_id UUID,
+    type VARCHAR(40) NOT NULL,
+    occurred_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    processed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_usergroup_event_group ON public.usergroup_event (usergroup_id, user_id, occurred_at DESC);
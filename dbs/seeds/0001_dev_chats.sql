@@ -0,0 +1,29 @@
+-- Development chats and participants. Seeds run on every start when enabled, so they must be idempotent.
+INSERT INTO public.chat(id, type, usergroup_id, container_id, created_at) VALUES
+('01987073-0a87-7b32-9439-86868dfe9bd2', 'group', 1, NULL, CURRENT_TIMESTAMP),
+('01987073-cf13-7621-af36-54ce20056d18', 'group', 2, NULL, CURRENT_TIMESTAMP),
+('01987075-16cb-7337-af15-cd28f64c93a3', 'group', 3, NULL, CURRENT_TIMESTAMP),
+('01987074-1f7f-7aad-ad76-a4b83544fa2d', 'group', 4, NULL, CURRENT_TIMESTAMP),
+('01987074-440c-73f8-aa5b-ba2b50a19395', 'group', 5, NULL, CURRENT_TIMESTAMP)
+ON CONFLICT (id) DO NOTHING;
+
+INSERT INTO public.chat_participant(chat_id, user_id, role, status)
+SELECT seed.chat_id::uuid, seed.user_id::uuid, seed.role, seed.status
+FROM (VALUES
+    -- Chat 1 (User Group 1): kevin, macy, testing1 are members (based on usergroup_user table)
+    ('01987073-0a87-7b32-9439-86868dfe9bd2', '01959b38-b3f9-7ec5-8ac8-e353bfe08a2d', 'admin',  'active'),
+    ('01987073-0a87-7b32-9439-86868dfe9bd2', '01959b39-febd-770d-9e1b-e5ee392fce54', 'member', 'active'),
+    ('01987073-0a87-7b32-9439-86868dfe9bd2', '01959b3a-405b-7591-86dd-87174e2453fd', 'member', 'active'),
+    -- Chat 2 (User Group 2): testing2 is member
+    ('01987073-cf13-7621-af36-54ce20056d18', '0195c388-d0f4-77d5-be90-971d38344c74', 'admin', 'active'),
+    -- Chat 3 (User Group 3): kevin is member
+    ('01987075-16cb-7337-af15-cd28f64c93a3', '01959b38-b3f9-7ec5-8ac8-e353bfe08a2d', 'admin', 'active'),
+    -- Chat 4 (User Group 4): macy is member
+    ('01987074-1f7f-7aad-ad76-a4b83544fa2d', '01959b39-febd-770d-9e1b-e5ee392fce54', 'admin', 'active'),
+    -- Chat 5 (User Group 5): kevin is member
+    ('01987074-440c-73f8-aa5b-ba2b50a19395', '01959b38-b3f9-7ec5-8ac8-e353bfe08a2d', 'admin', 'active')
+) AS seed(chat_id, user_id, role, status)
+WHERE NOT EXISTS (
+    SELECT 1 FROM public.chat_participant cp
+    WHERE cp.chat_id = seed.chat_id::uuid AND cp.user_id = seed.user_id::uuid
+);
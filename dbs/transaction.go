@@ -0,0 +1,139 @@
+package dbs
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+)
+
+// Querier is the part of *sql.DB and *sql.Tx the repositories run their statements on.
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// UnitOfWork runs fn atomically. Repository calls made with the context passed to fn take part in the unit,
+// everything they wrote is rolled back when fn returns an error. Calling Do inside fn joins the running unit.
+type UnitOfWork interface {
+	Do(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type txKey struct{}
+
+// TxUnitOfWork runs each unit in a Postgres transaction.
+type TxUnitOfWork struct {
+	db *sql.DB
+}
+
+var _ UnitOfWork = (*TxUnitOfWork)(nil)
+
+func NewUnitOfWork(db *sql.DB) *TxUnitOfWork {
+	return &TxUnitOfWork{db: db}
+}
+
+func (u *TxUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+	tx, err := u.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Conn returns the transaction of the unit of work running in ctx, or db outside of one.
+func Conn(ctx context.Context, db *sql.DB) Querier {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	return db
+}
+
+// Tx is a transaction of a single repository method. Inside a unit of work it runs on the unit's transaction,
+// and Commit and Rollback are left to the unit.
+type Tx struct {
+	Querier
+	tx *sql.Tx
+}
+
+// BeginTx starts a transaction on db, or joins the unit of work running in ctx.
+func BeginTx(ctx context.Context, db *sql.DB) (*Tx, error) {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return &Tx{Querier: tx}, nil
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{Querier: tx, tx: tx}, nil
+}
+
+func (t *Tx) Commit() error {
+	if t.tx == nil {
+		return nil
+	}
+	return t.tx.Commit()
+}
+
+func (t *Tx) Rollback() error {
+	if t.tx == nil {
+		return nil
+	}
+	return t.tx.Rollback()
+}
+
+// Snapshotter is a store that can be put back to an earlier state, like the in-memory repositories.
+type Snapshotter interface {
+	// Snapshot copies the current state and returns a function restoring it.
+	Snapshot() (restore func())
+}
+
+type memoryUnitKey struct{}
+
+// MemoryUnitOfWork gives stores without transactions all-or-nothing units by restoring snapshots taken before
+// fn ran. Units run one at a time, but writes made outside of a unit while one runs are lost on rollback, so
+// it is meant for development and tests only.
+type MemoryUnitOfWork struct {
+	mu     sync.Mutex
+	stores []Snapshotter
+}
+
+var _ UnitOfWork = (*MemoryUnitOfWork)(nil)
+
+func NewMemoryUnitOfWork(stores ...Snapshotter) *MemoryUnitOfWork {
+	return &MemoryUnitOfWork{stores: stores}
+}
+
+func (u *MemoryUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(memoryUnitKey{}) == u {
+		return fn(ctx)
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	restores := make([]func(), len(u.stores))
+	for i, store := range u.stores {
+		restores[i] = store.Snapshot()
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			for _, restore := range restores {
+				restore()
+			}
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, memoryUnitKey{}, u)); err != nil {
+		return err
+	}
+	committed = true
+	return nil
+}
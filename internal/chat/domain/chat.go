@@ -4,6 +4,7 @@ import (
 	"errors"
 	"time"
 
+	"github.com/HappYness-Project/ChatBackendServer/common"
 	"github.com/google/uuid"
 )
 
@@ -15,6 +16,18 @@ const (
 	ChatTypeContainer ChatType = "container"
 )
 
+// ChatRestoreWindow is how long a deleted chat can be restored before it is purged for good.
+const ChatRestoreWindow = 30 * 24 * time.Hour
+
+var (
+	ErrChatNotFound         = common.NotFound("ChatNotFound", "Chat not found with the provided ID")
+	ErrDeletedChatNotFound  = common.NotFound("DeletedChatNotFound", "No deleted chat found with the provided ID")
+	ErrChatArchived         = common.Conflict("ChatArchived", "chat is archived")
+	ErrChatNotArchived      = common.Conflict("ChatNotArchived", "chat is not archived")
+	ErrChatNotDeleted       = common.Conflict("ChatNotDeleted", "chat is not deleted")
+	ErrRestoreWindowExpired = common.Gone("RestoreWindowExpired", "chat was deleted too long ago to be restored")
+)
+
 func (ct ChatType) String() string {
 	return string(ct)
 }
@@ -28,12 +41,25 @@ func (ct ChatType) IsValid() bool {
 	}
 }
 
+// ChatPurgeResult lists the chats a purge removed. StorageKeys are the blobs of their attachments, which the
+// caller deletes from storage as the rows referencing them are gone.
+type ChatPurgeResult struct {
+	ChatIDs     []string
+	StorageKeys []string
+}
+
 type Chat struct {
-	Id          string    `json:"id"`
-	Type        ChatType  `json:"type"`
-	UserGroupId *int      `json:"usergroup_id,omitempty"`
-	ContainerId *string   `json:"container_id,omitempty"`
-	CreatedAt   time.Time `json:"created_at"`
+	Id          string       `json:"id"`
+	Type        ChatType     `json:"type"`
+	UserGroupId *int         `json:"usergroup_id,omitempty"`
+	ContainerId *string      `json:"container_id,omitempty"`
+	Name        string       `json:"name"`
+	Description string       `json:"description,omitempty"`
+	AvatarUrl   string       `json:"avatar_url,omitempty"`
+	Settings    ChatSettings `json:"settings"`
+	CreatedAt   time.Time    `json:"created_at"`
+	ArchivedAt  *time.Time   `json:"archived_at,omitempty"`
+	DeletedAt   *time.Time   `json:"deleted_at,omitempty"`
 }
 
 func NewChat(chatType ChatType, userGroupId *int, containerId *string) (*Chat, error) {
@@ -55,6 +81,7 @@ func NewChat(chatType ChatType, userGroupId *int, containerId *string) (*Chat, e
 		Type:        chatType,
 		UserGroupId: userGroupId,
 		ContainerId: containerId,
+		Settings:    DefaultChatSettings(),
 		CreatedAt:   time.Now().UTC(),
 	}, nil
 }
@@ -94,3 +121,49 @@ func (c *Chat) IsPrivate() bool {
 func (c *Chat) IsContainer() bool {
 	return c.Type == ChatTypeContainer
 }
+
+func (c *Chat) IsArchived() bool {
+	return c.ArchivedAt != nil
+}
+
+func (c *Chat) IsDeleted() bool {
+	return c.DeletedAt != nil
+}
+
+// Archive makes the chat read-only and hides it from listings. Messages stay readable.
+func (c *Chat) Archive(now time.Time) error {
+	if c.IsArchived() {
+		return ErrChatArchived
+	}
+	c.ArchivedAt = &now
+	return nil
+}
+
+func (c *Chat) Unarchive() error {
+	if !c.IsArchived() {
+		return ErrChatNotArchived
+	}
+	c.ArchivedAt = nil
+	return nil
+}
+
+// RestorableUntil is when a deleted chat becomes eligible for purging.
+func (c *Chat) RestorableUntil() *time.Time {
+	if !c.IsDeleted() {
+		return nil
+	}
+	until := c.DeletedAt.Add(ChatRestoreWindow)
+	return &until
+}
+
+// Restore brings back a deleted chat as it was, which is only possible within the restore window.
+func (c *Chat) Restore(now time.Time) error {
+	if !c.IsDeleted() {
+		return ErrChatNotDeleted
+	}
+	if !now.Before(*c.RestorableUntil()) {
+		return ErrRestoreWindowExpired
+	}
+	c.DeletedAt = nil
+	return nil
+}
@@ -0,0 +1,89 @@
+package domain
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/HappYness-Project/ChatBackendServer/common"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvitationNotFound  = common.NotFound("InvitationNotFound", "Invitation not found with the provided token")
+	ErrInvitationExpired   = common.Gone("InvitationExpired", "invitation has expired")
+	ErrInvitationExhausted = common.Gone("InvitationExhausted", "invitation has reached its maximum number of uses")
+	ErrInvitationRevoked   = common.Gone("InvitationRevoked", "invitation has been revoked")
+)
+
+type ChatInvitation struct {
+	Id        string     `json:"id"`
+	ChatId    string     `json:"chat_id"`
+	Token     string     `json:"token"`
+	CreatedBy string     `json:"created_by"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	MaxUses   *int       `json:"max_uses,omitempty"`
+	Uses      int        `json:"uses"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// NewChatInvitation creates an invitation with a random url-safe token.
+// A nil expiresAt or maxUses means the invitation does not expire or is not limited.
+func NewChatInvitation(chatId, createdBy string, expiresAt *time.Time, maxUses *int) (*ChatInvitation, error) {
+	if chatId == "" {
+		return nil, fmt.Errorf("chat_id is required")
+	}
+	if createdBy == "" {
+		return nil, fmt.Errorf("created_by is required")
+	}
+	now := time.Now().UTC()
+	if expiresAt != nil && !expiresAt.After(now) {
+		return nil, fmt.Errorf("expires_at must be in the future")
+	}
+	if maxUses != nil && *maxUses <= 0 {
+		return nil, fmt.Errorf("max_uses must be greater than zero")
+	}
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+	token, err := generateInvitationToken()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ChatInvitation{
+		Id:        id.String(),
+		ChatId:    chatId,
+		Token:     token,
+		CreatedBy: createdBy,
+		ExpiresAt: expiresAt,
+		MaxUses:   maxUses,
+		CreatedAt: now,
+	}, nil
+}
+
+// CanBeRedeemed reports why the invitation cannot be used at the given time, if at all.
+func (ci *ChatInvitation) CanBeRedeemed(now time.Time) error {
+	if ci.RevokedAt != nil {
+		return ErrInvitationRevoked
+	}
+	if ci.ExpiresAt != nil && !ci.ExpiresAt.After(now) {
+		return ErrInvitationExpired
+	}
+	if ci.MaxUses != nil && ci.Uses >= *ci.MaxUses {
+		return ErrInvitationExhausted
+	}
+	return nil
+}
+
+func generateInvitationToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
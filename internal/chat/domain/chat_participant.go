@@ -3,6 +3,17 @@ package domain
 import (
 	"fmt"
 	"time"
+
+	"github.com/HappYness-Project/ChatBackendServer/common"
+)
+
+var (
+	ErrParticipantNotFound = common.NotFound("ParticipantNotFound", "User is not a participant of this chat")
+	ErrJoinRequestNotFound = common.NotFound("JoinRequestNotFound", "No pending join request found for the provided user")
+	ErrNotChatParticipant  = common.Forbidden("NotChatParticipant", "User is not an active participant of this chat")
+	ErrChatAdminRequired   = common.Forbidden("ChatAdminRequired", "Only chat admins can perform this action")
+	// ErrAlreadyParticipant is returned when adding a user that already has a participant row in the chat.
+	ErrAlreadyParticipant = common.Conflict("UserAlreadyParticipant", "User is already a participant in this chat")
 )
 
 type ChatParticipant struct {
@@ -12,6 +23,7 @@ type ChatParticipant struct {
 	JoinedAt time.Time         `json:"joined_at"`
 	Role     ParticipantRole   `json:"role"`
 	Status   ParticipantStatus `json:"status"`
+	LeftAt   *time.Time        `json:"left_at,omitempty"`
 }
 
 // NewChatParticipant creates a new ChatParticipant with validation
@@ -48,3 +60,48 @@ func (cp *ChatParticipant) ChangeStatus(newStatus string) error {
 	cp.Status = status
 	return nil
 }
+
+func (cp *ChatParticipant) IsAdmin() bool {
+	return cp.Role == RoleAdmin && cp.Status == StatusActive
+}
+
+func (cp *ChatParticipant) IsPending() bool {
+	return cp.Status == StatusPending
+}
+
+// Approve activates a pending participant, used when an admin accepts a join request.
+func (cp *ChatParticipant) Approve() error {
+	if !cp.IsPending() {
+		return fmt.Errorf("participant is not pending approval")
+	}
+	cp.Status = StatusActive
+	return nil
+}
+
+// Leave marks the participant as having left the chat at the given time. Muted participants cannot leave, as
+// rejoining would make them active again and lift the mute.
+func (cp *ChatParticipant) Leave(at time.Time) error {
+	if cp.Status != StatusActive {
+		return fmt.Errorf("participant with status %s cannot leave the chat", cp.Status)
+	}
+	cp.Status = StatusLeft
+	cp.LeftAt = &at
+	return nil
+}
+
+// Rejoin reactivates a participant that previously left the chat. Participants that were removed cannot rejoin.
+// Rejoining restores access to the whole history, including the messages posted while the participant was
+// away, the same as for members added to a chat after it started.
+func (cp *ChatParticipant) Rejoin() error {
+	if cp.Status != StatusLeft {
+		return fmt.Errorf("participant has not left the chat")
+	}
+	cp.Status = StatusActive
+	cp.LeftAt = nil
+	return nil
+}
+
+// CanReadMessages reports whether the participant may connect to the chat and read its history.
+func (cp *ChatParticipant) CanReadMessages() bool {
+	return cp.Status == StatusActive || cp.Status == StatusMuted
+}
@@ -0,0 +1,186 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+	"unicode/utf8"
+)
+
+type PermissionLevel string
+
+const (
+	PermissionMembers PermissionLevel = "members"
+	PermissionAdmins  PermissionLevel = "admins"
+)
+
+func (p PermissionLevel) IsValid() bool {
+	return p == PermissionMembers || p == PermissionAdmins
+}
+
+const (
+	MaxChatNameLength        = 100
+	MaxChatDescriptionLength = 1000
+	MaxAvatarUrlLength       = 2048
+	MaxSlowModeSeconds       = 6 * 60 * 60
+	MinRetentionSeconds      = 60 * 60
+	MaxRetentionSeconds      = 365 * 24 * 60 * 60
+)
+
+// ChatSettings controls who may do what in a chat. It is stored as a JSON document on the chat row.
+type ChatSettings struct {
+	WhoCanPost      PermissionLevel `json:"who_can_post"`
+	WhoCanInvite    PermissionLevel `json:"who_can_invite"`
+	SlowModeSeconds int             `json:"slow_mode_seconds"`
+	// Messages older than RetentionSeconds are purged, 0 keeps them forever.
+	RetentionSeconds int `json:"retention_seconds"`
+}
+
+func DefaultChatSettings() ChatSettings {
+	return ChatSettings{
+		WhoCanPost:   PermissionMembers,
+		WhoCanInvite: PermissionAdmins,
+	}
+}
+
+func (s ChatSettings) Validate() error {
+	if !s.WhoCanPost.IsValid() {
+		return fmt.Errorf("invalid who_can_post: %s. Must be 'members' or 'admins'", s.WhoCanPost)
+	}
+	if !s.WhoCanInvite.IsValid() {
+		return fmt.Errorf("invalid who_can_invite: %s. Must be 'members' or 'admins'", s.WhoCanInvite)
+	}
+	if s.SlowModeSeconds < 0 || s.SlowModeSeconds > MaxSlowModeSeconds {
+		return fmt.Errorf("slow_mode_seconds must be between 0 and %d", MaxSlowModeSeconds)
+	}
+	if s.RetentionSeconds != 0 && (s.RetentionSeconds < MinRetentionSeconds || s.RetentionSeconds > MaxRetentionSeconds) {
+		return fmt.Errorf("retention_seconds must be 0 or between %d and %d", MinRetentionSeconds, MaxRetentionSeconds)
+	}
+	return nil
+}
+
+func (s ChatSettings) SlowModeInterval() time.Duration {
+	return time.Duration(s.SlowModeSeconds) * time.Second
+}
+
+func (s ChatSettings) RetentionPeriod() time.Duration {
+	return time.Duration(s.RetentionSeconds) * time.Second
+}
+
+func (s ChatSettings) Value() (driver.Value, error) {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan reads the JSON document, filling in defaults for keys missing from older rows.
+func (s *ChatSettings) Scan(src interface{}) error {
+	*s = DefaultChatSettings()
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported type for chat settings: %T", src)
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return err
+	}
+	if s.WhoCanPost == "" {
+		s.WhoCanPost = PermissionMembers
+	}
+	if s.WhoCanInvite == "" {
+		s.WhoCanInvite = PermissionAdmins
+	}
+	return nil
+}
+
+// ChatUpdate holds a partial update of the chat metadata, nil fields are left unchanged.
+type ChatUpdate struct {
+	Name             *string
+	Description      *string
+	AvatarUrl        *string
+	WhoCanPost       *PermissionLevel
+	WhoCanInvite     *PermissionLevel
+	SlowModeSeconds  *int
+	RetentionSeconds *int
+}
+
+func (c *Chat) ApplyUpdate(update ChatUpdate) error {
+	if update.Name != nil {
+		if utf8.RuneCountInString(*update.Name) > MaxChatNameLength {
+			return fmt.Errorf("name must be at most %d characters", MaxChatNameLength)
+		}
+		c.Name = *update.Name
+	}
+	if update.Description != nil {
+		if utf8.RuneCountInString(*update.Description) > MaxChatDescriptionLength {
+			return fmt.Errorf("description must be at most %d characters", MaxChatDescriptionLength)
+		}
+		c.Description = *update.Description
+	}
+	if update.AvatarUrl != nil {
+		if err := validateAvatarUrl(*update.AvatarUrl); err != nil {
+			return err
+		}
+		c.AvatarUrl = *update.AvatarUrl
+	}
+
+	settings := c.Settings
+	if update.WhoCanPost != nil {
+		settings.WhoCanPost = *update.WhoCanPost
+	}
+	if update.WhoCanInvite != nil {
+		settings.WhoCanInvite = *update.WhoCanInvite
+	}
+	if update.SlowModeSeconds != nil {
+		settings.SlowModeSeconds = *update.SlowModeSeconds
+	}
+	if update.RetentionSeconds != nil {
+		settings.RetentionSeconds = *update.RetentionSeconds
+	}
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+	c.Settings = settings
+	return nil
+}
+
+// CanPost reports whether the participant may send messages under the chat settings.
+func (c *Chat) CanPost(participant *ChatParticipant) bool {
+	if participant.Status != StatusActive {
+		return false
+	}
+	return c.Settings.WhoCanPost == PermissionMembers || participant.Role == RoleAdmin
+}
+
+// CanInvite reports whether the participant may create invitations under the chat settings.
+func (c *Chat) CanInvite(participant *ChatParticipant) bool {
+	if participant.Status != StatusActive {
+		return false
+	}
+	return c.Settings.WhoCanInvite == PermissionMembers || participant.Role == RoleAdmin
+}
+
+func validateAvatarUrl(avatarUrl string) error {
+	if avatarUrl == "" {
+		return nil
+	}
+	if len(avatarUrl) > MaxAvatarUrlLength {
+		return fmt.Errorf("avatar_url must be at most %d characters", MaxAvatarUrlLength)
+	}
+	parsed, err := url.Parse(avatarUrl)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return errors.New("avatar_url must be an absolute http or https URL")
+	}
+	return nil
+}
@@ -10,16 +10,20 @@ const (
 	StatusBanned  ParticipantStatus = "banned"
 	StatusMuted   ParticipantStatus = "muted"
 	StatusPending ParticipantStatus = "pending"
+	// StatusRemoved marks members removed by an admin or the user group, and rejected join requests. Unlike
+	// StatusLeft it cannot be undone by the user, only by adding them again.
+	StatusRemoved ParticipantStatus = "removed"
 )
 
 func NewParticipantStatus(status string) (ParticipantStatus, error) {
 	switch status {
-	case string(StatusActive), string(StatusLeft), string(StatusBanned), string(StatusMuted), string(StatusPending):
+	case string(StatusActive), string(StatusLeft), string(StatusBanned), string(StatusMuted), string(StatusPending),
+		string(StatusRemoved):
 		return ParticipantStatus(status), nil
 	case "":
 		return StatusActive, nil // Default to active
 	default:
-		return "", fmt.Errorf("invalid status: %s. Must be one of: active, left, banned, muted, pending", status)
+		return "", fmt.Errorf("invalid status: %s. Must be one of: active, left, banned, muted, pending, removed", status)
 	}
 }
 
@@ -28,7 +32,13 @@ func (s ParticipantStatus) String() string {
 }
 
 func (s ParticipantStatus) IsValid() bool {
-	return s == StatusActive || s == StatusLeft || s == StatusBanned || s == StatusMuted || s == StatusPending
+	return s == StatusActive || s == StatusLeft || s == StatusBanned || s == StatusMuted || s == StatusPending ||
+		s == StatusRemoved
+}
+
+// HasLeft reports whether the participant is no longer a member, because they left or were removed.
+func (s ParticipantStatus) HasLeft() bool {
+	return s == StatusLeft || s == StatusRemoved
 }
 
 func (s ParticipantStatus) CanParticipate() bool {
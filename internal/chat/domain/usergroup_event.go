@@ -0,0 +1,155 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type UserGroupEventType string
+
+const (
+	UserGroupCreated       UserGroupEventType = "usergroup.created"
+	UserGroupDeleted       UserGroupEventType = "usergroup.deleted"
+	UserGroupMemberAdded   UserGroupEventType = "usergroup.member_added"
+	UserGroupMemberRemoved UserGroupEventType = "usergroup.member_removed"
+)
+
+func (t UserGroupEventType) IsValid() bool {
+	switch t {
+	case UserGroupCreated, UserGroupDeleted, UserGroupMemberAdded, UserGroupMemberRemoved:
+		return true
+	default:
+		return false
+	}
+}
+
+// UserGroupEvent is sent by the main application whenever a user group or its membership changes.
+// Group chats mirror the user group identified by UserGroupId.
+type UserGroupEvent struct {
+	EventId     string             `json:"event_id"`
+	Type        UserGroupEventType `json:"type"`
+	UserGroupId int                `json:"usergroup_id"`
+	OwnerId     string             `json:"owner_id,omitempty"`
+	MemberIds   []string           `json:"member_ids,omitempty"`
+	UserId      string             `json:"user_id,omitempty"`
+	Role        string             `json:"role,omitempty"`
+	OccurredAt  time.Time          `json:"occurred_at"`
+}
+
+func (e *UserGroupEvent) Validate() error {
+	if e.EventId == "" {
+		return errors.New("event_id is required")
+	}
+	if !e.Type.IsValid() {
+		return fmt.Errorf("invalid event type: %s", e.Type)
+	}
+	if e.OccurredAt.IsZero() {
+		return errors.New("occurred_at is required")
+	}
+	if e.UserGroupId <= 0 {
+		return errors.New("usergroup_id is required")
+	}
+	switch e.Type {
+	case UserGroupMemberAdded, UserGroupMemberRemoved:
+		if e.UserId == "" {
+			return errors.New("user_id is required for member events")
+		}
+	}
+	if err := validateUserId("user_id", e.UserId); err != nil {
+		return err
+	}
+	if err := validateUserId("owner_id", e.OwnerId); err != nil {
+		return err
+	}
+	for _, memberId := range e.MemberIds {
+		if err := validateUserId("member_ids", memberId); err != nil {
+			return err
+		}
+	}
+	if _, err := NewParticipantRole(e.Role); err != nil {
+		return err
+	}
+	return nil
+}
+
+// validateUserId checks that a user id sent with the event is a UUID, ids that are not set are left to the
+// required checks.
+func validateUserId(field, userId string) error {
+	if userId == "" {
+		return nil
+	}
+	if _, err := uuid.Parse(userId); err != nil {
+		return fmt.Errorf("%s must be a UUID: %s", field, userId)
+	}
+	return nil
+}
+
+// MemberId returns the user a member event is about, and an empty id for events about the group itself.
+func (e *UserGroupEvent) MemberId() string {
+	switch e.Type {
+	case UserGroupMemberAdded, UserGroupMemberRemoved:
+		return e.UserId
+	default:
+		return ""
+	}
+}
+
+// UserGroupEventLog holds when the events applied for a user group occurred, the latest per member keyed by
+// user id and the latest about the group itself under the empty id.
+type UserGroupEventLog map[string]time.Time
+
+// IsStale reports whether a newer event was applied already, so applying the event would undo it. Events about
+// the group are ordered among each other, member events also against the events of that member.
+func (l UserGroupEventLog) IsStale(e *UserGroupEvent) bool {
+	if !e.OccurredAt.After(l[""]) {
+		return true
+	}
+	if memberId := e.MemberId(); memberId != "" {
+		return !e.OccurredAt.After(l[memberId])
+	}
+	return false
+}
+
+// MemberChangedAfter reports whether an event about the member that occurred after at was applied.
+func (l UserGroupEventLog) MemberChangedAfter(userId string, at time.Time) bool {
+	return l[userId].After(at)
+}
+
+// Participants returns the chat participants implied by the event. The group owner becomes an admin.
+func (e *UserGroupEvent) Participants(chatId string) ([]*ChatParticipant, error) {
+	participants := make([]*ChatParticipant, 0, len(e.MemberIds)+1)
+	switch e.Type {
+	case UserGroupCreated:
+		if e.OwnerId != "" {
+			owner, err := NewChatParticipant(chatId, e.OwnerId, RoleAdmin, StatusActive)
+			if err != nil {
+				return nil, err
+			}
+			participants = append(participants, owner)
+		}
+		for _, memberId := range e.MemberIds {
+			if memberId == e.OwnerId {
+				continue
+			}
+			member, err := NewChatParticipant(chatId, memberId, RoleMember, StatusActive)
+			if err != nil {
+				return nil, err
+			}
+			participants = append(participants, member)
+		}
+	case UserGroupMemberAdded:
+		role, err := NewParticipantRole(e.Role)
+		if err != nil {
+			return nil, err
+		}
+		member, err := NewChatParticipant(chatId, e.UserId, role, StatusActive)
+		if err != nil {
+			return nil, err
+		}
+		participants = append(participants, member)
+	}
+	return participants, nil
+}
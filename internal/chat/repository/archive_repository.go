@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/HappYness-Project/ChatBackendServer/dbs"
+	"github.com/HappYness-Project/ChatBackendServer/internal/chat/domain"
+)
+
+// GetDeletedChatById returns a chat that was soft deleted and not purged yet, ErrDeletedChatNotFound otherwise.
+func (r *ChatRepo) GetDeletedChatById(ctx context.Context, chatId string) (*domain.Chat, error) {
+	rows, err := r.conn(ctx).QueryContext(ctx, `SELECT `+chatColumns+`
+							FROM public.chat
+							WHERE id = $1 AND deleted_at IS NOT NULL`, chatId)
+	if err != nil {
+		return nil, dbs.TranslateError(err)
+	}
+	defer rows.Close()
+	chat := new(domain.Chat)
+	for rows.Next() {
+		chat, err = scanRowsIntoChat(rows)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if chat.Id == "" {
+		return nil, domain.ErrDeletedChatNotFound
+	}
+	return chat, nil
+}
+
+func (r *ChatRepo) ArchiveChat(ctx context.Context, chatId string, archivedAt time.Time) error {
+	_, err := r.conn(ctx).ExecContext(ctx, `UPDATE public.chat SET archived_at = $2
+						 WHERE id = $1 AND archived_at IS NULL`, chatId, archivedAt)
+	return err
+}
+
+func (r *ChatRepo) UnarchiveChat(ctx context.Context, chatId string) error {
+	_, err := r.conn(ctx).ExecContext(ctx, `UPDATE public.chat SET archived_at = NULL WHERE id = $1`, chatId)
+	return err
+}
+
+// SoftDeleteChat hides the chat everywhere while keeping its rows, so it can be restored until it is purged.
+func (r *ChatRepo) SoftDeleteChat(ctx context.Context, chatId string, deletedAt time.Time) error {
+	_, err := r.conn(ctx).ExecContext(ctx, `UPDATE public.chat SET deleted_at = $2
+						 WHERE id = $1 AND deleted_at IS NULL`, chatId, deletedAt)
+	return err
+}
+
+func (r *ChatRepo) RestoreChat(ctx context.Context, chatId string) error {
+	_, err := r.conn(ctx).ExecContext(ctx, `UPDATE public.chat SET deleted_at = NULL WHERE id = $1`, chatId)
+	return err
+}
+
+// PurgeDeletedChats hard deletes up to limit chats soft deleted before deletedBefore. Participants, messages and
+// everything else belonging to the chats go with them by cascade. Rows locked by a concurrent purge are skipped.
+func (r *ChatRepo) PurgeDeletedChats(ctx context.Context, deletedBefore time.Time, limit int) (*domain.ChatPurgeResult, error) {
+	result := &domain.ChatPurgeResult{ChatIDs: []string{}, StorageKeys: []string{}}
+
+	tx, err := dbs.BeginTx(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `SELECT id FROM public.chat
+						   WHERE deleted_at < $1
+						   ORDER BY deleted_at ASC
+						   LIMIT $2
+						   FOR UPDATE SKIP LOCKED`, deletedBefore, limit)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		result.ChatIDs = append(result.ChatIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(result.ChatIDs) == 0 {
+		return result, nil
+	}
+
+	// Attachment rows would cascade with the chat, delete them first to learn which blobs to remove.
+	rows, err = tx.QueryContext(ctx, `DELETE FROM public.message_attachment
+						  WHERE chat_id = ANY($1)
+						  RETURNING storage_key`, result.ChatIDs)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		result.StorageKeys = append(result.StorageKeys, key)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if _, err = tx.ExecContext(ctx, `DELETE FROM public.chat WHERE id = ANY($1)`, result.ChatIDs); err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -1,40 +1,73 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"time"
 
+	"github.com/HappYness-Project/ChatBackendServer/dbs"
 	"github.com/HappYness-Project/ChatBackendServer/internal/chat/domain"
 	"github.com/google/uuid"
 )
 
 type ChatRepository interface {
-	GetChatByUserGroupId(userGroupId int) (*domain.Chat, error)
-	GetChatById(chatId string) (*domain.Chat, error)
-	GetChatByGroupID(groupID int) (*domain.Chat, error)
-	CreateChat(chat *domain.Chat) (*domain.Chat, error)
-	CreateChatWithParticipant(chat *domain.Chat, participant *domain.ChatParticipant) (*domain.Chat, error)
-	DeleteChat(chatId string) error
-	GetChatParticipants(chatId string) ([]domain.ChatParticipant, error)
-	AddParticipantToChat(participant *domain.ChatParticipant) (*domain.ChatParticipant, error)
-	IsUserParticipantInChat(chatId, userId string) (bool, error)
-	DeleteParticipantFromChat(chatId, participantId string) error
+	GetChatByUserGroupId(ctx context.Context, userGroupId int) (*domain.Chat, error)
+	GetChatById(ctx context.Context, chatId string) (*domain.Chat, error)
+	GetChatByGroupID(ctx context.Context, groupID int) (*domain.Chat, error)
+	CreateChat(ctx context.Context, chat *domain.Chat) (*domain.Chat, error)
+	EnsureUserGroupChat(ctx context.Context, userGroupId int) (*domain.Chat, error)
+	CreateChatWithParticipant(ctx context.Context, chat *domain.Chat, participant *domain.ChatParticipant) (*domain.Chat, error)
+	UpdateChat(ctx context.Context, chat *domain.Chat) error
+	DeleteChat(ctx context.Context, chatId string) error
+	GetDeletedChatById(ctx context.Context, chatId string) (*domain.Chat, error)
+	ArchiveChat(ctx context.Context, chatId string, archivedAt time.Time) error
+	UnarchiveChat(ctx context.Context, chatId string) error
+	SoftDeleteChat(ctx context.Context, chatId string, deletedAt time.Time) error
+	RestoreChat(ctx context.Context, chatId string) error
+	PurgeDeletedChats(ctx context.Context, deletedBefore time.Time, limit int) (*domain.ChatPurgeResult, error)
+	GetChatParticipants(ctx context.Context, chatId string) ([]domain.ChatParticipant, error)
+	AddParticipantToChat(ctx context.Context, participant *domain.ChatParticipant) (*domain.ChatParticipant, error)
+	AddParticipantsToChat(ctx context.Context, chatId string, participants []*domain.ChatParticipant) (map[string]*domain.ChatParticipant, error)
+	IsUserParticipantInChat(ctx context.Context, chatId, userId string) (bool, error)
+	RemoveParticipant(ctx context.Context, chatId, userId string, removedAt time.Time) error
+	GetChatParticipant(ctx context.Context, chatId, userId string) (*domain.ChatParticipant, error)
+	GetChatParticipantsByStatus(ctx context.Context, chatId string, status domain.ParticipantStatus) ([]domain.ChatParticipant, error)
+	UpdateParticipantStatus(ctx context.Context, chatId, userId string, status domain.ParticipantStatus) error
+	LeaveChat(ctx context.Context, chatId, userId string, leftAt time.Time) error
+	RejoinChat(ctx context.Context, chatId, userId string) error
+	CreateInvitation(ctx context.Context, invitation *domain.ChatInvitation) (*domain.ChatInvitation, error)
+	GetInvitationsByChatId(ctx context.Context, chatId string) ([]domain.ChatInvitation, error)
+	GetInvitationByToken(ctx context.Context, token string) (*domain.ChatInvitation, error)
+	RevokeInvitation(ctx context.Context, chatId, invitationId string) error
+	RedeemInvitation(ctx context.Context, invitationId string, participant *domain.ChatParticipant) (*domain.ChatParticipant, error)
+	GetUserGroupEventLog(ctx context.Context, userGroupId int) (domain.UserGroupEventLog, error)
+	RecordUserGroupEvent(ctx context.Context, event *domain.UserGroupEvent) (bool, error)
 }
 
+const chatColumns = `id, type, usergroup_id, container_id, name, description, avatar_url, settings, created_at,
+	archived_at, deleted_at`
+
 type ChatRepo struct {
 	db *sql.DB
 }
 
+var _ ChatRepository = (*ChatRepo)(nil)
+
 func NewRepository(db *sql.DB) *ChatRepo {
 	return &ChatRepo{db: db}
 }
 
-func (r *ChatRepo) GetChatById(chatId string) (*domain.Chat, error) {
-	rows, err := r.db.Query(`SELECT id, type, usergroup_id, container_id, created_at
+// conn returns the connection to run statements on, the transaction of a unit of work if ctx carries one.
+func (r *ChatRepo) conn(ctx context.Context) dbs.Querier {
+	return dbs.Conn(ctx, r.db)
+}
+
+func (r *ChatRepo) GetChatById(ctx context.Context, chatId string) (*domain.Chat, error) {
+	rows, err := r.conn(ctx).QueryContext(ctx, `SELECT `+chatColumns+`
 							FROM public.chat
-							WHERE id = $1`, chatId)
+							WHERE id = $1 AND deleted_at IS NULL`, chatId)
 	if err != nil {
-		return nil, err
+		return nil, dbs.TranslateError(err)
 	}
 	defer rows.Close()
 	chat := new(domain.Chat)
@@ -47,15 +80,18 @@ func (r *ChatRepo) GetChatById(chatId string) (*domain.Chat, error) {
 	if err = rows.Err(); err != nil {
 		return nil, err
 	}
+	if chat.Id == "" {
+		return nil, domain.ErrChatNotFound
+	}
 	return chat, nil
 }
 
-func (r *ChatRepo) GetChatByUserGroupId(userGroupId int) (*domain.Chat, error) {
-	rows, err := r.db.Query(`SELECT id, type, usergroup_id, container_id, created_at
+func (r *ChatRepo) GetChatByUserGroupId(ctx context.Context, userGroupId int) (*domain.Chat, error) {
+	rows, err := r.conn(ctx).QueryContext(ctx, `SELECT `+chatColumns+`
 							FROM public.chat
-							WHERE usergroup_id = $1 and type = 'group'`, userGroupId)
+							WHERE usergroup_id = $1 and type = 'group' AND deleted_at IS NULL`, userGroupId)
 	if err != nil {
-		return nil, err
+		return nil, dbs.TranslateError(err)
 	}
 	defer rows.Close()
 
@@ -69,15 +105,18 @@ func (r *ChatRepo) GetChatByUserGroupId(userGroupId int) (*domain.Chat, error) {
 	if err = rows.Err(); err != nil {
 		return nil, err
 	}
+	if chat.Id == "" {
+		return nil, domain.ErrChatNotFound
+	}
 	return chat, nil
 }
 
-func (r *ChatRepo) GetChatByGroupID(groupID int) (*domain.Chat, error) {
-	rows, err := r.db.Query(`SELECT id, type, usergroup_id, container_id, created_at
+func (r *ChatRepo) GetChatByGroupID(ctx context.Context, groupID int) (*domain.Chat, error) {
+	rows, err := r.conn(ctx).QueryContext(ctx, `SELECT `+chatColumns+`
 							FROM public.chat
-							WHERE usergroup_id = $1`, groupID)
+							WHERE usergroup_id = $1 AND deleted_at IS NULL`, groupID)
 	if err != nil {
-		return nil, err
+		return nil, dbs.TranslateError(err)
 	}
 	defer rows.Close()
 
@@ -91,33 +130,103 @@ func (r *ChatRepo) GetChatByGroupID(groupID int) (*domain.Chat, error) {
 	if err = rows.Err(); err != nil {
 		return nil, err
 	}
+	if chat.Id == "" {
+		return nil, domain.ErrChatNotFound
+	}
+	return chat, nil
+}
+
+func (r *ChatRepo) CreateChat(ctx context.Context, chat *domain.Chat) (*domain.Chat, error) {
+	_, err := r.conn(ctx).ExecContext(ctx, `INSERT INTO public.chat (id, type, usergroup_id, container_id, name, description, avatar_url, settings, created_at)
+						VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		chat.Id, chat.Type.String(), chat.UserGroupId, chat.ContainerId,
+		chat.Name, chat.Description, chat.AvatarUrl, chat.Settings, chat.CreatedAt)
+	if err != nil {
+		return nil, dbs.TranslateError(err)
+	}
+
 	return chat, nil
 }
 
-func (r *ChatRepo) CreateChat(chat *domain.Chat) (*domain.Chat, error) {
-	_, err := r.db.Exec(`INSERT INTO public.chat (id, type, usergroup_id, container_id, created_at)
-						VALUES ($1, $2, $3, $4, $5)`,
-		chat.Id, chat.Type.String(), chat.UserGroupId, chat.ContainerId, chat.CreatedAt)
+// EnsureUserGroupChat returns the group chat of the user group, creating it when it does not exist yet
+// and restoring it when it was deleted but not purged.
+// A transaction scoped advisory lock on the group id keeps concurrent events from creating duplicates.
+func (r *ChatRepo) EnsureUserGroupChat(ctx context.Context, userGroupId int) (*domain.Chat, error) {
+	tx, err := dbs.BeginTx(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, userGroupId); err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT `+chatColumns+`
+						   FROM public.chat
+						   WHERE usergroup_id = $1 and type = 'group'`, userGroupId)
+	if err != nil {
+		return nil, err
+	}
+	chat := new(domain.Chat)
+	for rows.Next() {
+		chat, err = scanRowsIntoChat(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if chat.Id != "" {
+		if !chat.IsDeleted() {
+			return chat, nil
+		}
+		// A group that is active again after its chat was deleted gets the old chat back rather than a second one.
+		if _, err = tx.ExecContext(ctx, `UPDATE public.chat SET deleted_at = NULL WHERE id = $1`, chat.Id); err != nil {
+			return nil, err
+		}
+		if err = tx.Commit(); err != nil {
+			return nil, err
+		}
+		chat.DeletedAt = nil
+		return chat, nil
+	}
+
+	chat, err = domain.NewChat(domain.ChatTypeGroup, &userGroupId, nil)
 	if err != nil {
 		return nil, err
 	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO public.chat (id, type, usergroup_id, container_id, name, description, avatar_url, settings, created_at)
+					  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		chat.Id, chat.Type.String(), chat.UserGroupId, chat.ContainerId,
+		chat.Name, chat.Description, chat.AvatarUrl, chat.Settings, chat.CreatedAt)
+	if err != nil {
+		return nil, dbs.TranslateError(err)
+	}
 
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
 	return chat, nil
 }
 
-func (r *ChatRepo) CreateChatWithParticipant(chat *domain.Chat, participant *domain.ChatParticipant) (*domain.Chat, error) {
-	tx, err := r.db.Begin()
+func (r *ChatRepo) CreateChatWithParticipant(ctx context.Context, chat *domain.Chat, participant *domain.ChatParticipant) (*domain.Chat, error) {
+	tx, err := dbs.BeginTx(ctx, r.db)
 	if err != nil {
 		return nil, err
 	}
 	defer tx.Rollback()
 
 	// Create chat
-	_, err = tx.Exec(`INSERT INTO public.chat (id, type, usergroup_id, container_id, created_at)
-					  VALUES ($1, $2, $3, $4, $5)`,
-		chat.Id, chat.Type.String(), chat.UserGroupId, chat.ContainerId, chat.CreatedAt)
+	_, err = tx.ExecContext(ctx, `INSERT INTO public.chat (id, type, usergroup_id, container_id, name, description, avatar_url, settings, created_at)
+					  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		chat.Id, chat.Type.String(), chat.UserGroupId, chat.ContainerId,
+		chat.Name, chat.Description, chat.AvatarUrl, chat.Settings, chat.CreatedAt)
 	if err != nil {
-		return nil, err
+		return nil, dbs.TranslateError(err)
 	}
 
 	// Generate participant ID and timestamp
@@ -130,12 +239,12 @@ func (r *ChatRepo) CreateChatWithParticipant(chat *domain.Chat, participant *dom
 	participant.JoinedAt = time.Now().UTC()
 
 	// Add participant
-	_, err = tx.Exec(`INSERT INTO public.chat_participant (id, chat_id, user_id, joined_at, role, status)
+	_, err = tx.ExecContext(ctx, `INSERT INTO public.chat_participant (id, chat_id, user_id, joined_at, role, status)
 					  VALUES ($1, $2, $3, $4, $5, $6)`,
 		participant.Id, participant.ChatId, participant.UserId, participant.JoinedAt,
 		participant.Role.String(), participant.Status.String())
 	if err != nil {
-		return nil, err
+		return nil, dbs.TranslateError(err)
 	}
 
 	// Commit transaction
@@ -146,13 +255,20 @@ func (r *ChatRepo) CreateChatWithParticipant(chat *domain.Chat, participant *dom
 	return chat, nil
 }
 
-func (r *ChatRepo) DeleteChat(chatId string) error {
-	_, err := r.db.Exec(`DELETE FROM public.chat WHERE id = $1`, chatId)
+func (r *ChatRepo) UpdateChat(ctx context.Context, chat *domain.Chat) error {
+	_, err := r.conn(ctx).ExecContext(ctx, `UPDATE public.chat SET name = $2, description = $3, avatar_url = $4, settings = $5
+						 WHERE id = $1`,
+		chat.Id, chat.Name, chat.Description, chat.AvatarUrl, chat.Settings)
+	return dbs.TranslateError(err)
+}
+
+func (r *ChatRepo) DeleteChat(ctx context.Context, chatId string) error {
+	_, err := r.conn(ctx).ExecContext(ctx, `DELETE FROM public.chat WHERE id = $1`, chatId)
 	return err
 }
 
-func (r *ChatRepo) GetChatParticipants(chatId string) ([]domain.ChatParticipant, error) {
-	rows, err := r.db.Query(`SELECT id, chat_id, user_id, joined_at, role, status
+func (r *ChatRepo) GetChatParticipants(ctx context.Context, chatId string) ([]domain.ChatParticipant, error) {
+	rows, err := r.conn(ctx).QueryContext(ctx, `SELECT id, chat_id, user_id, joined_at, role, status, left_at
 							FROM public.chat_participant
 							WHERE chat_id = $1
 							ORDER BY joined_at ASC`, chatId)
@@ -177,9 +293,9 @@ func (r *ChatRepo) GetChatParticipants(chatId string) ([]domain.ChatParticipant,
 	return participants, nil
 }
 
-func (r *ChatRepo) IsUserParticipantInChat(chatId, userId string) (bool, error) {
+func (r *ChatRepo) IsUserParticipantInChat(ctx context.Context, chatId, userId string) (bool, error) {
 	var count int
-	err := r.db.QueryRow(`SELECT COUNT(*) FROM public.chat_participant
+	err := r.conn(ctx).QueryRowContext(ctx, `SELECT COUNT(*) FROM public.chat_participant
 						  WHERE chat_id = $1 AND user_id = $2`, chatId, userId).Scan(&count)
 	if err != nil {
 		return false, err
@@ -187,7 +303,7 @@ func (r *ChatRepo) IsUserParticipantInChat(chatId, userId string) (bool, error)
 	return count > 0, nil
 }
 
-func (r *ChatRepo) AddParticipantToChat(participant *domain.ChatParticipant) (*domain.ChatParticipant, error) {
+func (r *ChatRepo) AddParticipantToChat(ctx context.Context, participant *domain.ChatParticipant) (*domain.ChatParticipant, error) {
 	id, err := uuid.NewV7()
 	if err != nil {
 		return nil, err
@@ -196,21 +312,184 @@ func (r *ChatRepo) AddParticipantToChat(participant *domain.ChatParticipant) (*d
 	participant.Id = id.String()
 	participant.JoinedAt = time.Now().UTC()
 
-	_, err = r.db.Exec(`INSERT INTO public.chat_participant (id, chat_id, user_id, joined_at, role, status)
-						VALUES ($1, $2, $3, $4, $5, $6)`,
+	result, err := r.conn(ctx).ExecContext(ctx, `INSERT INTO public.chat_participant (id, chat_id, user_id, joined_at, role, status)
+							  VALUES ($1, $2, $3, $4, $5, $6)
+							  ON CONFLICT (chat_id, user_id) DO NOTHING`,
 		participant.Id, participant.ChatId, participant.UserId, participant.JoinedAt,
 		participant.Role.String(), participant.Status.String())
+	if err != nil {
+		return nil, dbs.TranslateError(err)
+	}
+	affected, err := result.RowsAffected()
 	if err != nil {
 		return nil, err
 	}
+	if affected == 0 {
+		return nil, domain.ErrAlreadyParticipant
+	}
 
 	return participant, nil
 }
 
-func (r *ChatRepo) DeleteParticipantFromChat(chatId, participantId string) error {
-	_, err := r.db.Exec(`DELETE FROM public.chat_participant 
-						 WHERE chat_id = $1 AND user_id = $2`, chatId, participantId)
-	return err
+// AddParticipantsToChat inserts the participants in one transaction. Users that left or were removed from
+// the chat are reactivated; other users that already have a participant row are skipped and returned with
+// their existing row, keyed by user id.
+func (r *ChatRepo) AddParticipantsToChat(ctx context.Context, chatId string, participants []*domain.ChatParticipant) (map[string]*domain.ChatParticipant, error) {
+	tx, err := dbs.BeginTx(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Lock the chat row so concurrent bulk adds to the same chat are serialised.
+	if _, err = tx.ExecContext(ctx, `SELECT id FROM public.chat WHERE id = $1 FOR UPDATE`, chatId); err != nil {
+		return nil, err
+	}
+
+	existing := make(map[string]*domain.ChatParticipant)
+	for _, participant := range participants {
+		rows, err := tx.QueryContext(ctx, `SELECT id, chat_id, user_id, joined_at, role, status, left_at
+							   FROM public.chat_participant
+							   WHERE chat_id = $1 AND user_id = $2`, chatId, participant.UserId)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			found, err := scanRowsIntoChatParticipant(rows)
+			if err != nil {
+				rows.Close()
+				return nil, err
+			}
+			existing[participant.UserId] = found
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return nil, err
+		}
+		if found, ok := existing[participant.UserId]; ok {
+			if !found.Status.HasLeft() {
+				continue
+			}
+			// Users that left earlier are re-added in place so their join history is kept.
+			_, err = tx.ExecContext(ctx, `UPDATE public.chat_participant SET role = $3, status = $4, left_at = NULL
+							  WHERE chat_id = $1 AND user_id = $2`,
+				chatId, participant.UserId, participant.Role.String(), participant.Status.String())
+			if err != nil {
+				return nil, err
+			}
+			participant.Id = found.Id
+			participant.JoinedAt = found.JoinedAt
+			delete(existing, participant.UserId)
+			continue
+		}
+
+		id, err := uuid.NewV7()
+		if err != nil {
+			return nil, err
+		}
+		participant.Id = id.String()
+		participant.JoinedAt = time.Now().UTC()
+
+		_, err = tx.ExecContext(ctx, `INSERT INTO public.chat_participant (id, chat_id, user_id, joined_at, role, status)
+						  VALUES ($1, $2, $3, $4, $5, $6)`,
+			participant.Id, participant.ChatId, participant.UserId, participant.JoinedAt,
+			participant.Role.String(), participant.Status.String())
+		if err != nil {
+			return nil, dbs.TranslateError(err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
+
+// RemoveParticipant marks the participant as removed at removedAt. The row is kept, so the join history is not
+// lost and the user cannot come back by themselves.
+func (r *ChatRepo) RemoveParticipant(ctx context.Context, chatId, userId string, removedAt time.Time) error {
+	result, err := r.conn(ctx).ExecContext(ctx, `UPDATE public.chat_participant SET status = 'removed', left_at = $3
+						 WHERE chat_id = $1 AND user_id = $2 AND status <> 'removed'`, chatId, userId, removedAt)
+	if err != nil {
+		return dbs.TranslateError(err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return domain.ErrParticipantNotFound
+	}
+	return nil
+}
+
+func (r *ChatRepo) GetChatParticipant(ctx context.Context, chatId, userId string) (*domain.ChatParticipant, error) {
+	rows, err := r.conn(ctx).QueryContext(ctx, `SELECT id, chat_id, user_id, joined_at, role, status, left_at
+							FROM public.chat_participant
+							WHERE chat_id = $1 AND user_id = $2`, chatId, userId)
+	if err != nil {
+		return nil, dbs.TranslateError(err)
+	}
+	defer rows.Close()
+
+	participant := new(domain.ChatParticipant)
+	for rows.Next() {
+		participant, err = scanRowsIntoChatParticipant(rows)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if participant.Id == "" {
+		return nil, domain.ErrParticipantNotFound
+	}
+	return participant, nil
+}
+
+func (r *ChatRepo) GetChatParticipantsByStatus(ctx context.Context, chatId string, status domain.ParticipantStatus) ([]domain.ChatParticipant, error) {
+	rows, err := r.conn(ctx).QueryContext(ctx, `SELECT id, chat_id, user_id, joined_at, role, status, left_at
+							FROM public.chat_participant
+							WHERE chat_id = $1 AND status = $2
+							ORDER BY joined_at ASC`, chatId, status.String())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	participants := make([]domain.ChatParticipant, 0)
+	for rows.Next() {
+		participant, err := scanRowsIntoChatParticipant(rows)
+		if err != nil {
+			return nil, err
+		}
+		participants = append(participants, *participant)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return participants, nil
+}
+
+func (r *ChatRepo) UpdateParticipantStatus(ctx context.Context, chatId, userId string, status domain.ParticipantStatus) error {
+	_, err := r.conn(ctx).ExecContext(ctx, `UPDATE public.chat_participant SET status = $3
+						 WHERE chat_id = $1 AND user_id = $2`, chatId, userId, status.String())
+	return dbs.TranslateError(err)
+}
+
+func (r *ChatRepo) LeaveChat(ctx context.Context, chatId, userId string, leftAt time.Time) error {
+	_, err := r.conn(ctx).ExecContext(ctx, `UPDATE public.chat_participant SET status = 'left', left_at = $3
+						 WHERE chat_id = $1 AND user_id = $2 AND status = 'active'`, chatId, userId, leftAt)
+	return dbs.TranslateError(err)
+}
+
+func (r *ChatRepo) RejoinChat(ctx context.Context, chatId, userId string) error {
+	_, err := r.conn(ctx).ExecContext(ctx, `UPDATE public.chat_participant SET status = 'active', left_at = NULL
+						 WHERE chat_id = $1 AND user_id = $2 AND status = 'left'`, chatId, userId)
+	return dbs.TranslateError(err)
 }
 
 func scanRowsIntoChat(rows *sql.Rows) (*domain.Chat, error) {
@@ -221,7 +500,13 @@ func scanRowsIntoChat(rows *sql.Rows) (*domain.Chat, error) {
 		&typeStr,
 		&chat.UserGroupId,
 		&chat.ContainerId,
+		&chat.Name,
+		&chat.Description,
+		&chat.AvatarUrl,
+		&chat.Settings,
 		&chat.CreatedAt,
+		&chat.ArchivedAt,
+		&chat.DeletedAt,
 	)
 	if err != nil {
 		return nil, err
@@ -234,9 +519,10 @@ func scanRowsIntoChat(rows *sql.Rows) (*domain.Chat, error) {
 func scanRowsIntoChatParticipant(rows *sql.Rows) (*domain.ChatParticipant, error) {
 	var id, chatId, userId string
 	var joinedAt time.Time
+	var leftAt *time.Time
 	var roleStr, statusStr string
 
-	err := rows.Scan(&id, &chatId, &userId, &joinedAt, &roleStr, &statusStr)
+	err := rows.Scan(&id, &chatId, &userId, &joinedAt, &roleStr, &statusStr, &leftAt)
 	if err != nil {
 		return nil, err
 	}
@@ -258,5 +544,6 @@ func scanRowsIntoChatParticipant(rows *sql.Rows) (*domain.ChatParticipant, error
 		JoinedAt: joinedAt,
 		Role:     role,
 		Status:   status,
+		LeftAt:   leftAt,
 	}, nil
 }
@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/HappYness-Project/ChatBackendServer/dbs"
+	"github.com/HappYness-Project/ChatBackendServer/internal/chat/domain"
+	"github.com/google/uuid"
+)
+
+func (r *ChatRepo) CreateInvitation(ctx context.Context, invitation *domain.ChatInvitation) (*domain.ChatInvitation, error) {
+	_, err := r.conn(ctx).ExecContext(ctx, `INSERT INTO public.chat_invitation (id, chat_id, token, created_by, expires_at, max_uses, uses, created_at)
+						VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		invitation.Id, invitation.ChatId, invitation.Token, invitation.CreatedBy,
+		invitation.ExpiresAt, invitation.MaxUses, invitation.Uses, invitation.CreatedAt)
+	if err != nil {
+		return nil, dbs.TranslateError(err)
+	}
+	return invitation, nil
+}
+
+func (r *ChatRepo) GetInvitationsByChatId(ctx context.Context, chatId string) ([]domain.ChatInvitation, error) {
+	rows, err := r.conn(ctx).QueryContext(ctx, `SELECT id, chat_id, token, created_by, expires_at, max_uses, uses, created_at, revoked_at
+							FROM public.chat_invitation
+							WHERE chat_id = $1
+							ORDER BY created_at DESC`, chatId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invitations := make([]domain.ChatInvitation, 0)
+	for rows.Next() {
+		invitation, err := scanRowsIntoChatInvitation(rows)
+		if err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, *invitation)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return invitations, nil
+}
+
+func (r *ChatRepo) GetInvitationByToken(ctx context.Context, token string) (*domain.ChatInvitation, error) {
+	rows, err := r.conn(ctx).QueryContext(ctx, `SELECT id, chat_id, token, created_by, expires_at, max_uses, uses, created_at, revoked_at
+							FROM public.chat_invitation
+							WHERE token = $1`, token)
+	if err != nil {
+		return nil, dbs.TranslateError(err)
+	}
+	defer rows.Close()
+
+	invitation := new(domain.ChatInvitation)
+	for rows.Next() {
+		invitation, err = scanRowsIntoChatInvitation(rows)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if invitation.Id == "" {
+		return nil, domain.ErrInvitationNotFound
+	}
+	return invitation, nil
+}
+
+// RevokeInvitation revokes an invitation of the chat. Unknown invitations, invitations of other chats and
+// invitations revoked before are not found.
+func (r *ChatRepo) RevokeInvitation(ctx context.Context, chatId, invitationId string) error {
+	result, err := r.conn(ctx).ExecContext(ctx, `UPDATE public.chat_invitation SET revoked_at = $3
+						 WHERE chat_id = $1 AND id = $2 AND revoked_at IS NULL`,
+		chatId, invitationId, time.Now().UTC())
+	if err != nil {
+		return dbs.TranslateError(err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return domain.ErrInvitationNotFound
+	}
+	return nil
+}
+
+// RedeemInvitation consumes one use of the invitation and adds the participant in the same transaction.
+// The use counter is guarded in SQL so concurrent redemptions cannot exceed max_uses.
+func (r *ChatRepo) RedeemInvitation(ctx context.Context, invitationId string, participant *domain.ChatParticipant) (*domain.ChatParticipant, error) {
+	tx, err := dbs.BeginTx(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `UPDATE public.chat_invitation SET uses = uses + 1
+							WHERE id = $1
+							  AND revoked_at IS NULL
+							  AND (expires_at IS NULL OR expires_at > $2)
+							  AND (max_uses IS NULL OR uses < max_uses)`, invitationId, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if affected == 0 {
+		return nil, domain.ErrInvitationExhausted
+	}
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+	participant.Id = id.String()
+	participant.JoinedAt = time.Now().UTC()
+
+	result, err = tx.ExecContext(ctx, `INSERT INTO public.chat_participant (id, chat_id, user_id, joined_at, role, status)
+						   VALUES ($1, $2, $3, $4, $5, $6)
+						   ON CONFLICT (chat_id, user_id) DO NOTHING`,
+		participant.Id, participant.ChatId, participant.UserId, participant.JoinedAt,
+		participant.Role.String(), participant.Status.String())
+	if err != nil {
+		return nil, dbs.TranslateError(err)
+	}
+	// The use taken from the invitation is returned by rolling back. Inside a unit of work the rollback below
+	// does nothing, the use is only returned if the caller lets the error fail the unit.
+	affected, err = result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if affected == 0 {
+		return nil, domain.ErrAlreadyParticipant
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return participant, nil
+}
+
+func scanRowsIntoChatInvitation(rows *sql.Rows) (*domain.ChatInvitation, error) {
+	invitation := new(domain.ChatInvitation)
+	err := rows.Scan(
+		&invitation.Id,
+		&invitation.ChatId,
+		&invitation.Token,
+		&invitation.CreatedBy,
+		&invitation.ExpiresAt,
+		&invitation.MaxUses,
+		&invitation.Uses,
+		&invitation.CreatedAt,
+		&invitation.RevokedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return invitation, nil
+}
@@ -0,0 +1,594 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/HappYness-Project/ChatBackendServer/internal/chat/domain"
+	"github.com/google/uuid"
+)
+
+// MemoryChatRepo keeps chats, participants and invitations in memory. It behaves like ChatRepo, not found
+// included, so the server and tests can run without Postgres. Data is lost when the process exits.
+type MemoryChatRepo struct {
+	mu              sync.RWMutex
+	chats           map[string]domain.Chat
+	participants    map[string][]domain.ChatParticipant
+	invitations     map[string]domain.ChatInvitation
+	userGroupEvents map[string]domain.UserGroupEvent
+	cascades        []func(chatIds []string) []string
+}
+
+var _ ChatRepository = (*MemoryChatRepo)(nil)
+
+func NewMemoryRepository() *MemoryChatRepo {
+	return &MemoryChatRepo{
+		chats:           make(map[string]domain.Chat),
+		participants:    make(map[string][]domain.ChatParticipant),
+		invitations:     make(map[string]domain.ChatInvitation),
+		userGroupEvents: make(map[string]domain.UserGroupEvent),
+	}
+}
+
+// OnChatsRemoved registers cleanup for chats that are hard deleted, standing in for the foreign key cascades
+// of the database. The function returns the storage keys of attachments it removed.
+func (r *MemoryChatRepo) OnChatsRemoved(cascade func(chatIds []string) []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cascades = append(r.cascades, cascade)
+}
+
+// Snapshot copies the chats, participants and invitations for MemoryUnitOfWork to restore on rollback.
+func (r *MemoryChatRepo) Snapshot() func() {
+	r.mu.RLock()
+	chats, invitations, userGroupEvents := maps.Clone(r.chats), maps.Clone(r.invitations), maps.Clone(r.userGroupEvents)
+	participants := make(map[string][]domain.ChatParticipant, len(r.participants))
+	for chatId, members := range r.participants {
+		participants[chatId] = slices.Clone(members)
+	}
+	r.mu.RUnlock()
+
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.chats, r.participants, r.invitations, r.userGroupEvents = chats, participants, invitations, userGroupEvents
+	}
+}
+
+func (r *MemoryChatRepo) GetChatById(ctx context.Context, chatId string) (*domain.Chat, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	chat, ok := r.chats[chatId]
+	if !ok || chat.IsDeleted() {
+		return nil, domain.ErrChatNotFound
+	}
+	return &chat, nil
+}
+
+func (r *MemoryChatRepo) GetChatByUserGroupId(ctx context.Context, userGroupId int) (*domain.Chat, error) {
+	return r.findChat(ctx, func(chat domain.Chat) bool {
+		return chat.Type == domain.ChatTypeGroup && chat.UserGroupId != nil && *chat.UserGroupId == userGroupId && !chat.IsDeleted()
+	})
+}
+
+func (r *MemoryChatRepo) GetChatByGroupID(ctx context.Context, groupID int) (*domain.Chat, error) {
+	return r.findChat(ctx, func(chat domain.Chat) bool {
+		return chat.UserGroupId != nil && *chat.UserGroupId == groupID && !chat.IsDeleted()
+	})
+}
+
+func (r *MemoryChatRepo) CreateChat(ctx context.Context, chat *domain.Chat) (*domain.Chat, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.insertChat(chat); err != nil {
+		return nil, err
+	}
+	return chat, nil
+}
+
+func (r *MemoryChatRepo) EnsureUserGroupChat(ctx context.Context, userGroupId int) (*domain.Chat, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, chat := range r.chats {
+		if chat.Type != domain.ChatTypeGroup || chat.UserGroupId == nil || *chat.UserGroupId != userGroupId {
+			continue
+		}
+		chat.DeletedAt = nil
+		r.chats[id] = chat
+		return &chat, nil
+	}
+
+	chat, err := domain.NewChat(domain.ChatTypeGroup, &userGroupId, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err = r.insertChat(chat); err != nil {
+		return nil, err
+	}
+	return chat, nil
+}
+
+func (r *MemoryChatRepo) CreateChatWithParticipant(ctx context.Context, chat *domain.Chat, participant *domain.ChatParticipant) (*domain.Chat, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.insertChat(chat); err != nil {
+		return nil, err
+	}
+	if err := r.insertParticipant(participant); err != nil {
+		delete(r.chats, chat.Id)
+		return nil, err
+	}
+	return chat, nil
+}
+
+func (r *MemoryChatRepo) UpdateChat(ctx context.Context, chat *domain.Chat) error {
+	return r.updateChat(ctx, chat.Id, func(stored *domain.Chat) {
+		stored.Name = chat.Name
+		stored.Description = chat.Description
+		stored.AvatarUrl = chat.AvatarUrl
+		stored.Settings = chat.Settings
+	})
+}
+
+func (r *MemoryChatRepo) DeleteChat(ctx context.Context, chatId string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.removeChats([]string{chatId})
+	return nil
+}
+
+func (r *MemoryChatRepo) GetDeletedChatById(ctx context.Context, chatId string) (*domain.Chat, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	chat, ok := r.chats[chatId]
+	if !ok || !chat.IsDeleted() {
+		return nil, domain.ErrDeletedChatNotFound
+	}
+	return &chat, nil
+}
+
+func (r *MemoryChatRepo) ArchiveChat(ctx context.Context, chatId string, archivedAt time.Time) error {
+	return r.updateChat(ctx, chatId, func(stored *domain.Chat) {
+		if stored.ArchivedAt == nil {
+			stored.ArchivedAt = &archivedAt
+		}
+	})
+}
+
+func (r *MemoryChatRepo) UnarchiveChat(ctx context.Context, chatId string) error {
+	return r.updateChat(ctx, chatId, func(stored *domain.Chat) {
+		stored.ArchivedAt = nil
+	})
+}
+
+func (r *MemoryChatRepo) SoftDeleteChat(ctx context.Context, chatId string, deletedAt time.Time) error {
+	return r.updateChat(ctx, chatId, func(stored *domain.Chat) {
+		if stored.DeletedAt == nil {
+			stored.DeletedAt = &deletedAt
+		}
+	})
+}
+
+func (r *MemoryChatRepo) RestoreChat(ctx context.Context, chatId string) error {
+	return r.updateChat(ctx, chatId, func(stored *domain.Chat) {
+		stored.DeletedAt = nil
+	})
+}
+
+func (r *MemoryChatRepo) PurgeDeletedChats(ctx context.Context, deletedBefore time.Time, limit int) (*domain.ChatPurgeResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	result := &domain.ChatPurgeResult{ChatIDs: []string{}, StorageKeys: []string{}}
+
+	r.mu.RLock()
+	deleted := make([]domain.Chat, 0)
+	for _, chat := range r.chats {
+		if chat.DeletedAt != nil && chat.DeletedAt.Before(deletedBefore) {
+			deleted = append(deleted, chat)
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(deleted, func(i, j int) bool { return deleted[i].DeletedAt.Before(*deleted[j].DeletedAt) })
+	if len(deleted) > limit {
+		deleted = deleted[:limit]
+	}
+	for _, chat := range deleted {
+		result.ChatIDs = append(result.ChatIDs, chat.Id)
+	}
+	if len(result.ChatIDs) == 0 {
+		return result, nil
+	}
+	result.StorageKeys = append(result.StorageKeys, r.removeChats(result.ChatIDs)...)
+	return result, nil
+}
+
+func (r *MemoryChatRepo) GetChatParticipants(ctx context.Context, chatId string) ([]domain.ChatParticipant, error) {
+	return r.filterParticipants(ctx, chatId, func(domain.ChatParticipant) bool { return true })
+}
+
+func (r *MemoryChatRepo) AddParticipantToChat(ctx context.Context, participant *domain.ChatParticipant) (*domain.ChatParticipant, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.insertParticipant(participant); err != nil {
+		return nil, err
+	}
+	return participant, nil
+}
+
+func (r *MemoryChatRepo) AddParticipantsToChat(ctx context.Context, chatId string, participants []*domain.ChatParticipant) (map[string]*domain.ChatParticipant, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing := make(map[string]*domain.ChatParticipant)
+	for _, participant := range participants {
+		if i := r.participantIndex(chatId, participant.UserId); i >= 0 {
+			found := &r.participants[chatId][i]
+			if !found.Status.HasLeft() {
+				copied := *found
+				existing[participant.UserId] = &copied
+				continue
+			}
+			found.Role = participant.Role
+			found.Status = participant.Status
+			found.LeftAt = nil
+			participant.Id = found.Id
+			participant.JoinedAt = found.JoinedAt
+			continue
+		}
+		if err := r.insertParticipant(participant); err != nil {
+			return nil, err
+		}
+	}
+	return existing, nil
+}
+
+func (r *MemoryChatRepo) IsUserParticipantInChat(ctx context.Context, chatId, userId string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.participantIndex(chatId, userId) >= 0, nil
+}
+
+func (r *MemoryChatRepo) RemoveParticipant(ctx context.Context, chatId, userId string, removedAt time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i := r.participantIndex(chatId, userId)
+	if i < 0 || r.participants[chatId][i].Status == domain.StatusRemoved {
+		return domain.ErrParticipantNotFound
+	}
+	participant := &r.participants[chatId][i]
+	participant.Status = domain.StatusRemoved
+	participant.LeftAt = &removedAt
+	return nil
+}
+
+func (r *MemoryChatRepo) GetChatParticipant(ctx context.Context, chatId, userId string) (*domain.ChatParticipant, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	i := r.participantIndex(chatId, userId)
+	if i < 0 {
+		return nil, domain.ErrParticipantNotFound
+	}
+	participant := r.participants[chatId][i]
+	return &participant, nil
+}
+
+func (r *MemoryChatRepo) GetChatParticipantsByStatus(ctx context.Context, chatId string, status domain.ParticipantStatus) ([]domain.ChatParticipant, error) {
+	return r.filterParticipants(ctx, chatId, func(participant domain.ChatParticipant) bool {
+		return participant.Status == status
+	})
+}
+
+func (r *MemoryChatRepo) UpdateParticipantStatus(ctx context.Context, chatId, userId string, status domain.ParticipantStatus) error {
+	return r.updateParticipant(ctx, chatId, userId, func(participant *domain.ChatParticipant) {
+		participant.Status = status
+	})
+}
+
+func (r *MemoryChatRepo) LeaveChat(ctx context.Context, chatId, userId string, leftAt time.Time) error {
+	return r.updateParticipant(ctx, chatId, userId, func(participant *domain.ChatParticipant) {
+		if participant.Status == domain.StatusActive {
+			participant.Status = domain.StatusLeft
+			participant.LeftAt = &leftAt
+		}
+	})
+}
+
+func (r *MemoryChatRepo) RejoinChat(ctx context.Context, chatId, userId string) error {
+	return r.updateParticipant(ctx, chatId, userId, func(participant *domain.ChatParticipant) {
+		if participant.Status == domain.StatusLeft {
+			participant.Status = domain.StatusActive
+			participant.LeftAt = nil
+		}
+	})
+}
+
+func (r *MemoryChatRepo) CreateInvitation(ctx context.Context, invitation *domain.ChatInvitation) (*domain.ChatInvitation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.chats[invitation.ChatId]; !ok {
+		return nil, fmt.Errorf("chat %s does not exist", invitation.ChatId)
+	}
+	for _, existing := range r.invitations {
+		if existing.Id == invitation.Id || existing.Token == invitation.Token {
+			return nil, fmt.Errorf("invitation %s already exists", invitation.Id)
+		}
+	}
+	r.invitations[invitation.Id] = *invitation
+	return invitation, nil
+}
+
+func (r *MemoryChatRepo) GetInvitationsByChatId(ctx context.Context, chatId string) ([]domain.ChatInvitation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	invitations := make([]domain.ChatInvitation, 0)
+	for _, invitation := range r.invitations {
+		if invitation.ChatId == chatId {
+			invitations = append(invitations, invitation)
+		}
+	}
+	sort.Slice(invitations, func(i, j int) bool { return invitations[i].CreatedAt.After(invitations[j].CreatedAt) })
+	return invitations, nil
+}
+
+func (r *MemoryChatRepo) GetInvitationByToken(ctx context.Context, token string) (*domain.ChatInvitation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, invitation := range r.invitations {
+		if invitation.Token == token {
+			return &invitation, nil
+		}
+	}
+	return nil, domain.ErrInvitationNotFound
+}
+
+func (r *MemoryChatRepo) RevokeInvitation(ctx context.Context, chatId, invitationId string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	invitation, ok := r.invitations[invitationId]
+	if !ok || invitation.ChatId != chatId || invitation.RevokedAt != nil {
+		return domain.ErrInvitationNotFound
+	}
+	now := time.Now().UTC()
+	invitation.RevokedAt = &now
+	r.invitations[invitationId] = invitation
+	return nil
+}
+
+// RedeemInvitation checks the invitation and adds the participant under one lock, so concurrent redemptions
+// cannot exceed max_uses.
+func (r *MemoryChatRepo) RedeemInvitation(ctx context.Context, invitationId string, participant *domain.ChatParticipant) (*domain.ChatParticipant, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	invitation, ok := r.invitations[invitationId]
+	now := time.Now().UTC()
+	if !ok || invitation.RevokedAt != nil || (invitation.ExpiresAt != nil && !invitation.ExpiresAt.After(now)) ||
+		(invitation.MaxUses != nil && invitation.Uses >= *invitation.MaxUses) {
+		return nil, domain.ErrInvitationExhausted
+	}
+	if err := r.insertParticipant(participant); err != nil {
+		return nil, err
+	}
+	invitation.Uses++
+	r.invitations[invitationId] = invitation
+	return participant, nil
+}
+
+func (r *MemoryChatRepo) findChat(ctx context.Context, match func(domain.Chat) bool) (*domain.Chat, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, chat := range r.chats {
+		if match(chat) {
+			return &chat, nil
+		}
+	}
+	return nil, domain.ErrChatNotFound
+}
+
+func (r *MemoryChatRepo) updateChat(ctx context.Context, chatId string, update func(*domain.Chat)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if chat, ok := r.chats[chatId]; ok {
+		update(&chat)
+		r.chats[chatId] = chat
+	}
+	return nil
+}
+
+// insertChat must be called with the lock held.
+func (r *MemoryChatRepo) insertChat(chat *domain.Chat) error {
+	if _, ok := r.chats[chat.Id]; ok {
+		return fmt.Errorf("chat %s already exists", chat.Id)
+	}
+	r.chats[chat.Id] = *chat
+	return nil
+}
+
+// removeChats hard deletes the chats with everything belonging to them and returns the attachment storage keys
+// reported by the cascades. The cascades run without the lock, as they may read chats themselves.
+func (r *MemoryChatRepo) removeChats(chatIds []string) []string {
+	r.mu.Lock()
+	for _, chatId := range chatIds {
+		delete(r.chats, chatId)
+		delete(r.participants, chatId)
+		for id, invitation := range r.invitations {
+			if invitation.ChatId == chatId {
+				delete(r.invitations, id)
+			}
+		}
+	}
+	cascades := r.cascades
+	r.mu.Unlock()
+
+	storageKeys := []string{}
+	for _, cascade := range cascades {
+		storageKeys = append(storageKeys, cascade(chatIds)...)
+	}
+	return storageKeys
+}
+
+// insertParticipant assigns the id and join time like the database insert. It must be called with the lock held.
+func (r *MemoryChatRepo) insertParticipant(participant *domain.ChatParticipant) error {
+	if _, ok := r.chats[participant.ChatId]; !ok {
+		return fmt.Errorf("chat %s does not exist", participant.ChatId)
+	}
+	if r.participantIndex(participant.ChatId, participant.UserId) >= 0 {
+		return domain.ErrAlreadyParticipant
+	}
+	id, err := uuid.NewV7()
+	if err != nil {
+		return err
+	}
+	participant.Id = id.String()
+	participant.JoinedAt = time.Now().UTC()
+	r.participants[participant.ChatId] = append(r.participants[participant.ChatId], *participant)
+	return nil
+}
+
+// participantIndex must be called with the lock held.
+func (r *MemoryChatRepo) participantIndex(chatId, userId string) int {
+	for i, participant := range r.participants[chatId] {
+		if participant.UserId == userId {
+			return i
+		}
+	}
+	return -1
+}
+
+func (r *MemoryChatRepo) updateParticipant(ctx context.Context, chatId, userId string, update func(*domain.ChatParticipant)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if i := r.participantIndex(chatId, userId); i >= 0 {
+		update(&r.participants[chatId][i])
+	}
+	return nil
+}
+
+func (r *MemoryChatRepo) filterParticipants(ctx context.Context, chatId string, match func(domain.ChatParticipant) bool) ([]domain.ChatParticipant, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	participants := make([]domain.ChatParticipant, 0)
+	for _, participant := range r.participants[chatId] {
+		if match(participant) {
+			participants = append(participants, participant)
+		}
+	}
+	sort.SliceStable(participants, func(i, j int) bool { return participants[i].JoinedAt.Before(participants[j].JoinedAt) })
+	return participants, nil
+}
+
+// GetUserGroupEventLog has no lock to take, the memory unit of work already runs one unit at a time.
+func (r *MemoryChatRepo) GetUserGroupEventLog(ctx context.Context, userGroupId int) (domain.UserGroupEventLog, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	log := make(domain.UserGroupEventLog)
+	for _, event := range r.userGroupEvents {
+		if event.UserGroupId != userGroupId {
+			continue
+		}
+		memberId := event.MemberId()
+		if last, ok := log[memberId]; !ok || event.OccurredAt.After(last) {
+			log[memberId] = event.OccurredAt
+		}
+	}
+	return log, nil
+}
+
+func (r *MemoryChatRepo) RecordUserGroupEvent(ctx context.Context, event *domain.UserGroupEvent) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.userGroupEvents[event.EventId]; ok {
+		return false, nil
+	}
+	r.userGroupEvents[event.EventId] = *event
+	return true, nil
+}
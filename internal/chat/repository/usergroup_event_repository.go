@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/HappYness-Project/ChatBackendServer/dbs"
+	"github.com/HappYness-Project/ChatBackendServer/internal/chat/domain"
+)
+
+// GetUserGroupEventLog returns when the user group and each of its members last changed. It takes the advisory
+// lock on the group id that EnsureUserGroupChat uses, so inside a unit of work the events of a group are applied
+// one at a time.
+func (r *ChatRepo) GetUserGroupEventLog(ctx context.Context, userGroupId int) (domain.UserGroupEventLog, error) {
+	if _, err := r.conn(ctx).ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, userGroupId); err != nil {
+		return nil, err
+	}
+	rows, err := r.conn(ctx).QueryContext(ctx, `SELECT COALESCE(user_id::text, ''), MAX(occurred_at)
+							FROM public.usergroup_event
+							WHERE usergroup_id = $1
+							GROUP BY user_id`, userGroupId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	log := make(domain.UserGroupEventLog)
+	for rows.Next() {
+		var userId string
+		var occurredAt time.Time
+		if err := rows.Scan(&userId, &occurredAt); err != nil {
+			return nil, err
+		}
+		log[userId] = occurredAt
+	}
+	return log, rows.Err()
+}
+
+// RecordUserGroupEvent stores the event and reports false when an event with the same id was recorded before.
+func (r *ChatRepo) RecordUserGroupEvent(ctx context.Context, event *domain.UserGroupEvent) (bool, error) {
+	result, err := r.conn(ctx).ExecContext(ctx, `INSERT INTO public.usergroup_event (event_id, usergroup_id, user_id, type, occurred_at)
+						 VALUES ($1, $2, NULLIF($3, '')::uuid, $4, $5)
+						 ON CONFLICT (event_id) DO NOTHING`,
+		event.EventId, event.UserGroupId, event.MemberId(), string(event.Type), event.OccurredAt)
+	if err != nil {
+		return false, dbs.TranslateError(err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
@@ -0,0 +1,118 @@
+package route
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/HappYness-Project/ChatBackendServer/common"
+	domain "github.com/HappYness-Project/ChatBackendServer/internal/chat/domain"
+	"github.com/go-chi/chi/v5"
+)
+
+// ArchiveChat makes the chat read-only. Members keep access to the history but cannot post or invite anymore.
+func (h *Handler) ArchiveChat(w http.ResponseWriter, r *http.Request) {
+	chatID := chi.URLParam(r, "chatID")
+	chat, ok := h.findChat(r.Context(), w, chatID)
+	if !ok {
+		return
+	}
+	if _, ok := h.requireChatAdmin(w, r, chatID); !ok {
+		return
+	}
+
+	if err := chat.Archive(time.Now().UTC()); err != nil {
+		common.WriteError(w, err)
+		return
+	}
+	if err := h.chatRepo.ArchiveChat(r.Context(), chat.Id, *chat.ArchivedAt); err != nil {
+		h.writeError(w, err, "Failed to archive chat")
+		return
+	}
+
+	h.connections.PublishToChat(chat.Id, EventChatUpdated, chat)
+	common.WriteJsonWithEncode(w, http.StatusOK, chat)
+}
+
+func (h *Handler) UnarchiveChat(w http.ResponseWriter, r *http.Request) {
+	chatID := chi.URLParam(r, "chatID")
+	chat, ok := h.findChat(r.Context(), w, chatID)
+	if !ok {
+		return
+	}
+	if _, ok := h.requireChatAdmin(w, r, chatID); !ok {
+		return
+	}
+
+	if err := chat.Unarchive(); err != nil {
+		common.WriteError(w, err)
+		return
+	}
+	if err := h.chatRepo.UnarchiveChat(r.Context(), chat.Id); err != nil {
+		h.writeError(w, err, "Failed to unarchive chat")
+		return
+	}
+
+	h.connections.PublishToChat(chat.Id, EventChatUpdated, chat)
+	common.WriteJsonWithEncode(w, http.StatusOK, chat)
+}
+
+// RestoreChat undoes the deletion of a chat that has not been purged yet. Participants are kept while a chat
+// is deleted, so its admins are the ones who may restore it.
+func (h *Handler) RestoreChat(w http.ResponseWriter, r *http.Request) {
+	chatID := chi.URLParam(r, "chatID")
+	chat, err := h.chatRepo.GetDeletedChatById(r.Context(), chatID)
+	if err != nil {
+		h.writeError(w, err, "Failed to retrieve deleted chat")
+		return
+	}
+	if _, ok := h.requireChatAdmin(w, r, chatID); !ok {
+		return
+	}
+
+	if err := chat.Restore(time.Now().UTC()); err != nil {
+		common.WriteError(w, err)
+		return
+	}
+	if err := h.chatRepo.RestoreChat(r.Context(), chat.Id); err != nil {
+		h.writeError(w, err, "Failed to restore chat")
+		return
+	}
+
+	h.logger.Info().Msg("Restored chat " + chat.Id)
+	common.WriteJsonWithEncode(w, http.StatusOK, chat)
+}
+
+// softDeleteChat hides the chat until it is restored or purged.
+func (h *Handler) softDeleteChat(ctx context.Context, w http.ResponseWriter, chat *domain.Chat) bool {
+	deletedAt := time.Now().UTC()
+	if err := h.chatRepo.SoftDeleteChat(ctx, chat.Id, deletedAt); err != nil {
+		h.writeError(w, err, "Failed to delete chat")
+		return false
+	}
+	chat.DeletedAt = &deletedAt
+	h.notifyChatDeleted(chat)
+	return true
+}
+
+// notifyChatDeleted tells connected members that the chat is gone and closes their connections.
+func (h *Handler) notifyChatDeleted(chat *domain.Chat) {
+	h.connections.PublishToChat(chat.Id, EventChatDeleted, map[string]interface{}{
+		"chat_id":          chat.Id,
+		"restorable_until": chat.RestorableUntil(),
+	})
+	h.connections.DisconnectChat(chat.Id)
+}
+
+// findWritableChat is findChat for changes, archived chats are read-only and answered with a conflict.
+func (h *Handler) findWritableChat(ctx context.Context, w http.ResponseWriter, chatID string) (*domain.Chat, bool) {
+	chat, ok := h.findChat(ctx, w, chatID)
+	if !ok {
+		return nil, false
+	}
+	if chat.IsArchived() {
+		common.WriteError(w, domain.ErrChatArchived)
+		return nil, false
+	}
+	return chat, true
+}
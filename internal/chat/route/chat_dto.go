@@ -1,12 +1,73 @@
 package route
 
+import (
+	"time"
+
+	domain "github.com/HappYness-Project/ChatBackendServer/internal/chat/domain"
+)
+
 type AddParticipantRequest struct {
-	UserId  string `json:"user_id" validate:"required"`
-	GroupId int    `json:"group_id" validate:"required"`
+	UserId string `json:"user_id" validate:"required,uuid"`
+	Role   string `json:"role,omitempty" validate:"omitempty,oneof=admin member"`
+}
+type AddParticipantsRequest struct {
+	Participants []AddParticipantRequest `json:"participants" validate:"required,min=1,max=100"`
+}
+
+const (
+	ParticipantResultAdded         = "added"
+	ParticipantResultAlreadyMember = "already_member"
+	ParticipantResultInvalid       = "invalid"
+)
+
+type AddParticipantResult struct {
+	UserId      string                  `json:"user_id"`
+	Result      string                  `json:"result"`
+	Detail      string                  `json:"detail,omitempty"`
+	Participant *domain.ChatParticipant `json:"participant,omitempty"`
 }
 type CreateChatRequest struct {
 	Type        string  `json:"type"`
 	UserGroupId *int    `json:"usergroup_id,omitempty"`
 	ContainerId *string `json:"container_id,omitempty"`
 	UserId      string  `json:"user_id,omitempty"`
+	UpdateChatRequest
+}
+type UpdateChatRequest struct {
+	Name        *string              `json:"name,omitempty"`
+	Description *string              `json:"description,omitempty"`
+	AvatarUrl   *string              `json:"avatar_url,omitempty"`
+	Settings    *ChatSettingsRequest `json:"settings,omitempty"`
+}
+type ChatSettingsRequest struct {
+	WhoCanPost       *string `json:"who_can_post,omitempty"`
+	WhoCanInvite     *string `json:"who_can_invite,omitempty"`
+	SlowModeSeconds  *int    `json:"slow_mode_seconds,omitempty"`
+	RetentionSeconds *int    `json:"retention_seconds,omitempty"`
+}
+
+func (r UpdateChatRequest) ChatUpdate() domain.ChatUpdate {
+	update := domain.ChatUpdate{
+		Name:        r.Name,
+		Description: r.Description,
+		AvatarUrl:   r.AvatarUrl,
+	}
+	if r.Settings != nil {
+		if r.Settings.WhoCanPost != nil {
+			level := domain.PermissionLevel(*r.Settings.WhoCanPost)
+			update.WhoCanPost = &level
+		}
+		if r.Settings.WhoCanInvite != nil {
+			level := domain.PermissionLevel(*r.Settings.WhoCanInvite)
+			update.WhoCanInvite = &level
+		}
+		update.SlowModeSeconds = r.Settings.SlowModeSeconds
+		update.RetentionSeconds = r.Settings.RetentionSeconds
+	}
+	return update
+}
+
+type CreateInvitationRequest struct {
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	MaxUses   *int       `json:"max_uses,omitempty"`
 }
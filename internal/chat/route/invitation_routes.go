@@ -0,0 +1,311 @@
+package route
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/HappYness-Project/ChatBackendServer/common"
+	domain "github.com/HappYness-Project/ChatBackendServer/internal/chat/domain"
+	"github.com/go-chi/chi/v5"
+)
+
+// CreateInvitation creates an invitation link. Depending on the chat settings only admins or all
+// active members may invite.
+func (h *Handler) CreateInvitation(w http.ResponseWriter, r *http.Request) {
+	chatID := chi.URLParam(r, "chatID")
+	chat, ok := h.findWritableChat(r.Context(), w, chatID)
+	if !ok {
+		return
+	}
+	userId, ok := h.currentUserId(w, r)
+	if !ok {
+		return
+	}
+	participant, ok := h.findParticipant(r.Context(), w, chatID, userId)
+	if !ok {
+		return
+	}
+	if !chat.CanInvite(participant) {
+		common.ErrorResponse(w, http.StatusForbidden, common.ProblemDetails{
+			Title:     "Forbidden",
+			ErrorCode: "InvitePermissionRequired",
+			Detail:    "The chat settings do not allow this user to invite others",
+		})
+		return
+	}
+
+	var request CreateInvitationRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		common.ErrorResponse(w, http.StatusBadRequest, common.ProblemDetails{
+			Title:     "Invalid Request Body",
+			ErrorCode: "InvalidJSON",
+			Detail:    "Unable to decode request body as JSON",
+		})
+		return
+	}
+
+	invitation, err := domain.NewChatInvitation(chatID, userId, request.ExpiresAt, request.MaxUses)
+	if err != nil {
+		common.ErrorResponse(w, http.StatusBadRequest, common.ProblemDetails{
+			Title:     "Invalid Request",
+			ErrorCode: "InvalidInvitationData",
+			Detail:    err.Error(),
+		})
+		return
+	}
+
+	createdInvitation, err := h.chatRepo.CreateInvitation(r.Context(), invitation)
+	if err != nil {
+		h.writeError(w, err, "Failed to create chat invitation")
+		return
+	}
+
+	common.WriteJsonWithEncode(w, http.StatusCreated, createdInvitation)
+}
+
+func (h *Handler) GetInvitations(w http.ResponseWriter, r *http.Request) {
+	chatID := chi.URLParam(r, "chatID")
+	if _, ok := h.findChat(r.Context(), w, chatID); !ok {
+		return
+	}
+	if _, ok := h.requireChatAdmin(w, r, chatID); !ok {
+		return
+	}
+
+	invitations, err := h.chatRepo.GetInvitationsByChatId(r.Context(), chatID)
+	if err != nil {
+		h.writeError(w, err, "Failed to retrieve chat invitations")
+		return
+	}
+
+	common.WriteJsonWithEncode(w, http.StatusOK, map[string]interface{}{
+		"invitations": invitations,
+		"count":       len(invitations),
+	})
+}
+
+func (h *Handler) RevokeInvitation(w http.ResponseWriter, r *http.Request) {
+	chatID := chi.URLParam(r, "chatID")
+	invitationID := chi.URLParam(r, "invitationID")
+	if _, ok := h.findChat(r.Context(), w, chatID); !ok {
+		return
+	}
+	if _, ok := h.requireChatAdmin(w, r, chatID); !ok {
+		return
+	}
+
+	if err := h.chatRepo.RevokeInvitation(r.Context(), chatID, invitationID); err != nil {
+		h.writeError(w, err, "Failed to revoke chat invitation")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// JoinByInvitation redeems an invitation token. The caller is added as a pending participant
+// and has to be accepted by a chat admin before becoming active.
+func (h *Handler) JoinByInvitation(w http.ResponseWriter, r *http.Request) {
+	userId, ok := h.currentUserId(w, r)
+	if !ok {
+		return
+	}
+
+	invitation, err := h.chatRepo.GetInvitationByToken(r.Context(), chi.URLParam(r, "token"))
+	if err != nil {
+		h.writeError(w, err, "Failed to retrieve chat invitation")
+		return
+	}
+	if err := invitation.CanBeRedeemed(time.Now().UTC()); err != nil {
+		common.WriteError(w, err)
+		return
+	}
+	if _, ok := h.findWritableChat(r.Context(), w, invitation.ChatId); !ok {
+		return
+	}
+	if !h.ensureNotParticipant(r.Context(), w, invitation.ChatId, userId) {
+		return
+	}
+
+	participant, err := domain.NewChatParticipant(invitation.ChatId, userId, domain.RoleMember, domain.StatusPending)
+	if err != nil {
+		common.ErrorResponse(w, http.StatusBadRequest, common.ProblemDetails{
+			Title:     "Invalid Request",
+			ErrorCode: "InvalidParticipantData",
+			Detail:    err.Error(),
+		})
+		return
+	}
+
+	createdParticipant, err := h.chatRepo.RedeemInvitation(r.Context(), invitation.Id, participant)
+	if err != nil {
+		h.writeError(w, err, "Failed to redeem chat invitation")
+		return
+	}
+
+	common.WriteJsonWithEncode(w, http.StatusAccepted, createdParticipant)
+}
+
+// CreateJoinRequest lets the caller ask to join a chat without an invitation.
+func (h *Handler) CreateJoinRequest(w http.ResponseWriter, r *http.Request) {
+	chatID := chi.URLParam(r, "chatID")
+	userId, ok := h.currentUserId(w, r)
+	if !ok {
+		return
+	}
+	if _, ok := h.findWritableChat(r.Context(), w, chatID); !ok {
+		return
+	}
+	if !h.ensureNotParticipant(r.Context(), w, chatID, userId) {
+		return
+	}
+
+	participant, err := domain.NewChatParticipant(chatID, userId, domain.RoleMember, domain.StatusPending)
+	if err != nil {
+		common.ErrorResponse(w, http.StatusBadRequest, common.ProblemDetails{
+			Title:     "Invalid Request",
+			ErrorCode: "InvalidParticipantData",
+			Detail:    err.Error(),
+		})
+		return
+	}
+
+	createdParticipant, err := h.chatRepo.AddParticipantToChat(r.Context(), participant)
+	if err != nil {
+		h.writeError(w, err, "Failed to create join request")
+		return
+	}
+
+	common.WriteJsonWithEncode(w, http.StatusAccepted, createdParticipant)
+}
+
+func (h *Handler) GetJoinRequests(w http.ResponseWriter, r *http.Request) {
+	chatID := chi.URLParam(r, "chatID")
+	if _, ok := h.findChat(r.Context(), w, chatID); !ok {
+		return
+	}
+	if _, ok := h.requireChatAdmin(w, r, chatID); !ok {
+		return
+	}
+
+	pending, err := h.chatRepo.GetChatParticipantsByStatus(r.Context(), chatID, domain.StatusPending)
+	if err != nil {
+		h.writeError(w, err, "Failed to retrieve join requests")
+		return
+	}
+
+	common.WriteJsonWithEncode(w, http.StatusOK, map[string]interface{}{
+		"join_requests": pending,
+		"count":         len(pending),
+	})
+}
+
+func (h *Handler) AcceptJoinRequest(w http.ResponseWriter, r *http.Request) {
+	chatID := chi.URLParam(r, "chatID")
+	if _, ok := h.requireChatAdmin(w, r, chatID); !ok {
+		return
+	}
+	participant, ok := h.findJoinRequest(r.Context(), w, chatID, chi.URLParam(r, "userID"))
+	if !ok {
+		return
+	}
+
+	if err := participant.Approve(); err != nil {
+		common.ErrorResponse(w, http.StatusConflict, common.ProblemDetails{
+			Title:     "Conflict",
+			ErrorCode: "JoinRequestNotPending",
+			Detail:    err.Error(),
+		})
+		return
+	}
+	if err := h.chatRepo.UpdateParticipantStatus(r.Context(), chatID, participant.UserId, participant.Status); err != nil {
+		h.writeError(w, err, "Failed to accept join request")
+		return
+	}
+
+	h.logger.Info().Msg("Accepted join request of user " + participant.UserId + " for chat " + chatID)
+	common.WriteJsonWithEncode(w, http.StatusOK, participant)
+}
+
+func (h *Handler) RejectJoinRequest(w http.ResponseWriter, r *http.Request) {
+	chatID := chi.URLParam(r, "chatID")
+	if _, ok := h.requireChatAdmin(w, r, chatID); !ok {
+		return
+	}
+	participant, ok := h.findJoinRequest(r.Context(), w, chatID, chi.URLParam(r, "userID"))
+	if !ok {
+		return
+	}
+
+	if err := h.chatRepo.RemoveParticipant(r.Context(), chatID, participant.UserId, time.Now().UTC()); err != nil {
+		h.writeError(w, err, "Failed to reject join request")
+		return
+	}
+
+	h.logger.Info().Msg("Rejected join request of user " + participant.UserId + " for chat " + chatID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) findChat(ctx context.Context, w http.ResponseWriter, chatID string) (*domain.Chat, bool) {
+	chat, err := h.chatRepo.GetChatById(ctx, chatID)
+	if err != nil {
+		h.writeError(w, err, "Failed to retrieve chat by ID")
+		return nil, false
+	}
+	return chat, true
+}
+
+func (h *Handler) findJoinRequest(ctx context.Context, w http.ResponseWriter, chatID, userID string) (*domain.ChatParticipant, bool) {
+	participant, err := h.chatRepo.GetChatParticipant(ctx, chatID, userID)
+	if errors.Is(err, domain.ErrParticipantNotFound) || err == nil && !participant.IsPending() {
+		err = domain.ErrJoinRequestNotFound
+	}
+	if err != nil {
+		h.writeError(w, err, "Failed to retrieve chat participant")
+		return nil, false
+	}
+	return participant, true
+}
+
+func (h *Handler) ensureNotParticipant(ctx context.Context, w http.ResponseWriter, chatID, userId string) bool {
+	existing, err := h.chatRepo.GetChatParticipant(ctx, chatID, userId)
+	if errors.Is(err, domain.ErrParticipantNotFound) {
+		return true
+	}
+	if err != nil {
+		h.writeError(w, err, "Failed to check if user is participant")
+		return false
+	}
+
+	switch existing.Status {
+	case domain.StatusPending:
+		common.ErrorResponse(w, http.StatusConflict, common.ProblemDetails{
+			Title:     "Conflict",
+			ErrorCode: "JoinRequestPending",
+			Detail:    "A join request for this chat is already pending approval",
+		})
+	case domain.StatusLeft:
+		common.ErrorResponse(w, http.StatusConflict, common.ProblemDetails{
+			Title:     "Conflict",
+			ErrorCode: "UserLeftChat",
+			Detail:    "User has left this chat and can rejoin it instead",
+		})
+	case domain.StatusRemoved:
+		common.ErrorResponse(w, http.StatusForbidden, common.ProblemDetails{
+			Title:     "Forbidden",
+			ErrorCode: "UserRemoved",
+			Detail:    "User was removed from this chat and can only be added again by an admin",
+		})
+	case domain.StatusBanned:
+		common.ErrorResponse(w, http.StatusForbidden, common.ProblemDetails{
+			Title:     "Forbidden",
+			ErrorCode: "UserBanned",
+			Detail:    "User is banned from this chat",
+		})
+	default:
+		common.WriteError(w, domain.ErrAlreadyParticipant)
+	}
+	return false
+}
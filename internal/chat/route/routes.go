@@ -1,30 +1,62 @@
 package route
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/HappYness-Project/ChatBackendServer/common"
+	"github.com/HappYness-Project/ChatBackendServer/dbs"
 	domain "github.com/HappYness-Project/ChatBackendServer/internal/chat/domain"
 	"github.com/HappYness-Project/ChatBackendServer/internal/chat/repository"
+	messageDomain "github.com/HappYness-Project/ChatBackendServer/internal/message/domain"
 	"github.com/HappYness-Project/ChatBackendServer/loggers"
 	"github.com/go-chi/chi/v5"
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// ConnectionManager gives the chat handlers access to the live websocket connections of a chat.
+type ConnectionManager interface {
+	DisconnectUser(chatId, userId string)
+	DisconnectChat(chatId string)
+	PublishToChat(chatId string, event string, data interface{})
+	SendToClients(msg messageDomain.Message, logger *loggers.AppLogger)
+}
+
+// MessageStore stores the system messages the chat handlers post about changes to a chat.
+type MessageStore interface {
+	Create(ctx context.Context, message messageDomain.Message) error
+}
+
+const (
+	EventChatUpdated = "chat.updated"
+	EventChatDeleted = "chat.deleted"
+)
+
 type Handler struct {
-	logger    *loggers.AppLogger
-	chatRepo  repository.ChatRepo
-	jwtSecret []byte
+	logger        *loggers.AppLogger
+	chatRepo      repository.ChatRepository
+	messageRepo   MessageStore
+	unitOfWork    dbs.UnitOfWork
+	connections   ConnectionManager
+	jwtSecret     []byte
+	webhookSecret []byte
 }
 
-func NewHandler(logger *loggers.AppLogger, chatRepo repository.ChatRepo, secretKey string) *Handler {
+func NewHandler(logger *loggers.AppLogger, chatRepo repository.ChatRepository, messageRepo MessageStore,
+	unitOfWork dbs.UnitOfWork, connections ConnectionManager, secretKey string, webhookSecret string) *Handler {
 	return &Handler{
-		logger:    logger,
-		chatRepo:  chatRepo,
-		jwtSecret: []byte(secretKey),
+		logger:        logger,
+		chatRepo:      chatRepo,
+		messageRepo:   messageRepo,
+		unitOfWork:    unitOfWork,
+		connections:   connections,
+		jwtSecret:     []byte(secretKey),
+		webhookSecret: []byte(webhookSecret),
 	}
 }
 
@@ -32,11 +64,26 @@ func (h *Handler) RegisterRoutes(router chi.Router) {
 	router.Get("/api/chats/{chatID}", h.GetChatById)
 	router.Get("/api/user-groups/{groupID}/chat", h.GetChatByGroupID)
 	router.Post("/api/chats", h.CreateChat)
+	router.Patch("/api/chats/{chatID}", h.UpdateChat)
 	router.Delete("/api/chats/{chatID}", h.RemoveChat)
 	router.Delete("/api/user-groups/{groupID}/chat", h.RemoveChatByUserGroupId)
+	router.Post("/api/chats/{chatID}/archive", h.ArchiveChat)
+	router.Post("/api/chats/{chatID}/unarchive", h.UnarchiveChat)
+	router.Post("/api/chats/{chatID}/restore", h.RestoreChat)
 	router.Get("/api/chats/{chatID}/chat-participants", h.GetChatParticipants)
 	router.Post("/api/chats/{chatID}/chat-participants", h.AddChatParticipant)
 	router.Delete("/api/chats/{chatID}/chat-participants/{participantID}", h.DeleteParticipantFromChat)
+	router.Post("/api/chats/{chatID}/leave", h.LeaveChat)
+	router.Post("/api/chats/{chatID}/rejoin", h.RejoinChat)
+	router.Post("/api/chats/{chatID}/invitations", h.CreateInvitation)
+	router.Get("/api/chats/{chatID}/invitations", h.GetInvitations)
+	router.Delete("/api/chats/{chatID}/invitations/{invitationID}", h.RevokeInvitation)
+	router.Post("/api/invitations/{token}/join", h.JoinByInvitation)
+	router.Post("/api/chats/{chatID}/join-requests", h.CreateJoinRequest)
+	router.Get("/api/chats/{chatID}/join-requests", h.GetJoinRequests)
+	router.Post("/api/chats/{chatID}/join-requests/{userID}/accept", h.AcceptJoinRequest)
+	router.Post("/api/chats/{chatID}/join-requests/{userID}/reject", h.RejectJoinRequest)
+	router.Post("/api/webhooks/user-groups", h.HandleUserGroupEvent)
 }
 func (h *Handler) GetChatById(w http.ResponseWriter, r *http.Request) {
 	chatID := chi.URLParam(r, "chatID")
@@ -49,22 +96,9 @@ func (h *Handler) GetChatById(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	chat, err := h.chatRepo.GetChatById(chatID)
+	chat, err := h.chatRepo.GetChatById(r.Context(), chatID)
 	if err != nil {
-		h.logger.Error().Err(err).Msg("Failed to retrieve chat by ID")
-		common.ErrorResponse(w, http.StatusInternalServerError, common.ProblemDetails{
-			Title:  "Internal Server Error",
-			Detail: "Error occurred while retrieving chat",
-		})
-		return
-	}
-
-	if chat.Id == "" {
-		common.ErrorResponse(w, http.StatusNotFound, common.ProblemDetails{
-			Title:     "Not Found",
-			ErrorCode: "ChatNotFound",
-			Detail:    "Chat not found with the provided ID",
-		})
+		h.writeError(w, err, "Failed to retrieve chat by ID")
 		return
 	}
 
@@ -88,13 +122,9 @@ func (h *Handler) GetChatByGroupID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	chat, err := h.chatRepo.GetChatByGroupID(groupID)
+	chat, err := h.chatRepo.GetChatByGroupID(r.Context(), groupID)
 	if err != nil {
-		h.logger.Error().Err(err).Msg("Failed to retrieve chat by groupID")
-		common.ErrorResponse(w, http.StatusInternalServerError, common.ProblemDetails{
-			Title:  "Internal Server Error",
-			Detail: "Error occurred during getting chat by group ID",
-		})
+		h.writeError(w, err, "Failed to retrieve chat by groupID")
 		return
 	}
 
@@ -117,6 +147,9 @@ func (h *Handler) CreateChat(w http.ResponseWriter, r *http.Request) {
 		chatType = domain.ChatType(request.Type)
 	}
 	chat, err := domain.NewChat(chatType, request.UserGroupId, request.ContainerId)
+	if err == nil {
+		err = chat.ApplyUpdate(request.ChatUpdate())
+	}
 	if err != nil {
 		common.ErrorResponse(w, http.StatusBadRequest, common.ProblemDetails{
 			Title:     "Invalid Request",
@@ -127,6 +160,7 @@ func (h *Handler) CreateChat(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var createdChat *domain.Chat
+	var notice *messageDomain.Message
 
 	if request.UserId != "" {
 		participant, err := domain.NewChatParticipant(chat.Id, request.UserId, "admin", "active")
@@ -140,68 +174,94 @@ func (h *Handler) CreateChat(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		createdChat, err = h.chatRepo.CreateChatWithParticipant(chat, participant)
+		// The chat is only created together with its first member and the notice opening its timeline.
+		message := messageDomain.NewSystemMessage(chat.Id, request.UserId, messageDomain.NoticeChatCreated, time.Now().UTC())
+		err = h.unitOfWork.Do(r.Context(), func(ctx context.Context) error {
+			var err error
+			if createdChat, err = h.chatRepo.CreateChatWithParticipant(ctx, chat, participant); err != nil {
+				return err
+			}
+			return h.messageRepo.Create(ctx, message)
+		})
 		if err != nil {
-			h.logger.Error().Err(err).Msg("Failed to create chat with participant")
-			common.ErrorResponse(w, http.StatusInternalServerError, common.ProblemDetails{
-				Title:  "Internal Server Error",
-				Detail: "Error occurred while creating chat with participant",
-			})
+			h.writeError(w, err, "Failed to create chat with participant")
 			return
 		}
+		notice = &message
 	} else {
 		// Create chat only
-		createdChat, err = h.chatRepo.CreateChat(chat)
+		createdChat, err = h.chatRepo.CreateChat(r.Context(), chat)
 		if err != nil {
-			h.logger.Error().Err(err).Msg("Failed to create chat")
-			common.ErrorResponse(w, http.StatusInternalServerError, common.ProblemDetails{
-				Title:  "Internal Server Error",
-				Detail: "Error occurred while creating chat",
-			})
+			h.writeError(w, err, "Failed to create chat")
 			return
 		}
 	}
+	if notice != nil {
+		h.connections.SendToClients(*notice, h.logger)
+	}
 	h.logger.Info().Msg("Successfully created chat with ID: " + createdChat.Id)
 	common.WriteJsonWithEncode(w, http.StatusCreated, createdChat)
 }
 
-func (h *Handler) RemoveChat(w http.ResponseWriter, r *http.Request) {
+// UpdateChat changes the chat name, description, avatar or settings. Only chat admins may do this,
+// connected members are notified with a chat.updated event.
+func (h *Handler) UpdateChat(w http.ResponseWriter, r *http.Request) {
 	chatID := chi.URLParam(r, "chatID")
-	if chatID == "" {
+	chat, ok := h.findWritableChat(r.Context(), w, chatID)
+	if !ok {
+		return
+	}
+	if _, ok := h.requireChatAdmin(w, r, chatID); !ok {
+		return
+	}
+
+	var request UpdateChatRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
 		common.ErrorResponse(w, http.StatusBadRequest, common.ProblemDetails{
-			Title:     "Invalid Parameter",
-			ErrorCode: "MissingChatID",
-			Detail:    "chatID is required",
+			Title:     "Invalid Request Body",
+			ErrorCode: "InvalidJSON",
+			Detail:    "Unable to decode request body as JSON",
 		})
 		return
 	}
 
-	chat, err := h.chatRepo.GetChatById(chatID)
-	if err != nil {
-		h.logger.Error().Err(err).Msg("Failed to retrieve chat by ID")
-		common.ErrorResponse(w, http.StatusInternalServerError, common.ProblemDetails{
-			Title:  "Internal Server Error",
-			Detail: "Error occurred while retrieving chat",
+	if err := chat.ApplyUpdate(request.ChatUpdate()); err != nil {
+		common.ErrorResponse(w, http.StatusBadRequest, common.ProblemDetails{
+			Title:     "Invalid Request",
+			ErrorCode: "InvalidChatUpdate",
+			Detail:    err.Error(),
 		})
 		return
 	}
 
-	if chat.Id == "" {
-		common.ErrorResponse(w, http.StatusNotFound, common.ProblemDetails{
-			Title:     "Not Found",
-			ErrorCode: "ChatNotFound",
-			Detail:    "Chat not found with the provided ID",
+	if err := h.chatRepo.UpdateChat(r.Context(), chat); err != nil {
+		h.writeError(w, err, "Failed to update chat")
+		return
+	}
+
+	h.connections.PublishToChat(chat.Id, EventChatUpdated, chat)
+	common.WriteJsonWithEncode(w, http.StatusOK, chat)
+}
+
+// RemoveChat soft deletes the chat. It can be restored within domain.ChatRestoreWindow, after which it is purged.
+func (h *Handler) RemoveChat(w http.ResponseWriter, r *http.Request) {
+	chatID := chi.URLParam(r, "chatID")
+	if chatID == "" {
+		common.ErrorResponse(w, http.StatusBadRequest, common.ProblemDetails{
+			Title:     "Invalid Parameter",
+			ErrorCode: "MissingChatID",
+			Detail:    "chatID is required",
 		})
 		return
 	}
 
-	err = h.chatRepo.DeleteChat(chatID)
+	chat, err := h.chatRepo.GetChatById(r.Context(), chatID)
 	if err != nil {
-		h.logger.Error().Err(err).Msg("Failed to delete chat")
-		common.ErrorResponse(w, http.StatusInternalServerError, common.ProblemDetails{
-			Title:  "Internal Server Error",
-			Detail: "Error occurred while deleting chat",
-		})
+		h.writeError(w, err, "Failed to retrieve chat by ID")
+		return
+	}
+
+	if !h.softDeleteChat(r.Context(), w, chat) {
 		return
 	}
 
@@ -229,32 +289,13 @@ func (h *Handler) RemoveChatByUserGroupId(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	chat, err := h.chatRepo.GetChatByGroupID(groupID)
+	chat, err := h.chatRepo.GetChatByGroupID(r.Context(), groupID)
 	if err != nil {
-		h.logger.Error().Err(err).Msg("Failed to retrieve chat by groupID")
-		common.ErrorResponse(w, http.StatusInternalServerError, common.ProblemDetails{
-			Title:  "Internal Server Error",
-			Detail: "Error occurred while retrieving chat",
-		})
-		return
-	}
-
-	if chat.Id == "" {
-		common.ErrorResponse(w, http.StatusNotFound, common.ProblemDetails{
-			Title:     "Not Found",
-			ErrorCode: "ChatNotFound",
-			Detail:    "Chat not found for the provided group ID",
-		})
+		h.writeError(w, err, "Failed to retrieve chat by groupID")
 		return
 	}
 
-	err = h.chatRepo.DeleteChat(chat.Id)
-	if err != nil {
-		h.logger.Error().Err(err).Msg("Failed to delete chat")
-		common.ErrorResponse(w, http.StatusInternalServerError, common.ProblemDetails{
-			Title:  "Internal Server Error",
-			Detail: "Error occurred while deleting chat",
-		})
+	if !h.softDeleteChat(r.Context(), w, chat) {
 		return
 	}
 
@@ -273,32 +314,14 @@ func (h *Handler) GetChatParticipants(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Verify chat exists first
-	chat, err := h.chatRepo.GetChatById(chatID)
-	if err != nil {
-		h.logger.Error().Err(err).Msg("Failed to retrieve chat by ID")
-		common.ErrorResponse(w, http.StatusInternalServerError, common.ProblemDetails{
-			Title:  "Internal Server Error",
-			Detail: "Error occurred while retrieving chat",
-		})
+	if _, err := h.chatRepo.GetChatById(r.Context(), chatID); err != nil {
+		h.writeError(w, err, "Failed to retrieve chat by ID")
 		return
 	}
 
-	if chat.Id == "" {
-		common.ErrorResponse(w, http.StatusNotFound, common.ProblemDetails{
-			Title:     "Not Found",
-			ErrorCode: "ChatNotFound",
-			Detail:    "Chat not found with the provided ID",
-		})
-		return
-	}
-
-	participants, err := h.chatRepo.GetChatParticipants(chatID)
+	participants, err := h.chatRepo.GetChatParticipants(r.Context(), chatID)
 	if err != nil {
-		h.logger.Error().Err(err).Msg("Failed to retrieve chat participants")
-		common.ErrorResponse(w, http.StatusInternalServerError, common.ProblemDetails{
-			Title:  "Internal Server Error",
-			Detail: "Error occurred while retrieving chat participants",
-		})
+		h.writeError(w, err, "Failed to retrieve chat participants")
 		return
 	}
 
@@ -308,6 +331,9 @@ func (h *Handler) GetChatParticipants(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// AddChatParticipant adds one or more users to a chat in a single transaction. The caller must be allowed to
+// invite by the chat settings, and only admins can add admins.
+// Each requested user gets its own result so partial success is reported back to the caller.
 func (h *Handler) AddChatParticipant(w http.ResponseWriter, r *http.Request) {
 	chatID := chi.URLParam(r, "chatID")
 	if chatID == "" {
@@ -319,7 +345,7 @@ func (h *Handler) AddChatParticipant(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var request AddParticipantRequest
+	var request AddParticipantsRequest
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
 		common.ErrorResponse(w, http.StatusBadRequest, common.ProblemDetails{
 			Title:     "Invalid Request Body",
@@ -328,47 +354,107 @@ func (h *Handler) AddChatParticipant(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
-
-	isParticipant, err := h.chatRepo.IsUserParticipantInChat(chatID, request.UserId)
-	if err != nil {
-		h.logger.Error().Err(err).Msg("Failed to check if user is participant")
-		common.ErrorResponse(w, http.StatusInternalServerError, common.ProblemDetails{
-			Title:  "Internal Server Error",
-			Detail: "Error occurred while checking participant status",
+	if err := common.ValidateStruct(request); err != nil {
+		common.ErrorResponse(w, http.StatusBadRequest, common.ProblemDetails{
+			Title:     "Invalid Request",
+			ErrorCode: "ValidationFailed",
+			Detail:    common.ValidationErrorDetail(err),
 		})
 		return
 	}
-	if isParticipant {
-		common.ErrorResponse(w, http.StatusConflict, common.ProblemDetails{
-			Title:     "Conflict",
-			ErrorCode: "UserAlreadyParticipant",
-			Detail:    "User is already a participant in this chat",
-		})
+
+	chat, ok := h.findWritableChat(r.Context(), w, chatID)
+	if !ok {
 		return
 	}
-
-	// Create participant using domain constructor (includes validation)
-	participant, err := domain.NewChatParticipant(chatID, request.UserId, domain.RoleMember, domain.StatusActive)
+	userId, ok := h.currentUserId(w, r)
+	if !ok {
+		return
+	}
+	caller, err := h.chatRepo.GetChatParticipant(r.Context(), chatID, userId)
+	if errors.Is(err, domain.ErrParticipantNotFound) {
+		err = domain.ErrNotChatParticipant
+	}
 	if err != nil {
-		common.ErrorResponse(w, http.StatusBadRequest, common.ProblemDetails{
-			Title:     "Invalid Request",
-			ErrorCode: "InvalidParticipantData",
-			Detail:    err.Error(),
+		h.writeError(w, err, "Failed to retrieve chat participant")
+		return
+	}
+	if !chat.CanInvite(caller) {
+		common.ErrorResponse(w, http.StatusForbidden, common.ProblemDetails{
+			Title:     "Forbidden",
+			ErrorCode: "InvitePermissionRequired",
+			Detail:    "The chat settings do not allow this user to add participants",
 		})
 		return
 	}
 
-	createdParticipant, err := h.chatRepo.AddParticipantToChat(participant)
+	results := make([]AddParticipantResult, len(request.Participants))
+	toAdd := make([]*domain.ChatParticipant, 0, len(request.Participants))
+	seen := make(map[string]bool, len(request.Participants))
+	for i, item := range request.Participants {
+		results[i] = AddParticipantResult{UserId: item.UserId}
+		if err := common.ValidateStruct(item); err != nil {
+			results[i].Result = ParticipantResultInvalid
+			results[i].Detail = common.ValidationErrorDetail(err)
+			continue
+		}
+		if seen[item.UserId] {
+			results[i].Result = ParticipantResultInvalid
+			results[i].Detail = "user_id is duplicated in the request"
+			continue
+		}
+		seen[item.UserId] = true
+
+		role, err := domain.NewParticipantRole(item.Role)
+		if err != nil {
+			results[i].Result = ParticipantResultInvalid
+			results[i].Detail = err.Error()
+			continue
+		}
+		if role == domain.RoleAdmin && !caller.IsAdmin() {
+			results[i].Result = ParticipantResultInvalid
+			results[i].Detail = "only chat admins can add admins"
+			continue
+		}
+		participant, err := domain.NewChatParticipant(chatID, item.UserId, role, domain.StatusActive)
+		if err != nil {
+			results[i].Result = ParticipantResultInvalid
+			results[i].Detail = err.Error()
+			continue
+		}
+		results[i].Participant = participant
+		toAdd = append(toAdd, participant)
+	}
+
+	existing, err := h.chatRepo.AddParticipantsToChat(r.Context(), chatID, toAdd)
 	if err != nil {
-		h.logger.Error().Err(err).Msg("Failed to add participant to chat")
-		common.ErrorResponse(w, http.StatusInternalServerError, common.ProblemDetails{
-			Title:  "Internal Server Error",
-			Detail: "Error occurred while adding participant to chat",
-		})
+		h.writeError(w, err, "Failed to add participants to chat")
 		return
 	}
 
-	common.WriteJsonWithEncode(w, http.StatusCreated, createdParticipant)
+	added := 0
+	for i := range results {
+		if results[i].Result == ParticipantResultInvalid {
+			continue
+		}
+		if participant, ok := existing[results[i].UserId]; ok {
+			results[i].Result = ParticipantResultAlreadyMember
+			results[i].Detail = "participant status is " + participant.Status.String()
+			results[i].Participant = participant
+			continue
+		}
+		results[i].Result = ParticipantResultAdded
+		added++
+	}
+
+	status := http.StatusOK
+	if added > 0 {
+		status = http.StatusCreated
+	}
+	common.WriteJsonWithEncode(w, status, map[string]interface{}{
+		"results": results,
+		"added":   added,
+	})
 }
 
 func (h *Handler) DeleteParticipantFromChat(w http.ResponseWriter, r *http.Request) {
@@ -392,21 +478,151 @@ func (h *Handler) DeleteParticipantFromChat(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	// Delete the participant from the chat
-	err := h.chatRepo.DeleteParticipantFromChat(chatID, participantID)
+	notice, err := h.removeParticipant(r.Context(), chatID, participantID, messageDomain.NoticeMemberRemoved, func(ctx context.Context) error {
+		return h.chatRepo.RemoveParticipant(ctx, chatID, participantID, time.Now().UTC())
+	})
 	if err != nil {
-		h.logger.Error().Err(err).Msg("Failed to delete participant from chat")
-		common.ErrorResponse(w, http.StatusInternalServerError, common.ProblemDetails{
-			Title:  "Internal Server Error",
-			Detail: "Error occurred while removing participant from chat",
-		})
+		h.writeError(w, err, "Failed to delete participant from chat")
 		return
 	}
 
+	h.connections.DisconnectUser(chatID, participantID)
+	h.connections.SendToClients(notice, h.logger)
 	h.logger.Info().Msg("Successfully removed participant " + participantID + " from chat " + chatID)
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// LeaveChat marks the caller as having left the chat. The participant row is kept so the
+// user can rejoin later, and their open websocket connections to the chat are closed.
+func (h *Handler) LeaveChat(w http.ResponseWriter, r *http.Request) {
+	chatID := chi.URLParam(r, "chatID")
+	userId, ok := h.currentUserId(w, r)
+	if !ok {
+		return
+	}
+	participant, ok := h.findParticipant(r.Context(), w, chatID, userId)
+	if !ok {
+		return
+	}
+
+	if err := participant.Leave(time.Now().UTC()); err != nil {
+		common.ErrorResponse(w, http.StatusConflict, common.ProblemDetails{
+			Title:     "Conflict",
+			ErrorCode: "CannotLeaveChat",
+			Detail:    err.Error(),
+		})
+		return
+	}
+	notice, err := h.removeParticipant(r.Context(), chatID, userId, messageDomain.NoticeMemberLeft, func(ctx context.Context) error {
+		return h.chatRepo.LeaveChat(ctx, chatID, userId, *participant.LeftAt)
+	})
+	if err != nil {
+		h.writeError(w, err, "Failed to leave chat")
+		return
+	}
+
+	h.connections.DisconnectUser(chatID, userId)
+	h.connections.SendToClients(notice, h.logger)
+	h.logger.Info().Msg("User " + userId + " left chat " + chatID)
+	common.WriteJsonWithEncode(w, http.StatusOK, participant)
+}
+
+// removeParticipant runs remove and posts the notice about userId in one unit of work, so a member is never
+// removed without the notice or the other way round. The notice is returned to be sent to connected members
+// once the unit has committed.
+func (h *Handler) removeParticipant(ctx context.Context, chatID, userId, notice string,
+	remove func(ctx context.Context) error) (messageDomain.Message, error) {
+	message := messageDomain.NewSystemMessage(chatID, userId, notice, time.Now().UTC())
+	err := h.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		if err := remove(ctx); err != nil {
+			return err
+		}
+		return h.messageRepo.Create(ctx, message)
+	})
+	return message, err
+}
+
+// RejoinChat makes a participant that left the chat active again. The history hidden from them while they were
+// away becomes readable again, participants that were removed cannot rejoin.
+func (h *Handler) RejoinChat(w http.ResponseWriter, r *http.Request) {
+	chatID := chi.URLParam(r, "chatID")
+	userId, ok := h.currentUserId(w, r)
+	if !ok {
+		return
+	}
+	participant, ok := h.findParticipant(r.Context(), w, chatID, userId)
+	if !ok {
+		return
+	}
+
+	if err := participant.Rejoin(); err != nil {
+		common.ErrorResponse(w, http.StatusConflict, common.ProblemDetails{
+			Title:     "Conflict",
+			ErrorCode: "CannotRejoinChat",
+			Detail:    err.Error(),
+		})
+		return
+	}
+	if err := h.chatRepo.RejoinChat(r.Context(), chatID, userId); err != nil {
+		h.writeError(w, err, "Failed to rejoin chat")
+		return
+	}
+
+	h.logger.Info().Msg("User " + userId + " rejoined chat " + chatID)
+	common.WriteJsonWithEncode(w, http.StatusOK, participant)
+}
+
+func (h *Handler) findParticipant(ctx context.Context, w http.ResponseWriter, chatID, userId string) (*domain.ChatParticipant, bool) {
+	participant, err := h.chatRepo.GetChatParticipant(ctx, chatID, userId)
+	if err != nil {
+		h.writeError(w, err, "Failed to retrieve chat participant")
+		return nil, false
+	}
+	return participant, true
+}
+
+// writeError answers with the problem details mapped from err. Internal errors are logged with msg first,
+// the client only gets a generic detail for them.
+func (h *Handler) writeError(w http.ResponseWriter, err error, msg string) {
+	if common.StatusCode(err) == http.StatusInternalServerError {
+		h.logger.Error().Err(err).Msg(msg)
+	}
+	common.WriteError(w, err)
+}
+
+// currentUserId resolves the caller from the request token and writes a 401 response when it is missing or invalid.
+func (h *Handler) currentUserId(w http.ResponseWriter, r *http.Request) (string, bool) {
+	userId, err := common.UserIdFromToken(common.TokenFromRequest(r), h.jwtSecret)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Unable to authenticate request")
+		common.ErrorResponse(w, http.StatusUnauthorized, common.ProblemDetails{
+			Title:     "Unauthorized",
+			ErrorCode: "AuthenticationFailure",
+			Detail:    "Invalid authentication token",
+		})
+		return "", false
+	}
+	return userId, true
+}
+
+// requireChatAdmin authenticates the caller and checks that they are an active admin of the chat.
+func (h *Handler) requireChatAdmin(w http.ResponseWriter, r *http.Request, chatID string) (string, bool) {
+	userId, ok := h.currentUserId(w, r)
+	if !ok {
+		return "", false
+	}
+
+	participant, err := h.chatRepo.GetChatParticipant(r.Context(), chatID, userId)
+	if errors.Is(err, domain.ErrParticipantNotFound) || err == nil && !participant.IsAdmin() {
+		err = domain.ErrChatAdminRequired
+	}
+	if err != nil {
+		h.writeError(w, err, "Failed to retrieve chat participant")
+		return "", false
+	}
+	return userId, true
+}
+
 func (h *Handler) validateJWTToken(tokenString string) bool {
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		if token.Method != jwt.SigningMethodHS512 {
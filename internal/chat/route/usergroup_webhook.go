@@ -0,0 +1,243 @@
+package route
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/HappYness-Project/ChatBackendServer/common"
+	domain "github.com/HappYness-Project/ChatBackendServer/internal/chat/domain"
+	messageDomain "github.com/HappYness-Project/ChatBackendServer/internal/message/domain"
+)
+
+const (
+	WebhookSignatureHeader = "X-Webhook-Signature"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+
+	webhookMaxBodyBytes = 1 << 20
+	webhookMaxClockSkew = 5 * time.Minute
+)
+
+// HandleUserGroupEvent reconciles group chats with user-group events from the main application.
+// Every event is applied once, redeliveries and events arriving after newer ones leave the chat unchanged.
+func (h *Handler) HandleUserGroupEvent(w http.ResponseWriter, r *http.Request) {
+	if len(h.webhookSecret) == 0 {
+		h.logger.Error().Msg("User group webhook received but WEBHOOK_SECRET is not configured")
+		common.ErrorResponse(w, http.StatusServiceUnavailable, common.ProblemDetails{
+			Title:     "Service Unavailable",
+			ErrorCode: "WebhookNotConfigured",
+			Detail:    "Webhook secret is not configured",
+		})
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, webhookMaxBodyBytes))
+	if err != nil {
+		common.ErrorResponse(w, http.StatusBadRequest, common.ProblemDetails{
+			Title:     "Invalid Request Body",
+			ErrorCode: "UnreadableBody",
+			Detail:    "Unable to read request body",
+		})
+		return
+	}
+
+	if err := h.verifyWebhookSignature(r, body, time.Now().UTC()); err != nil {
+		h.logger.Error().Err(err).Msg("Rejected user group webhook")
+		common.ErrorResponse(w, http.StatusUnauthorized, common.ProblemDetails{
+			Title:     "Unauthorized",
+			ErrorCode: "InvalidWebhookSignature",
+			Detail:    err.Error(),
+		})
+		return
+	}
+
+	var event domain.UserGroupEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		common.ErrorResponse(w, http.StatusBadRequest, common.ProblemDetails{
+			Title:     "Invalid Request Body",
+			ErrorCode: "InvalidJSON",
+			Detail:    "Unable to decode request body as JSON",
+		})
+		return
+	}
+	if err := event.Validate(); err != nil {
+		common.ErrorResponse(w, http.StatusBadRequest, common.ProblemDetails{
+			Title:     "Invalid Request",
+			ErrorCode: "InvalidUserGroupEvent",
+			Detail:    err.Error(),
+		})
+		return
+	}
+
+	chatId, status, err := h.applyUserGroupEvent(r.Context(), &event)
+	if err != nil {
+		h.writeError(w, err, "Failed to apply user group event "+event.EventId)
+		return
+	}
+
+	h.logger.Info().Str("event_id", event.EventId).Str("type", string(event.Type)).
+		Int("usergroup_id", event.UserGroupId).Str("status", status).Msg("Received user group event")
+	common.WriteJsonWithEncode(w, http.StatusOK, map[string]interface{}{
+		"event_id": event.EventId,
+		"chat_id":  chatId,
+		"status":   status,
+	})
+}
+
+// Outcomes of a user group event reported to the sender.
+const (
+	eventProcessed = "processed"
+	eventDuplicate = "duplicate"
+	eventStale     = "stale"
+)
+
+// applyUserGroupEvent records the event and applies it in one unit of work. Redelivered events and events that
+// occurred before one applied already are recorded without changing anything, the returned status tells which
+// it was. Connected clients are told about the changes once they are committed.
+func (h *Handler) applyUserGroupEvent(ctx context.Context, event *domain.UserGroupEvent) (string, string, error) {
+	var chatId string
+	var notify []func()
+	status := eventProcessed
+	err := h.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		log, err := h.chatRepo.GetUserGroupEventLog(ctx, event.UserGroupId)
+		if err != nil {
+			return err
+		}
+		recorded, err := h.chatRepo.RecordUserGroupEvent(ctx, event)
+		if err != nil {
+			return err
+		}
+		if !recorded {
+			status = eventDuplicate
+			return nil
+		}
+		if log.IsStale(event) {
+			status = eventStale
+			return nil
+		}
+		chatId, notify, err = h.reconcileUserGroupChat(ctx, event, log)
+		return err
+	})
+	if err != nil {
+		return "", "", err
+	}
+	for _, fn := range notify {
+		fn()
+	}
+	return chatId, status, nil
+}
+
+// reconcileUserGroupChat changes the group chat as the event says. It returns the chat id and the notifications
+// to send after the unit of work committed.
+func (h *Handler) reconcileUserGroupChat(ctx context.Context, event *domain.UserGroupEvent,
+	log domain.UserGroupEventLog) (string, []func(), error) {
+	switch event.Type {
+	case domain.UserGroupDeleted:
+		chat, err := h.chatRepo.GetChatByUserGroupId(ctx, event.UserGroupId)
+		if errors.Is(err, domain.ErrChatNotFound) {
+			return "", nil, nil
+		}
+		if err != nil {
+			return "", nil, err
+		}
+		deletedAt := time.Now().UTC()
+		if err := h.chatRepo.SoftDeleteChat(ctx, chat.Id, deletedAt); err != nil {
+			return "", nil, err
+		}
+		chat.DeletedAt = &deletedAt
+		return chat.Id, []func(){func() { h.notifyChatDeleted(chat) }}, nil
+
+	case domain.UserGroupMemberRemoved:
+		chat, err := h.chatRepo.GetChatByUserGroupId(ctx, event.UserGroupId)
+		if errors.Is(err, domain.ErrChatNotFound) {
+			return "", nil, nil
+		}
+		if err != nil {
+			return "", nil, err
+		}
+		notice, err := h.removeParticipant(ctx, chat.Id, event.UserId, messageDomain.NoticeMemberRemoved, func(ctx context.Context) error {
+			return h.chatRepo.RemoveParticipant(ctx, chat.Id, event.UserId, time.Now().UTC())
+		})
+		if errors.Is(err, domain.ErrParticipantNotFound) {
+			return chat.Id, nil, nil
+		}
+		if err != nil {
+			return "", nil, err
+		}
+		return chat.Id, []func(){func() {
+			h.connections.DisconnectUser(chat.Id, event.UserId)
+			h.connections.SendToClients(notice, h.logger)
+		}}, nil
+
+	default:
+		chat, err := h.chatRepo.EnsureUserGroupChat(ctx, event.UserGroupId)
+		if err != nil {
+			return "", nil, err
+		}
+		participants, err := event.Participants(chat.Id)
+		if err != nil {
+			return "", nil, err
+		}
+		// Members of a created group that changed since then keep what the newer event decided.
+		current := make([]*domain.ChatParticipant, 0, len(participants))
+		for _, participant := range participants {
+			if !log.MemberChangedAfter(participant.UserId, event.OccurredAt) {
+				current = append(current, participant)
+			}
+		}
+		if len(current) > 0 {
+			if _, err = h.chatRepo.AddParticipantsToChat(ctx, chat.Id, current); err != nil {
+				return "", nil, err
+			}
+		}
+		return chat.Id, nil, nil
+	}
+}
+
+// verifyWebhookSignature checks the hex encoded HMAC-SHA256 of "<timestamp>.<body>" sent as
+// "sha256=<hex>". The timestamp bounds how long a captured request can be replayed.
+func (h *Handler) verifyWebhookSignature(r *http.Request, body []byte, now time.Time) error {
+	timestamp := r.Header.Get(WebhookTimestampHeader)
+	if timestamp == "" {
+		return errors.New("missing webhook timestamp")
+	}
+	unixSeconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New("invalid webhook timestamp")
+	}
+	sentAt := time.Unix(unixSeconds, 0)
+	if now.Sub(sentAt) > webhookMaxClockSkew || sentAt.Sub(now) > webhookMaxClockSkew {
+		return errors.New("webhook timestamp is outside the allowed window")
+	}
+
+	signature, ok := strings.CutPrefix(r.Header.Get(WebhookSignatureHeader), "sha256=")
+	if !ok {
+		return errors.New("missing webhook signature")
+	}
+	received, err := hex.DecodeString(signature)
+	if err != nil {
+		return errors.New("malformed webhook signature")
+	}
+
+	if !hmac.Equal(received, SignWebhookPayload(h.webhookSecret, timestamp, body)) {
+		return errors.New("webhook signature mismatch")
+	}
+	return nil
+}
+
+// SignWebhookPayload computes the signature the main application is expected to send.
+func SignWebhookPayload(secret []byte, timestamp string, body []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,45 @@
+package linkpreview
+
+import (
+	"context"
+	"sync"
+)
+
+// FakeFetcher serves canned metadata without touching the network. URLs it does not know return ErrNoMetadata.
+type FakeFetcher struct {
+	mu       sync.Mutex
+	previews map[string]Metadata
+	calls    map[string]int
+}
+
+func NewFakeFetcher(previews map[string]Metadata) *FakeFetcher {
+	fetcher := &FakeFetcher{previews: make(map[string]Metadata), calls: make(map[string]int)}
+	for rawURL, metadata := range previews {
+		fetcher.previews[rawURL] = metadata
+	}
+	return fetcher
+}
+
+func (f *FakeFetcher) Fetch(ctx context.Context, rawURL string) (*Metadata, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls[rawURL]++
+	metadata, ok := f.previews[rawURL]
+	if !ok {
+		return nil, ErrNoMetadata
+	}
+	if metadata.URL == "" {
+		metadata.URL = rawURL
+	}
+	return &metadata, nil
+}
+
+// Calls reports how often the URL was fetched, to check that cached previews are not fetched again.
+func (f *FakeFetcher) Calls(rawURL string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[rawURL]
+}
@@ -0,0 +1,32 @@
+package linkpreview
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	// ErrUnsafeURL is returned for URLs that are not plain http(s) or resolve to a private, loopback or otherwise
+	// internal address.
+	ErrUnsafeURL = errors.New("url is not allowed for link previews")
+	// ErrNoMetadata is returned when the page could be fetched but has nothing worth previewing.
+	ErrNoMetadata = errors.New("page has no preview metadata")
+)
+
+// Metadata is what a page says about itself through Open Graph tags, falling back to its title and description.
+type Metadata struct {
+	URL         string
+	Title       string
+	Description string
+	ImageURL    string
+	SiteName    string
+}
+
+func (m *Metadata) IsEmpty() bool {
+	return m.Title == "" && m.Description == "" && m.ImageURL == ""
+}
+
+// Fetcher loads the preview metadata of a URL.
+type Fetcher interface {
+	Fetch(ctx context.Context, rawURL string) (*Metadata, error)
+}
@@ -0,0 +1,122 @@
+package linkpreview
+
+import (
+	"io"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	maxTitleLength       = 300
+	maxDescriptionLength = 1000
+	maxSiteNameLength    = 100
+	maxImageURLLength    = 2048
+)
+
+// ParseMetadata reads the Open Graph and Twitter card tags of an HTML page, falling back to <title> and the
+// description meta tag. Only the head is scanned. pageURL resolves relative image URLs.
+func ParseMetadata(body io.Reader, pageURL *url.URL) *Metadata {
+	tags := make(map[string]string)
+	var title strings.Builder
+	inTitle := false
+
+	tokenizer := html.NewTokenizer(body)
+scan:
+	for {
+		tokenType := tokenizer.Next()
+		switch tokenType {
+		case html.ErrorToken:
+			break scan
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			switch token.DataAtom {
+			case atom.Body:
+				break scan
+			case atom.Title:
+				inTitle = tokenType == html.StartTagToken
+			case atom.Meta:
+				key, content := metaTag(token)
+				if _, seen := tags[key]; key != "" && !seen {
+					tags[key] = content
+				}
+			}
+		case html.EndTagToken:
+			token := tokenizer.Token()
+			if token.DataAtom == atom.Head {
+				break scan
+			}
+			if token.DataAtom == atom.Title {
+				inTitle = false
+			}
+		case html.TextToken:
+			if inTitle {
+				title.Write(tokenizer.Text())
+			}
+		}
+	}
+
+	metadata := &Metadata{
+		URL:         pageURL.String(),
+		Title:       firstNonEmpty(tags["og:title"], tags["twitter:title"], title.String()),
+		Description: firstNonEmpty(tags["og:description"], tags["twitter:description"], tags["description"]),
+		SiteName:    firstNonEmpty(tags["og:site_name"]),
+		ImageURL:    resolveImageURL(firstNonEmpty(tags["og:image"], tags["og:image:url"], tags["twitter:image"]), pageURL),
+	}
+	if canonical := resolveImageURL(tags["og:url"], pageURL); canonical != "" {
+		metadata.URL = canonical
+	}
+	metadata.Title = truncate(metadata.Title, maxTitleLength)
+	metadata.Description = truncate(metadata.Description, maxDescriptionLength)
+	metadata.SiteName = truncate(metadata.SiteName, maxSiteNameLength)
+	return metadata
+}
+
+// metaTag returns the lower cased property or name of a meta tag with its content.
+func metaTag(token html.Token) (string, string) {
+	var key, content string
+	for _, attr := range token.Attr {
+		switch strings.ToLower(attr.Key) {
+		case "property", "name":
+			if key == "" {
+				key = strings.ToLower(strings.TrimSpace(attr.Val))
+			}
+		case "content":
+			content = attr.Val
+		}
+	}
+	return key, content
+}
+
+// resolveImageURL makes ref absolute against the page, dropping anything that is not http(s).
+func resolveImageURL(ref string, pageURL *url.URL) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || len(ref) > maxImageURLLength {
+		return ""
+	}
+	parsed, err := pageURL.Parse(ref)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return ""
+	}
+	return parsed.String()
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value = strings.Join(strings.Fields(value), " "); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func truncate(value string, maxLength int) string {
+	if utf8.RuneCountInString(value) <= maxLength {
+		return value
+	}
+	runes := []rune(value)
+	return strings.TrimSpace(string(runes[:maxLength-1])) + "…"
+}
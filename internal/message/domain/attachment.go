@@ -0,0 +1,130 @@
+package entity
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/HappYness-Project/ChatBackendServer/common"
+	"github.com/google/uuid"
+)
+
+var ErrAttachmentNotFound = common.NotFound("AttachmentNotFound", "Attachment not found with the provided ID")
+
+const (
+	MaxAttachmentSize        = 25 << 20
+	MaxAttachmentsPerMessage = 10
+	maxAttachmentNameLength  = 255
+)
+
+// Attachment describes an uploaded file. It belongs to the chat it was uploaded to and is linked
+// to a message once the uploader sends one referencing it.
+type Attachment struct {
+	ID          string    `json:"id"`
+	ChatID      string    `json:"chat_id"`
+	MessageID   *string   `json:"message_id,omitempty"`
+	UploaderID  string    `json:"uploader_id"`
+	StorageKey  string    `json:"-"`
+	FileName    string    `json:"file_name"`
+	ContentType string    `json:"content_type"`
+	SizeBytes   int64     `json:"size_bytes"`
+	Checksum    string    `json:"checksum_sha256"`
+	Width       *int      `json:"width,omitempty"`
+	Height      *int      `json:"height,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	DownloadURL string    `json:"download_url,omitempty"`
+}
+
+func NewAttachment(chatID, uploaderID, fileName, contentType string, size int64, checksum string) (*Attachment, error) {
+	if chatID == "" || uploaderID == "" {
+		return nil, errors.New("chat_id and uploader_id are required")
+	}
+	if size <= 0 {
+		return nil, errors.New("attachment is empty")
+	}
+	if size > MaxAttachmentSize {
+		return nil, errors.New("attachment exceeds the maximum size")
+	}
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+	return &Attachment{
+		ID:          id.String(),
+		ChatID:      chatID,
+		UploaderID:  uploaderID,
+		StorageKey:  "chats/" + chatID + "/" + id.String(),
+		FileName:    sanitizeFileName(fileName),
+		ContentType: contentType,
+		SizeBytes:   size,
+		Checksum:    checksum,
+		CreatedAt:   time.Now().UTC(),
+	}, nil
+}
+
+// Kind maps the content type onto the message type for attachments of that kind.
+func (a *Attachment) Kind() string {
+	switch {
+	case strings.HasPrefix(a.ContentType, "image/"):
+		return TypeImage
+	case strings.HasPrefix(a.ContentType, "video/"):
+		return TypeVideo
+	case strings.HasPrefix(a.ContentType, "audio/"):
+		return TypeAudio
+	default:
+		return TypeFile
+	}
+}
+
+func (a *Attachment) IsLinked() bool {
+	return a.MessageID != nil
+}
+
+// ValidateForMessage checks that the sender may attach the upload to a new message in the given chat.
+func (a *Attachment) ValidateForMessage(chatID, senderID string) error {
+	if a.ChatID != chatID {
+		return errors.New("attachment does not exist in this chat")
+	}
+	if a.UploaderID != senderID {
+		return errors.New("attachment was uploaded by another user")
+	}
+	if a.IsLinked() {
+		return errors.New("attachment is already linked to a message")
+	}
+	return nil
+}
+
+// AttachmentsMessageType returns the message type for a message carrying the attachments,
+// a single kind when they all share one and "file" otherwise.
+func AttachmentsMessageType(attachments []Attachment) string {
+	if len(attachments) == 0 {
+		return TypeText
+	}
+	kind := attachments[0].Kind()
+	for _, attachment := range attachments[1:] {
+		if attachment.Kind() != kind {
+			return TypeFile
+		}
+	}
+	return kind
+}
+
+func sanitizeFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, name)
+	if name == "." || name == "/" || name == "" {
+		name = "attachment"
+	}
+	for len(name) > maxAttachmentNameLength {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
+}
@@ -0,0 +1,128 @@
+package entity
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// MaxLinkPreviews is the number of URLs unfurled per message, the first ones in the content win.
+	MaxLinkPreviews     = 3
+	maxPreviewURLLength = 2048
+
+	// Successful fetches are reused for a day, pages without metadata are retried after an hour.
+	LinkPreviewCacheTTL     = 24 * time.Hour
+	LinkPreviewMissCacheTTL = time.Hour
+)
+
+var urlPattern = regexp.MustCompile(`(?i)\bhttps?://[^\s<>"'` + "`" + `]+`)
+
+// LinkPreview is the unfurled metadata of a URL found in the message content.
+type LinkPreview struct {
+	URL         string `json:"url"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	ImageURL    string `json:"image_url,omitempty"`
+	SiteName    string `json:"site_name,omitempty"`
+}
+
+// LinkPreviews is stored as a JSON array on the message row.
+type LinkPreviews []LinkPreview
+
+// LinkPreviewCacheEntry records the outcome of fetching a URL. Preview is nil when the page had nothing to preview.
+type LinkPreviewCacheEntry struct {
+	URL       string
+	Preview   *LinkPreview
+	FetchedAt time.Time
+}
+
+// IsFresh reports whether the cached outcome can still be used instead of fetching the URL again.
+func (e *LinkPreviewCacheEntry) IsFresh(now time.Time) bool {
+	ttl := LinkPreviewCacheTTL
+	if e.Preview == nil {
+		ttl = LinkPreviewMissCacheTTL
+	}
+	return now.Sub(e.FetchedAt) < ttl
+}
+
+// ExtractURLs returns the distinct http(s) URLs in the content in order of appearance, at most MaxLinkPreviews.
+// Punctuation that usually ends a sentence rather than the URL is trimmed.
+func ExtractURLs(content string) []string {
+	urls := []string{}
+	seen := make(map[string]bool)
+	for _, match := range urlPattern.FindAllString(content, -1) {
+		candidate := trimURLPunctuation(match)
+		if len(candidate) > maxPreviewURLLength || seen[candidate] {
+			continue
+		}
+		parsed, err := url.Parse(candidate)
+		if err != nil || parsed.Host == "" {
+			continue
+		}
+		seen[candidate] = true
+		urls = append(urls, candidate)
+		if len(urls) == MaxLinkPreviews {
+			break
+		}
+	}
+	return urls
+}
+
+// trimURLPunctuation drops trailing punctuation, keeping closing brackets that pair with one inside the URL
+// such as in wiki links.
+func trimURLPunctuation(candidate string) string {
+	for candidate != "" {
+		last := candidate[len(candidate)-1]
+		switch last {
+		case '.', ',', ';', ':', '!', '?':
+			candidate = candidate[:len(candidate)-1]
+			continue
+		case ')', ']', '}':
+			open := map[byte]string{')': "(", ']': "[", '}': "{"}[last]
+			if strings.Count(candidate, open) < strings.Count(candidate, string(last)) {
+				candidate = candidate[:len(candidate)-1]
+				continue
+			}
+		}
+		return candidate
+	}
+	return candidate
+}
+
+func (p LinkPreviews) Value() (driver.Value, error) {
+	if p == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (p *LinkPreviews) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*p = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return errors.New("unsupported type for link previews")
+	}
+	if err := json.Unmarshal(data, p); err != nil {
+		return err
+	}
+	if len(*p) == 0 {
+		*p = nil
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package entity
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type MentionType string
+
+const (
+	MentionUser   MentionType = "user"
+	MentionAll    MentionType = "all"
+	MentionAdmins MentionType = "admins"
+)
+
+// Mention is an @mention found in the message content. Offset and Length count characters, not bytes.
+type Mention struct {
+	Type   MentionType `json:"type"`
+	UserID string      `json:"user_id,omitempty"`
+	Offset int         `json:"offset"`
+	Length int         `json:"length"`
+}
+
+// Mentions is stored as JSONB on the message.
+type Mentions []Mention
+
+// MentionRecipient is a user notified about a message, either mentioned directly or through @all or @admins.
+type MentionRecipient struct {
+	UserID string      `json:"user_id"`
+	Type   MentionType `json:"mention_type"`
+}
+
+// MentionCandidate is a chat participant that can be mentioned.
+type MentionCandidate struct {
+	UserID  string
+	IsAdmin bool
+}
+
+// MentionNotification is a message listed in a user's mentions.
+type MentionNotification struct {
+	Message     Message     `json:"message"`
+	MentionType MentionType `json:"mention_type"`
+	MentionedAt time.Time   `json:"mentioned_at"`
+}
+
+// A mention starts at the beginning of the content or after a character that cannot be part of a word,
+// so e-mail addresses are not picked up.
+var mentionPattern = regexp.MustCompile(`(^|[^\w@])@(all|admins|[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12})\b`)
+
+// ParseMentions finds the @user, @all and @admins mentions in the content.
+func ParseMentions(content string) Mentions {
+	var mentions Mentions
+	for _, match := range mentionPattern.FindAllStringSubmatchIndex(content, -1) {
+		start, end := match[4]-1, match[5]
+		target := content[match[4]:match[5]]
+
+		mention := Mention{
+			Offset: utf8.RuneCountInString(content[:start]),
+			Length: utf8.RuneCountInString(content[start:end]),
+		}
+		switch target {
+		case "all":
+			mention.Type = MentionAll
+		case "admins":
+			mention.Type = MentionAdmins
+		default:
+			mention.Type = MentionUser
+			mention.UserID = strings.ToLower(target)
+		}
+		mentions = append(mentions, mention)
+	}
+	return mentions
+}
+
+// ResolveMentions keeps the mentions that apply to the chat and returns who should be notified.
+// Only participants can be mentioned, @all and @admins only apply to group chats, and senders are never notified
+// about their own message. A user mentioned in several ways is notified once, direct mentions taking precedence.
+func (m *Message) ResolveMentions(candidates []MentionCandidate, groupChat bool) []MentionRecipient {
+	members := make(map[string]MentionCandidate, len(candidates))
+	for _, candidate := range candidates {
+		members[candidate.UserID] = candidate
+	}
+
+	var resolved Mentions
+	notified := make(map[string]MentionType)
+	notify := func(userID string, mentionType MentionType) {
+		if userID == m.SenderID {
+			return
+		}
+		if current, ok := notified[userID]; !ok || (mentionType == MentionUser && current != MentionUser) {
+			notified[userID] = mentionType
+		}
+	}
+
+	for _, mention := range ParseMentions(m.Content) {
+		switch mention.Type {
+		case MentionUser:
+			if _, ok := members[mention.UserID]; !ok {
+				continue
+			}
+			notify(mention.UserID, MentionUser)
+		case MentionAll, MentionAdmins:
+			if !groupChat {
+				continue
+			}
+			for _, candidate := range candidates {
+				if mention.Type == MentionAll || candidate.IsAdmin {
+					notify(candidate.UserID, mention.Type)
+				}
+			}
+		}
+		resolved = append(resolved, mention)
+	}
+	m.Mentions = resolved
+
+	recipients := make([]MentionRecipient, 0, len(notified))
+	for _, candidate := range candidates {
+		if mentionType, ok := notified[candidate.UserID]; ok {
+			recipients = append(recipients, MentionRecipient{UserID: candidate.UserID, Type: mentionType})
+		}
+	}
+	return recipients
+}
+
+func (m Mentions) Value() (driver.Value, error) {
+	if m == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (m *Mentions) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*m = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return errors.New("unsupported type for mentions")
+	}
+	if err := json.Unmarshal(data, m); err != nil {
+		return err
+	}
+	if len(*m) == 0 {
+		*m = nil
+	}
+	return nil
+}
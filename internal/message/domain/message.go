@@ -1,15 +1,37 @@
 package entity
 
-import "time"
+import (
+	"errors"
+	"time"
+
+	"github.com/HappYness-Project/ChatBackendServer/common"
+)
+
+var (
+	ErrMessageNotFound = common.NotFound("MessageNotFound", "Message not found with the provided ID")
+	ErrThreadNotFound  = common.NotFound("ThreadNotFound", "No thread found for the provided message ID")
+)
 
 type Message struct {
-	ID          string    `json:"id"`
-	ChatID      string    `json:"chat_id"`
-	SenderID    string    `json:"sender_id"`
-	Content     string    `json:"content"`
-	MessageType string    `json:"message_type"`
-	CreatedAt   time.Time `json:"created_at"`
-	ReadStatus  bool      `json:"read_status"`
+	ID                string             `json:"id"`
+	ChatID            string             `json:"chat_id"`
+	SenderID          string             `json:"sender_id"`
+	Content           string             `json:"content"`
+	MessageType       string             `json:"message_type"`
+	CreatedAt         time.Time          `json:"created_at"`
+	ReadStatus        bool               `json:"read_status"`
+	ReplyToID         *string            `json:"reply_to_id,omitempty"`
+	ThreadRootID      *string            `json:"thread_root_id,omitempty"`
+	ThreadReplyCount  int                `json:"thread_reply_count"`
+	ThreadLastReplyAt *time.Time         `json:"thread_last_reply_at,omitempty"`
+	Reactions         []ReactionSummary  `json:"reactions,omitempty"`
+	AttachmentIDs     []string           `json:"attachment_ids,omitempty"`
+	Attachments       []Attachment       `json:"attachments,omitempty"`
+	Mentions          Mentions           `json:"mentions,omitempty"`
+	MentionRecipients []MentionRecipient `json:"-"`
+	TTLSeconds        *int               `json:"ttl_seconds,omitempty"`
+	ExpiresAt         *time.Time         `json:"expires_at,omitempty"`
+	LinkPreviews      LinkPreviews       `json:"link_previews,omitempty"`
 }
 type CreateMessageDto struct {
 	ChatID      string `json:"chat_id"`
@@ -17,3 +39,41 @@ type CreateMessageDto struct {
 	Content     string `json:"content"`
 	MessageType string `json:"message_type"`
 }
+
+// ThreadSummary is broadcast to a chat whenever a reply is added to one of its threads.
+type ThreadSummary struct {
+	RootID        string     `json:"root_id"`
+	ReplyCount    int        `json:"reply_count"`
+	LastReplyAt   *time.Time `json:"last_reply_at,omitempty"`
+	LatestReplyID string     `json:"latest_reply_id"`
+}
+
+func (m *Message) IsThreadReply() bool {
+	return m.ThreadRootID != nil
+}
+
+// ValidateReplyTarget checks that the message replied to belongs to the same chat. parent is nil when it does not exist.
+func (m *Message) ValidateReplyTarget(parent *Message) error {
+	if parent == nil {
+		return errors.New("reply_to_id does not reference an existing message")
+	}
+	if parent.ChatID != m.ChatID {
+		return errors.New("reply_to_id references a message from another chat")
+	}
+	return nil
+}
+
+// ValidateThreadRoot checks that the thread root belongs to the same chat and is not itself a thread reply.
+// root is nil when it does not exist.
+func (m *Message) ValidateThreadRoot(root *Message) error {
+	if root == nil {
+		return errors.New("thread_root_id does not reference an existing message")
+	}
+	if root.ChatID != m.ChatID {
+		return errors.New("thread_root_id references a message from another chat")
+	}
+	if root.IsThreadReply() {
+		return errors.New("thread_root_id references a thread reply, threads cannot be nested")
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package entity
+
+import (
+	"errors"
+	"time"
+)
+
+const MaxPinsPerChat = 50
+
+// Pin marks a message as pinned in its chat. Pins are listed by Position, the most recently pinned last.
+type Pin struct {
+	ChatID    string    `json:"chat_id"`
+	MessageID string    `json:"message_id"`
+	PinnedBy  string    `json:"pinned_by"`
+	PinnedAt  time.Time `json:"pinned_at"`
+	Position  int       `json:"position"`
+	Message   *Message  `json:"message,omitempty"`
+}
+
+func NewPin(message *Message, pinnedBy string) (*Pin, error) {
+	if message.ID == "" {
+		return nil, errors.New("message is required")
+	}
+	if message.IsThreadReply() {
+		return nil, errors.New("thread replies cannot be pinned")
+	}
+	return &Pin{
+		ChatID:    message.ChatID,
+		MessageID: message.ID,
+		PinnedBy:  pinnedBy,
+		PinnedAt:  time.Now().UTC(),
+	}, nil
+}
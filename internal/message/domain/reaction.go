@@ -0,0 +1,65 @@
+package entity
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const MaxEmojiLength = 64
+
+type Reaction struct {
+	MessageID string    `json:"message_id"`
+	UserID    string    `json:"user_id"`
+	Emoji     string    `json:"emoji"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ReactionSummary aggregates the reactions with one emoji on a message for the requesting user.
+type ReactionSummary struct {
+	Emoji       string `json:"emoji"`
+	Count       int    `json:"count"`
+	ReactedByMe bool   `json:"reacted_by_me"`
+}
+
+// ReactionEvent is broadcast to the chat when a reaction is added or removed.
+type ReactionEvent struct {
+	MessageID string `json:"message_id"`
+	UserID    string `json:"user_id"`
+	Emoji     string `json:"emoji"`
+	Count     int    `json:"count"`
+}
+
+func NewReaction(messageID, userID, emoji string) (*Reaction, error) {
+	if messageID == "" {
+		return nil, errors.New("message_id is required")
+	}
+	if userID == "" {
+		return nil, errors.New("user_id is required")
+	}
+	if err := ValidateEmoji(emoji); err != nil {
+		return nil, err
+	}
+	return &Reaction{
+		MessageID: messageID,
+		UserID:    userID,
+		Emoji:     emoji,
+		CreatedAt: time.Now().UTC(),
+	}, nil
+}
+
+// ValidateEmoji accepts a single emoji sequence or a short code such as ":thumbsup:".
+func ValidateEmoji(emoji string) error {
+	if emoji == "" {
+		return errors.New("emoji is required")
+	}
+	if len(emoji) > MaxEmojiLength || !utf8.ValidString(emoji) {
+		return errors.New("emoji is too long or not valid UTF-8")
+	}
+	if strings.IndexFunc(emoji, unicode.IsSpace) >= 0 {
+		return errors.New("emoji cannot contain whitespace")
+	}
+	return nil
+}
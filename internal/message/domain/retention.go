@@ -0,0 +1,44 @@
+package entity
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	MinMessageTTL = 5 * time.Second
+	MaxMessageTTL = 365 * 24 * time.Hour
+)
+
+// ExpiredMessage identifies a message removed by the retention purge.
+type ExpiredMessage struct {
+	ID     string `json:"id"`
+	ChatID string `json:"chat_id"`
+}
+
+// PurgeResult lists the messages removed by one purge batch and the blob storage keys of their attachments,
+// which have to be deleted from the blob store separately.
+type PurgeResult struct {
+	Messages    []ExpiredMessage
+	StorageKeys []string
+}
+
+// ApplyTTL sets ExpiresAt from the ttl_seconds the sender asked for. Messages without a TTL only expire through
+// the retention period of their chat.
+func (m *Message) ApplyTTL() error {
+	m.ExpiresAt = nil
+	if m.TTLSeconds == nil {
+		return nil
+	}
+	ttl := time.Duration(*m.TTLSeconds) * time.Second
+	if ttl < MinMessageTTL || ttl > MaxMessageTTL {
+		return &ValidationError{
+			Code:   "InvalidTTL",
+			Field:  "ttl_seconds",
+			Detail: fmt.Sprintf("ttl_seconds must be between %d and %d", int(MinMessageTTL.Seconds()), int(MaxMessageTTL.Seconds())),
+		}
+	}
+	expiresAt := m.CreatedAt.Add(ttl)
+	m.ExpiresAt = &expiresAt
+	return nil
+}
@@ -0,0 +1,113 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"github.com/HappYness-Project/ChatBackendServer/common"
+	"github.com/google/uuid"
+)
+
+var ErrScheduledMessageNotFound = common.NotFound("ScheduledMessageNotFound", "Scheduled message not found with the provided ID")
+
+type ScheduledStatus string
+
+const (
+	ScheduledPending   ScheduledStatus = "pending"
+	ScheduledSending   ScheduledStatus = "sending"
+	ScheduledSent      ScheduledStatus = "sent"
+	ScheduledCancelled ScheduledStatus = "cancelled"
+	ScheduledFailed    ScheduledStatus = "failed"
+)
+
+const (
+	MinScheduleDelay = 10 * time.Second
+	MaxScheduleAhead = 365 * 24 * time.Hour
+)
+
+// ScheduledMessage is a message composed now and delivered at SendAt. The message it produces reuses its id,
+// so a delivery retried after a crash cannot create the message twice.
+type ScheduledMessage struct {
+	ID            string          `json:"id"`
+	ChatID        string          `json:"chat_id"`
+	SenderID      string          `json:"sender_id"`
+	Content       string          `json:"content"`
+	MessageType   string          `json:"message_type"`
+	ReplyToID     *string         `json:"reply_to_id,omitempty"`
+	ThreadRootID  *string         `json:"thread_root_id,omitempty"`
+	SendAt        time.Time       `json:"send_at"`
+	Status        ScheduledStatus `json:"status"`
+	FailureReason *string         `json:"failure_reason,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+}
+
+func NewScheduledMessage(chatID, senderID, content string, replyToID, threadRootID *string, sendAt, now time.Time) (*ScheduledMessage, error) {
+	if err := ValidateSendAt(sendAt, now); err != nil {
+		return nil, err
+	}
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+	return &ScheduledMessage{
+		ID:           id.String(),
+		ChatID:       chatID,
+		SenderID:     senderID,
+		Content:      content,
+		MessageType:  TypeText,
+		ReplyToID:    replyToID,
+		ThreadRootID: threadRootID,
+		SendAt:       sendAt.UTC(),
+		Status:       ScheduledPending,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}, nil
+}
+
+// ValidateSendAt requires the send time to be at least MinScheduleDelay and at most MaxScheduleAhead away.
+func ValidateSendAt(sendAt, now time.Time) error {
+	if sendAt.Before(now.Add(MinScheduleDelay)) {
+		return &ValidationError{Code: "SendAtTooSoon", Field: "send_at", Detail: "send_at must be at least 10 seconds in the future"}
+	}
+	if sendAt.After(now.Add(MaxScheduleAhead)) {
+		return &ValidationError{Code: "SendAtTooFar", Field: "send_at", Detail: "send_at must be within one year"}
+	}
+	return nil
+}
+
+func (s *ScheduledMessage) IsPending() bool {
+	return s.Status == ScheduledPending
+}
+
+// Edit changes the content or send time of a pending scheduled message.
+func (s *ScheduledMessage) Edit(content *string, sendAt *time.Time, now time.Time) error {
+	if !s.IsPending() {
+		return errors.New("only pending scheduled messages can be changed")
+	}
+	if sendAt != nil {
+		if err := ValidateSendAt(*sendAt, now); err != nil {
+			return err
+		}
+		s.SendAt = sendAt.UTC()
+	}
+	if content != nil {
+		s.Content = *content
+	}
+	s.UpdatedAt = now
+	return nil
+}
+
+// ToMessage builds the message delivered for the schedule, created at the actual delivery time.
+func (s *ScheduledMessage) ToMessage(now time.Time) Message {
+	return Message{
+		ID:           s.ID,
+		ChatID:       s.ChatID,
+		SenderID:     s.SenderID,
+		Content:      s.Content,
+		MessageType:  s.MessageType,
+		ReplyToID:    s.ReplyToID,
+		ThreadRootID: s.ThreadRootID,
+		CreatedAt:    now,
+	}
+}
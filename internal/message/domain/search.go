@@ -0,0 +1,71 @@
+package entity
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const (
+	MaxSearchQueryLength = 256
+	DefaultSearchLimit   = 20
+	MaxSearchLimit       = 100
+)
+
+// SearchQuery filters a full-text search over the messages of the chats a user participates in.
+type SearchQuery struct {
+	Text        string
+	ChatID      string
+	SenderID    string
+	MessageType string
+	From        *time.Time
+	To          *time.Time
+	Limit       int
+	Offset      int
+}
+
+// SearchResult is a matching message with its rank and a snippet where matched terms are wrapped in <mark> tags.
+type SearchResult struct {
+	Message Message `json:"message"`
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+// Validate trims the search text, applies the default limit and checks the filters.
+func (q *SearchQuery) Validate() error {
+	q.Text = strings.TrimSpace(q.Text)
+	if q.Text == "" {
+		return &ValidationError{Code: "MissingQuery", Field: "q", Detail: "a search query is required"}
+	}
+	if !utf8.ValidString(q.Text) || utf8.RuneCountInString(q.Text) > MaxSearchQueryLength {
+		return &ValidationError{Code: "InvalidQuery", Field: "q", Detail: "the search query must be valid UTF-8 of at most 256 characters"}
+	}
+	if q.ChatID != "" {
+		if _, err := uuid.Parse(q.ChatID); err != nil {
+			return &ValidationError{Code: "InvalidFilter", Field: "chat_id", Detail: "chat_id must be a UUID"}
+		}
+	}
+	if q.SenderID != "" {
+		if _, err := uuid.Parse(q.SenderID); err != nil {
+			return &ValidationError{Code: "InvalidFilter", Field: "sender_id", Detail: "sender_id must be a UUID"}
+		}
+	}
+	if q.MessageType != "" && !IsValidMessageType(q.MessageType) {
+		return &ValidationError{Code: "InvalidFilter", Field: "type", Detail: "type is not a known message type"}
+	}
+	if q.From != nil && q.To != nil && q.From.After(*q.To) {
+		return &ValidationError{Code: "InvalidFilter", Field: "from", Detail: "from must not be after to"}
+	}
+	if q.Limit <= 0 {
+		q.Limit = DefaultSearchLimit
+	}
+	if q.Limit > MaxSearchLimit {
+		q.Limit = MaxSearchLimit
+	}
+	if q.Offset < 0 {
+		q.Offset = 0
+	}
+	return nil
+}
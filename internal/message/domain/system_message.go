@@ -0,0 +1,30 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TypeSystem marks notices the server posts about changes to a chat. Clients cannot send them.
+const TypeSystem = "system"
+
+// Notices carried as the content of system messages. The sender is the user the notice is about.
+const (
+	NoticeChatCreated   = "chat_created"
+	NoticeMemberLeft    = "member_left"
+	NoticeMemberRemoved = "member_removed"
+)
+
+// NewSystemMessage returns the notice about userID to post in the chat's timeline.
+func NewSystemMessage(chatID, userID, notice string, createdAt time.Time) Message {
+	id, _ := uuid.NewV7()
+	return Message{
+		ID:          id.String(),
+		ChatID:      chatID,
+		SenderID:    userID,
+		Content:     notice,
+		MessageType: TypeSystem,
+		CreatedAt:   createdAt,
+	}
+}
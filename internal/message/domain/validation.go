@@ -0,0 +1,125 @@
+package entity
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/HappYness-Project/ChatBackendServer/common"
+)
+
+// Message types clients may send. The CHECK constraint on message.message_type also allows TypeSystem.
+const (
+	TypeText  = "text"
+	TypeImage = "image"
+	TypeVideo = "video"
+	TypeAudio = "audio"
+	TypeFile  = "file"
+)
+
+// MaxContentLength is the longest message content accepted, counted in characters.
+const MaxContentLength = 4000
+
+// ValidationError explains why a message was rejected. Code is sent back to the sender as the error code.
+type ValidationError struct {
+	Code   string
+	Field  string
+	Detail string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Field + ": " + e.Detail
+}
+
+func (e *ValidationError) ErrorCode() string {
+	return e.Code
+}
+
+// Unwrap makes validation errors answer with a bad request through common.WriteError.
+func (e *ValidationError) Unwrap() error {
+	return common.ErrValidation
+}
+
+func IsValidMessageType(messageType string) bool {
+	switch messageType {
+	case TypeText, TypeImage, TypeVideo, TypeAudio, TypeFile:
+		return true
+	}
+	return false
+}
+
+// Normalize trims surrounding whitespace from the content and fills in the message type when the sender left
+// it out, "text" for plain messages or the kind of the attachments otherwise.
+func (m *Message) Normalize() {
+	m.Content = strings.TrimSpace(m.Content)
+	m.MessageType = strings.TrimSpace(m.MessageType)
+	if m.MessageType == "" {
+		m.MessageType = AttachmentsMessageType(m.Attachments)
+	}
+}
+
+// Validate checks the content and the payload the message type requires. Attachments must already be resolved.
+func (m *Message) Validate() error {
+	if err := validateContent(m.Content); err != nil {
+		return err
+	}
+	if !IsValidMessageType(m.MessageType) {
+		return &ValidationError{
+			Code:   "InvalidMessageType",
+			Field:  "message_type",
+			Detail: fmt.Sprintf("message_type must be one of: %s %s %s %s %s", TypeText, TypeImage, TypeVideo, TypeAudio, TypeFile),
+		}
+	}
+
+	switch m.MessageType {
+	case TypeText:
+		if m.Content == "" {
+			return &ValidationError{Code: "EmptyContent", Field: "content", Detail: "text messages cannot be empty"}
+		}
+		if len(m.Attachments) > 0 {
+			return &ValidationError{Code: "UnexpectedAttachments", Field: "attachment_ids", Detail: "text messages cannot carry attachments"}
+		}
+	case TypeImage, TypeVideo, TypeAudio:
+		if len(m.Attachments) == 0 {
+			return &ValidationError{Code: "MissingAttachments", Field: "attachment_ids", Detail: m.MessageType + " messages require at least one attachment"}
+		}
+		for _, attachment := range m.Attachments {
+			if attachment.Kind() != m.MessageType {
+				return &ValidationError{
+					Code:   "AttachmentTypeMismatch",
+					Field:  "attachment_ids",
+					Detail: fmt.Sprintf("%s messages only accept %s attachments, %s is %s", m.MessageType, m.MessageType, attachment.FileName, attachment.ContentType),
+				}
+			}
+		}
+	case TypeFile:
+		if len(m.Attachments) == 0 {
+			return &ValidationError{Code: "MissingAttachments", Field: "attachment_ids", Detail: "file messages require at least one attachment"}
+		}
+	}
+	return nil
+}
+
+func validateContent(content string) error {
+	if !utf8.ValidString(content) {
+		return &ValidationError{Code: "InvalidEncoding", Field: "content", Detail: "content must be valid UTF-8"}
+	}
+	if length := utf8.RuneCountInString(content); length > MaxContentLength {
+		return &ValidationError{
+			Code:   "ContentTooLong",
+			Field:  "content",
+			Detail: fmt.Sprintf("content is %d characters, the maximum is %d", length, MaxContentLength),
+		}
+	}
+	if strings.IndexFunc(content, isDisallowedControl) >= 0 {
+		return &ValidationError{Code: "InvalidCharacters", Field: "content", Detail: "content cannot contain control characters"}
+	}
+	return nil
+}
+
+// isDisallowedControl allows line breaks and tabs but rejects other control characters such as NUL,
+// which Postgres refuses to store in text columns.
+func isDisallowedControl(r rune) bool {
+	return unicode.IsControl(r) && r != '\n' && r != '\r' && r != '\t'
+}
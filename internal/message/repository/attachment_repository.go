@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/HappYness-Project/ChatBackendServer/common"
+	"github.com/HappYness-Project/ChatBackendServer/dbs"
+	domain "github.com/HappYness-Project/ChatBackendServer/internal/message/domain"
+)
+
+// ErrAttachmentUnavailable is returned when a message references attachments that are missing,
+// belong to another chat or uploader, or were already linked to a message.
+var ErrAttachmentUnavailable = common.Conflict("AttachmentUnavailable", "attachment is not available for this message")
+
+const attachmentColumns = `a.id, a.chat_id, a.message_id, a.uploader_id, a.storage_key, a.file_name, a.content_type,
+		a.size_bytes, a.checksum_sha256, a.width, a.height, a.created_at`
+
+func (r *MessageRepo) CreateAttachment(ctx context.Context, attachment domain.Attachment) error {
+	_, err := r.conn(ctx).ExecContext(ctx, `
+		INSERT INTO message_attachment (id, chat_id, uploader_id, storage_key, file_name, content_type,
+			size_bytes, checksum_sha256, width, height, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		attachment.ID, attachment.ChatID, attachment.UploaderID, attachment.StorageKey, attachment.FileName,
+		attachment.ContentType, attachment.SizeBytes, attachment.Checksum, attachment.Width, attachment.Height,
+		attachment.CreatedAt)
+	return dbs.TranslateError(err)
+}
+
+func (r *MessageRepo) GetAttachmentByID(ctx context.Context, attachmentID string) (*domain.Attachment, error) {
+	rows, err := r.conn(ctx).QueryContext(ctx, `
+		SELECT `+attachmentColumns+`
+		FROM message_attachment a
+		WHERE a.id = $1`, attachmentID)
+	if err != nil {
+		return nil, dbs.TranslateError(err)
+	}
+	defer rows.Close()
+
+	attachment := new(domain.Attachment)
+	for rows.Next() {
+		if attachment, err = scanAttachment(rows); err != nil {
+			return nil, err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if attachment.ID == "" {
+		return nil, domain.ErrAttachmentNotFound
+	}
+	return attachment, nil
+}
+
+// GetAttachmentsByMessageIDs returns the attachments of the given messages keyed by message id.
+func (r *MessageRepo) GetAttachmentsByMessageIDs(ctx context.Context, messageIDs []string) (map[string][]domain.Attachment, error) {
+	attachments := make(map[string][]domain.Attachment)
+	if len(messageIDs) == 0 {
+		return attachments, nil
+	}
+
+	rows, err := r.conn(ctx).QueryContext(ctx, `
+		SELECT `+attachmentColumns+`
+		FROM message_attachment a
+		WHERE a.message_id = ANY($1)
+		ORDER BY a.created_at ASC`, messageIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		attachment, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		attachments[*attachment.MessageID] = append(attachments[*attachment.MessageID], *attachment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+// linkAttachments claims unlinked uploads of the sender for the message. The guarded update keeps
+// two messages from claiming the same attachment.
+func linkAttachments(ctx context.Context, tx dbs.Querier, message domain.Message) error {
+	if len(message.AttachmentIDs) == 0 {
+		return nil
+	}
+	result, err := tx.ExecContext(ctx, `
+		UPDATE message_attachment
+		SET message_id = $1
+		WHERE id = ANY($2) AND chat_id = $3 AND uploader_id = $4 AND message_id IS NULL`,
+		message.ID, message.AttachmentIDs, message.ChatID, message.SenderID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected != int64(len(message.AttachmentIDs)) {
+		return ErrAttachmentUnavailable
+	}
+	return nil
+}
+
+func scanAttachment(rows *sql.Rows) (*domain.Attachment, error) {
+	attachment := new(domain.Attachment)
+	err := rows.Scan(&attachment.ID, &attachment.ChatID, &attachment.MessageID, &attachment.UploaderID,
+		&attachment.StorageKey, &attachment.FileName, &attachment.ContentType, &attachment.SizeBytes,
+		&attachment.Checksum, &attachment.Width, &attachment.Height, &attachment.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return attachment, nil
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	domain "github.com/HappYness-Project/ChatBackendServer/internal/message/domain"
+)
+
+// GetLinkPreviewCache returns the cached fetch outcome for the URL, an entry with an empty URL when it was never fetched.
+func (r *MessageRepo) GetLinkPreviewCache(ctx context.Context, url string) (*domain.LinkPreviewCacheEntry, error) {
+	rows, err := r.conn(ctx).QueryContext(ctx, `
+		SELECT url, found, title, description, image_url, site_name, fetched_at
+		FROM link_preview
+		WHERE url = $1`, url)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entry := new(domain.LinkPreviewCacheEntry)
+	for rows.Next() {
+		var found bool
+		var preview domain.LinkPreview
+		if err := rows.Scan(&entry.URL, &found, &preview.Title, &preview.Description, &preview.ImageURL,
+			&preview.SiteName, &entry.FetchedAt); err != nil {
+			return nil, err
+		}
+		if found {
+			preview.URL = entry.URL
+			entry.Preview = &preview
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// SaveLinkPreviewCache stores the outcome of fetching a URL, replacing an older one.
+func (r *MessageRepo) SaveLinkPreviewCache(ctx context.Context, entry domain.LinkPreviewCacheEntry) error {
+	preview := domain.LinkPreview{}
+	if entry.Preview != nil {
+		preview = *entry.Preview
+	}
+	_, err := r.conn(ctx).ExecContext(ctx, `
+		INSERT INTO link_preview (url, found, title, description, image_url, site_name, fetched_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (url) DO UPDATE
+		SET found = EXCLUDED.found, title = EXCLUDED.title, description = EXCLUDED.description,
+			image_url = EXCLUDED.image_url, site_name = EXCLUDED.site_name, fetched_at = EXCLUDED.fetched_at`,
+		entry.URL, entry.Preview != nil, preview.Title, preview.Description, preview.ImageURL, preview.SiteName, entry.FetchedAt)
+	return err
+}
+
+func (r *MessageRepo) SetLinkPreviews(ctx context.Context, messageID string, previews domain.LinkPreviews) error {
+	_, err := r.conn(ctx).ExecContext(ctx, `UPDATE message SET link_previews = $2 WHERE id = $1`, messageID, previews)
+	return err
+}
@@ -0,0 +1,990 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	chatDomain "github.com/HappYness-Project/ChatBackendServer/internal/chat/domain"
+	chatRepository "github.com/HappYness-Project/ChatBackendServer/internal/chat/repository"
+	domain "github.com/HappYness-Project/ChatBackendServer/internal/message/domain"
+)
+
+// MemoryMessageRepo keeps messages and everything attached to them in memory. It behaves like MessageRepo and
+// reads chats and participants from the chat repository it is paired with. Data is lost when the process exits.
+type MemoryMessageRepo struct {
+	mu           sync.RWMutex
+	chats        chatRepository.ChatRepository
+	messages     map[string]domain.Message
+	mentions     []memoryMention
+	reactions    []domain.Reaction
+	attachments  map[string]domain.Attachment
+	pins         map[string][]domain.Pin
+	scheduled    map[string]memoryScheduled
+	linkPreviews map[string]domain.LinkPreviewCacheEntry
+}
+
+type memoryMention struct {
+	messageID   string
+	userID      string
+	mentionType domain.MentionType
+	createdAt   time.Time
+}
+
+type memoryScheduled struct {
+	domain.ScheduledMessage
+	claimedAt time.Time
+}
+
+var _ MessageRepository = (*MemoryMessageRepo)(nil)
+
+// NewMemoryRepository pairs the repository with the in-memory chats, so purging a chat also removes its messages.
+func NewMemoryRepository(chats *chatRepository.MemoryChatRepo) *MemoryMessageRepo {
+	r := &MemoryMessageRepo{
+		chats:        chats,
+		messages:     make(map[string]domain.Message),
+		attachments:  make(map[string]domain.Attachment),
+		pins:         make(map[string][]domain.Pin),
+		scheduled:    make(map[string]memoryScheduled),
+		linkPreviews: make(map[string]domain.LinkPreviewCacheEntry),
+	}
+	chats.OnChatsRemoved(r.removeChats)
+	return r
+}
+
+// Snapshot copies the stored messages and everything attached to them for MemoryUnitOfWork to restore on rollback.
+func (r *MemoryMessageRepo) Snapshot() func() {
+	r.mu.RLock()
+	messages, attachments := maps.Clone(r.messages), maps.Clone(r.attachments)
+	scheduled, linkPreviews := maps.Clone(r.scheduled), maps.Clone(r.linkPreviews)
+	mentions, reactions := slices.Clone(r.mentions), slices.Clone(r.reactions)
+	pins := make(map[string][]domain.Pin, len(r.pins))
+	for chatID, chatPins := range r.pins {
+		pins[chatID] = slices.Clone(chatPins)
+	}
+	r.mu.RUnlock()
+
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.messages, r.attachments, r.scheduled, r.linkPreviews = messages, attachments, scheduled, linkPreviews
+		r.mentions, r.reactions, r.pins = mentions, reactions, pins
+	}
+}
+
+// Create stores the message like MessageRepo.Create: attachments are linked, mention recipients recorded and the
+// thread root updated, or nothing is stored at all.
+func (r *MemoryMessageRepo) Create(ctx context.Context, message domain.Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.messages[message.ID]; ok {
+		return fmt.Errorf("message %s already exists", message.ID)
+	}
+	for _, id := range message.AttachmentIDs {
+		attachment, ok := r.attachments[id]
+		if !ok || attachment.ChatID != message.ChatID || attachment.UploaderID != message.SenderID || attachment.MessageID != nil {
+			return ErrAttachmentUnavailable
+		}
+	}
+
+	messageID := message.ID
+	for _, id := range message.AttachmentIDs {
+		attachment := r.attachments[id]
+		attachment.MessageID = &messageID
+		r.attachments[id] = attachment
+	}
+	for _, recipient := range message.MentionRecipients {
+		if r.mentionIndex(message.ID, recipient.UserID) < 0 {
+			r.mentions = append(r.mentions, memoryMention{message.ID, recipient.UserID, recipient.Type, message.CreatedAt})
+		}
+	}
+	if message.ThreadRootID != nil {
+		if root, ok := r.messages[*message.ThreadRootID]; ok {
+			root.ThreadReplyCount++
+			if root.ThreadLastReplyAt == nil || message.CreatedAt.After(*root.ThreadLastReplyAt) {
+				lastReplyAt := message.CreatedAt
+				root.ThreadLastReplyAt = &lastReplyAt
+			}
+			r.messages[root.ID] = root
+		}
+	}
+
+	r.messages[message.ID] = domain.Message{
+		ID:           message.ID,
+		ChatID:       message.ChatID,
+		SenderID:     message.SenderID,
+		Content:      message.Content,
+		MessageType:  message.MessageType,
+		CreatedAt:    message.CreatedAt,
+		ReplyToID:    message.ReplyToID,
+		ThreadRootID: message.ThreadRootID,
+		Mentions:     message.Mentions,
+		ExpiresAt:    message.ExpiresAt,
+	}
+	return nil
+}
+
+func (r *MemoryMessageRepo) GetByID(ctx context.Context, messageID string) (*domain.Message, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	msg, ok := r.messages[messageID]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, domain.ErrMessageNotFound
+	}
+
+	period, err := r.retentionPeriod(ctx, msg.ChatID)
+	if err != nil {
+		return nil, err
+	}
+	if isExpired(msg, map[string]time.Duration{msg.ChatID: period}, time.Now().UTC()) {
+		return nil, domain.ErrMessageNotFound
+	}
+	return &msg, nil
+}
+
+func (r *MemoryMessageRepo) GetByChatID(ctx context.Context, chatID string, limit, offset int) ([]domain.Message, error) {
+	return r.unexpired(ctx, limit, offset, func(msg domain.Message) bool {
+		return msg.ChatID == chatID && msg.ThreadRootID == nil
+	})
+}
+
+func (r *MemoryMessageRepo) GetByChatIDUntil(ctx context.Context, chatID string, until time.Time, limit, offset int) ([]domain.Message, error) {
+	return r.unexpired(ctx, limit, offset, func(msg domain.Message) bool {
+		return msg.ChatID == chatID && msg.ThreadRootID == nil && !msg.CreatedAt.After(until)
+	})
+}
+
+func (r *MemoryMessageRepo) GetThreadMessages(ctx context.Context, rootID string, until *time.Time, limit, offset int) ([]domain.Message, error) {
+	return r.unexpired(ctx, limit, offset, func(msg domain.Message) bool {
+		return msg.ThreadRootID != nil && *msg.ThreadRootID == rootID && (until == nil || !msg.CreatedAt.After(*until))
+	})
+}
+
+func (r *MemoryMessageRepo) GetByGroupId(ctx context.Context, groupID int, limit, offset int) ([]domain.Message, error) {
+	chat, err := r.chats.GetChatByGroupID(ctx, groupID)
+	if errors.Is(err, chatDomain.ErrChatNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.GetByChatID(ctx, chat.Id, limit, offset)
+}
+
+func (r *MemoryMessageRepo) GetByUserGroup(ctx context.Context, userIDs []string, limit, offset int) ([]domain.Message, error) {
+	if len(userIDs) == 0 {
+		return []domain.Message{}, nil
+	}
+
+	member := make(map[string]bool)
+	for _, chatID := range r.chatIDs() {
+		for _, userID := range userIDs {
+			isParticipant, err := r.chats.IsUserParticipantInChat(ctx, chatID, userID)
+			if err != nil {
+				return nil, err
+			}
+			if isParticipant {
+				member[chatID] = true
+				break
+			}
+		}
+	}
+	return r.unexpired(ctx, limit, offset, func(msg domain.Message) bool {
+		return member[msg.ChatID]
+	})
+}
+
+func (r *MemoryMessageRepo) AddReaction(ctx context.Context, reaction domain.Reaction) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.reactionIndex(reaction.MessageID, reaction.UserID, reaction.Emoji) >= 0 {
+		return false, nil
+	}
+	r.reactions = append(r.reactions, reaction)
+	return true, nil
+}
+
+func (r *MemoryMessageRepo) RemoveReaction(ctx context.Context, messageID, userID, emoji string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i := r.reactionIndex(messageID, userID, emoji)
+	if i < 0 {
+		return false, nil
+	}
+	r.reactions = append(r.reactions[:i:i], r.reactions[i+1:]...)
+	return true, nil
+}
+
+func (r *MemoryMessageRepo) CountReactions(ctx context.Context, messageID, emoji string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	count := 0
+	for _, reaction := range r.reactions {
+		if reaction.MessageID == messageID && reaction.Emoji == emoji {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// GetReactionSummaries lists the emojis of each message in the order they were first used, like MessageRepo.
+func (r *MemoryMessageRepo) GetReactionSummaries(ctx context.Context, messageIDs []string, userID string) (map[string][]domain.ReactionSummary, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	wanted := toSet(messageIDs)
+	reactions := make([]domain.Reaction, 0)
+	for _, reaction := range r.reactions {
+		if wanted[reaction.MessageID] {
+			reactions = append(reactions, reaction)
+		}
+	}
+	sort.SliceStable(reactions, func(i, j int) bool { return reactions[i].CreatedAt.Before(reactions[j].CreatedAt) })
+
+	summaries := make(map[string][]domain.ReactionSummary)
+	for _, reaction := range reactions {
+		messageSummaries := summaries[reaction.MessageID]
+		i := 0
+		for i < len(messageSummaries) && messageSummaries[i].Emoji != reaction.Emoji {
+			i++
+		}
+		if i == len(messageSummaries) {
+			messageSummaries = append(messageSummaries, domain.ReactionSummary{Emoji: reaction.Emoji})
+		}
+		messageSummaries[i].Count++
+		messageSummaries[i].ReactedByMe = messageSummaries[i].ReactedByMe || reaction.UserID == userID
+		summaries[reaction.MessageID] = messageSummaries
+	}
+	return summaries, nil
+}
+
+func (r *MemoryMessageRepo) CreateAttachment(ctx context.Context, attachment domain.Attachment) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.attachments[attachment.ID]; ok {
+		return fmt.Errorf("attachment %s already exists", attachment.ID)
+	}
+	attachment.MessageID = nil
+	attachment.DownloadURL = ""
+	r.attachments[attachment.ID] = attachment
+	return nil
+}
+
+func (r *MemoryMessageRepo) GetAttachmentByID(ctx context.Context, attachmentID string) (*domain.Attachment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	attachment, ok := r.attachments[attachmentID]
+	if !ok {
+		return nil, domain.ErrAttachmentNotFound
+	}
+	return &attachment, nil
+}
+
+func (r *MemoryMessageRepo) GetAttachmentsByMessageIDs(ctx context.Context, messageIDs []string) (map[string][]domain.Attachment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	wanted := toSet(messageIDs)
+	linked := make([]domain.Attachment, 0)
+	for _, attachment := range r.attachments {
+		if attachment.MessageID != nil && wanted[*attachment.MessageID] {
+			linked = append(linked, attachment)
+		}
+	}
+	sort.Slice(linked, func(i, j int) bool { return linked[i].CreatedAt.Before(linked[j].CreatedAt) })
+
+	attachments := make(map[string][]domain.Attachment)
+	for _, attachment := range linked {
+		attachments[*attachment.MessageID] = append(attachments[*attachment.MessageID], attachment)
+	}
+	return attachments, nil
+}
+
+// Search matches every term of the query as a case-insensitive substring of the content, terms prefixed with
+// "-" must not occur. Without the full text index of Postgres there is no stemming, and the rank is the share
+// of the content covered by the terms.
+func (r *MemoryMessageRepo) Search(ctx context.Context, userID string, query domain.SearchQuery) ([]domain.SearchResult, error) {
+	include, exclude := searchTerms(query.Text)
+	if len(include) == 0 {
+		return []domain.SearchResult{}, nil
+	}
+
+	searchable := make(map[string]bool)
+	for _, chatID := range r.chatIDs() {
+		chat, err := r.chats.GetChatById(ctx, chatID)
+		if errors.Is(err, chatDomain.ErrChatNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if chat.IsArchived() && chat.Id != query.ChatID {
+			continue
+		}
+		participant, err := r.chats.GetChatParticipant(ctx, chatID, userID)
+		if errors.Is(err, chatDomain.ErrParticipantNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		searchable[chatID] = participant.CanReadMessages()
+	}
+	retention, err := r.retentionPeriods(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+
+	r.mu.RLock()
+	results := []domain.SearchResult{}
+	for _, msg := range r.messages {
+		if !searchable[msg.ChatID] || isExpired(msg, retention, now) || (query.ChatID != "" && msg.ChatID != query.ChatID) ||
+			(query.SenderID != "" && msg.SenderID != query.SenderID) ||
+			(query.MessageType != "" && msg.MessageType != query.MessageType) ||
+			(query.From != nil && msg.CreatedAt.Before(*query.From)) || (query.To != nil && msg.CreatedAt.After(*query.To)) {
+			continue
+		}
+		content := strings.ToLower(msg.Content)
+		if !containsAll(content, include) || containsAny(content, exclude) {
+			continue
+		}
+		matched := 0
+		for _, term := range include {
+			matched += strings.Count(content, term) * len(term)
+		}
+		results = append(results, domain.SearchResult{
+			Message: msg,
+			Rank:    float64(matched) / float64(len(content)),
+			Snippet: highlightSnippet(markTerms(msg.Content, include)),
+		})
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Rank != results[j].Rank {
+			return results[i].Rank > results[j].Rank
+		}
+		return results[i].Message.CreatedAt.After(results[j].Message.CreatedAt)
+	})
+	return paginate(results, query.Limit, query.Offset), nil
+}
+
+func (r *MemoryMessageRepo) GetMentionsForUser(ctx context.Context, userID string, limit, offset int) ([]domain.MentionNotification, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	notifications := []domain.MentionNotification{}
+	for _, mention := range r.mentions {
+		if msg, ok := r.messages[mention.messageID]; ok && mention.userID == userID {
+			notifications = append(notifications, domain.MentionNotification{Message: msg, MentionType: mention.mentionType, MentionedAt: mention.createdAt})
+		}
+	}
+	r.mu.RUnlock()
+
+	readable := notifications[:0]
+	for _, notification := range notifications {
+		chat, err := r.chats.GetChatById(ctx, notification.Message.ChatID)
+		if errors.Is(err, chatDomain.ErrChatNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		participant, err := r.chats.GetChatParticipant(ctx, notification.Message.ChatID, userID)
+		if errors.Is(err, chatDomain.ErrParticipantNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !chat.IsArchived() &&
+			(participant.Status == chatDomain.StatusActive || participant.Status == chatDomain.StatusMuted) {
+			readable = append(readable, notification)
+		}
+	}
+	sort.SliceStable(readable, func(i, j int) bool { return readable[i].MentionedAt.After(readable[j].MentionedAt) })
+	return paginate(readable, limit, offset), nil
+}
+
+func (r *MemoryMessageRepo) PinMessage(ctx context.Context, pin *domain.Pin) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	position := 1
+	for _, existing := range r.pins[pin.ChatID] {
+		if existing.MessageID == pin.MessageID {
+			return false, nil
+		}
+		position = max(position, existing.Position+1)
+	}
+	if len(r.pins[pin.ChatID]) >= domain.MaxPinsPerChat {
+		return false, ErrPinLimitReached
+	}
+
+	pin.Position = position
+	stored := *pin
+	stored.Message = nil
+	r.pins[pin.ChatID] = append(r.pins[pin.ChatID], stored)
+	return true, nil
+}
+
+func (r *MemoryMessageRepo) UnpinMessage(ctx context.Context, chatID, messageID string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pins := r.pins[chatID]
+	for i := range pins {
+		if pins[i].MessageID == messageID {
+			r.pins[chatID] = append(pins[:i:i], pins[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *MemoryMessageRepo) GetPins(ctx context.Context, chatID string) ([]domain.Pin, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	pins := []domain.Pin{}
+	for _, pin := range r.pins[chatID] {
+		msg, ok := r.messages[pin.MessageID]
+		if !ok {
+			continue
+		}
+		pin.Message = &msg
+		pins = append(pins, pin)
+	}
+	sort.Slice(pins, func(i, j int) bool { return pins[i].Position < pins[j].Position })
+	return pins, nil
+}
+
+func (r *MemoryMessageRepo) CreateScheduledMessage(ctx context.Context, scheduled domain.ScheduledMessage) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.scheduled[scheduled.ID]; ok {
+		return fmt.Errorf("scheduled message %s already exists", scheduled.ID)
+	}
+	r.scheduled[scheduled.ID] = memoryScheduled{ScheduledMessage: scheduled}
+	return nil
+}
+
+func (r *MemoryMessageRepo) GetScheduledMessage(ctx context.Context, scheduledID string) (*domain.ScheduledMessage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	scheduled, ok := r.scheduled[scheduledID]
+	if !ok {
+		return nil, domain.ErrScheduledMessageNotFound
+	}
+	return &scheduled.ScheduledMessage, nil
+}
+
+func (r *MemoryMessageRepo) GetPendingScheduledMessages(ctx context.Context, chatID, senderID string) ([]domain.ScheduledMessage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	pending := []domain.ScheduledMessage{}
+	for _, scheduled := range r.scheduled {
+		if scheduled.ChatID == chatID && scheduled.SenderID == senderID && scheduled.Status == domain.ScheduledPending {
+			pending = append(pending, scheduled.ScheduledMessage)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].SendAt.Before(pending[j].SendAt) })
+	return pending, nil
+}
+
+func (r *MemoryMessageRepo) UpdateScheduledMessage(ctx context.Context, scheduled domain.ScheduledMessage) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.scheduled[scheduled.ID]
+	if !ok || stored.Status != domain.ScheduledPending {
+		return false, nil
+	}
+	stored.Content = scheduled.Content
+	stored.SendAt = scheduled.SendAt
+	stored.UpdatedAt = scheduled.UpdatedAt
+	r.scheduled[scheduled.ID] = stored
+	return true, nil
+}
+
+func (r *MemoryMessageRepo) CancelScheduledMessage(ctx context.Context, scheduledID string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.scheduled[scheduledID]
+	if !ok || stored.Status != domain.ScheduledPending {
+		return false, nil
+	}
+	stored.Status = domain.ScheduledCancelled
+	stored.UpdatedAt = time.Now().UTC()
+	r.scheduled[scheduledID] = stored
+	return true, nil
+}
+
+func (r *MemoryMessageRepo) ClaimDueScheduledMessages(ctx context.Context, now, staleBefore time.Time, limit int) ([]domain.ScheduledMessage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	due := []domain.ScheduledMessage{}
+	for _, scheduled := range r.scheduled {
+		if (scheduled.Status == domain.ScheduledPending && !scheduled.SendAt.After(now)) ||
+			(scheduled.Status == domain.ScheduledSending && scheduled.claimedAt.Before(staleBefore)) {
+			due = append(due, scheduled.ScheduledMessage)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].SendAt.Before(due[j].SendAt) })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	for i := range due {
+		due[i].Status = domain.ScheduledSending
+		due[i].UpdatedAt = now
+		r.scheduled[due[i].ID] = memoryScheduled{ScheduledMessage: due[i], claimedAt: now}
+	}
+	return due, nil
+}
+
+func (r *MemoryMessageRepo) CompleteScheduledMessage(ctx context.Context, scheduledID string, status domain.ScheduledStatus, failureReason *string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if stored, ok := r.scheduled[scheduledID]; ok {
+		stored.Status = status
+		stored.FailureReason = failureReason
+		stored.UpdatedAt = time.Now().UTC()
+		r.scheduled[scheduledID] = stored
+	}
+	return nil
+}
+
+// PurgeExpiredMessages applies the same rules as MessageRepo.PurgeExpiredMessages, reading the retention period
+// of each chat from the chat repository.
+func (r *MemoryMessageRepo) PurgeExpiredMessages(ctx context.Context, now time.Time, limit int) (*domain.PurgeResult, error) {
+	result := &domain.PurgeResult{Messages: []domain.ExpiredMessage{}, StorageKeys: []string{}}
+
+	retention, err := r.retentionPeriods(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	expired := make([]domain.Message, 0)
+	for _, msg := range r.messages {
+		if isExpired(msg, retention, now) {
+			expired = append(expired, msg)
+		}
+	}
+	sort.Slice(expired, func(i, j int) bool { return expired[i].CreatedAt.Before(expired[j].CreatedAt) })
+	if len(expired) > limit {
+		expired = expired[:limit]
+	}
+	if len(expired) == 0 {
+		return result, nil
+	}
+
+	expiredIDs := make(map[string]bool)
+	for _, msg := range expired {
+		expiredIDs[msg.ID] = true
+	}
+	affectedRoots := make(map[string]bool)
+	removed := r.removeMessages(func(msg domain.Message) bool {
+		return expiredIDs[msg.ID] || (msg.ThreadRootID != nil && expiredIDs[*msg.ThreadRootID])
+	}, result)
+	for _, msg := range removed {
+		result.Messages = append(result.Messages, domain.ExpiredMessage{ID: msg.ID, ChatID: msg.ChatID})
+		if msg.ThreadRootID != nil {
+			affectedRoots[*msg.ThreadRootID] = true
+		}
+	}
+
+	for rootID := range affectedRoots {
+		root, ok := r.messages[rootID]
+		if !ok {
+			continue
+		}
+		root.ThreadReplyCount = 0
+		root.ThreadLastReplyAt = nil
+		for _, msg := range r.messages {
+			if msg.ThreadRootID == nil || *msg.ThreadRootID != rootID {
+				continue
+			}
+			root.ThreadReplyCount++
+			if root.ThreadLastReplyAt == nil || msg.CreatedAt.After(*root.ThreadLastReplyAt) {
+				lastReplyAt := msg.CreatedAt
+				root.ThreadLastReplyAt = &lastReplyAt
+			}
+		}
+		r.messages[rootID] = root
+	}
+	return result, nil
+}
+
+func (r *MemoryMessageRepo) GetLinkPreviewCache(ctx context.Context, url string) (*domain.LinkPreviewCacheEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entry, ok := r.linkPreviews[url]
+	if !ok {
+		return new(domain.LinkPreviewCacheEntry), nil
+	}
+	return &entry, nil
+}
+
+func (r *MemoryMessageRepo) SaveLinkPreviewCache(ctx context.Context, entry domain.LinkPreviewCacheEntry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if entry.Preview != nil {
+		preview := *entry.Preview
+		preview.URL = entry.URL
+		entry.Preview = &preview
+	}
+	r.linkPreviews[entry.URL] = entry
+	return nil
+}
+
+func (r *MemoryMessageRepo) SetLinkPreviews(ctx context.Context, messageID string, previews domain.LinkPreviews) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if msg, ok := r.messages[messageID]; ok {
+		msg.LinkPreviews = previews
+		r.messages[messageID] = msg
+	}
+	return nil
+}
+
+// removeChats is registered with the chat repository and drops everything belonging to purged chats.
+func (r *MemoryMessageRepo) removeChats(chatIDs []string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	removedChats := toSet(chatIDs)
+	result := &domain.PurgeResult{StorageKeys: []string{}}
+	r.removeMessages(func(msg domain.Message) bool { return removedChats[msg.ChatID] }, result)
+	for id, attachment := range r.attachments {
+		if removedChats[attachment.ChatID] {
+			result.StorageKeys = append(result.StorageKeys, attachment.StorageKey)
+			delete(r.attachments, id)
+		}
+	}
+	for id, scheduled := range r.scheduled {
+		if removedChats[scheduled.ChatID] {
+			delete(r.scheduled, id)
+		}
+	}
+	for _, chatID := range chatIDs {
+		delete(r.pins, chatID)
+	}
+	return result.StorageKeys
+}
+
+// removeMessages deletes the matching messages with their attachments, reactions, mentions and pins, adding the
+// attachment storage keys to result. It must be called with the lock held.
+func (r *MemoryMessageRepo) removeMessages(match func(domain.Message) bool, result *domain.PurgeResult) []domain.Message {
+	removed := make([]domain.Message, 0)
+	removedIDs := make(map[string]bool)
+	for id, msg := range r.messages {
+		if match(msg) {
+			removed = append(removed, msg)
+			removedIDs[id] = true
+			delete(r.messages, id)
+		}
+	}
+	if len(removed) == 0 {
+		return removed
+	}
+
+	for id, attachment := range r.attachments {
+		if attachment.MessageID != nil && removedIDs[*attachment.MessageID] {
+			result.StorageKeys = append(result.StorageKeys, attachment.StorageKey)
+			delete(r.attachments, id)
+		}
+	}
+	reactions := r.reactions[:0]
+	for _, reaction := range r.reactions {
+		if !removedIDs[reaction.MessageID] {
+			reactions = append(reactions, reaction)
+		}
+	}
+	r.reactions = reactions
+	mentions := r.mentions[:0]
+	for _, mention := range r.mentions {
+		if !removedIDs[mention.messageID] {
+			mentions = append(mentions, mention)
+		}
+	}
+	r.mentions = mentions
+	for chatID, pins := range r.pins {
+		kept := pins[:0]
+		for _, pin := range pins {
+			if !removedIDs[pin.MessageID] {
+				kept = append(kept, pin)
+			}
+		}
+		r.pins[chatID] = kept
+	}
+	return removed
+}
+
+// timeline returns the matching messages oldest first. Like scanMessages it returns nil when nothing matches.
+func (r *MemoryMessageRepo) timeline(ctx context.Context, limit, offset int, match func(domain.Message) bool) ([]domain.Message, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	var messages []domain.Message
+	for _, msg := range r.messages {
+		if match(msg) {
+			messages = append(messages, msg)
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(messages, func(i, j int) bool { return messages[i].CreatedAt.Before(messages[j].CreatedAt) })
+	return paginate(messages, limit, offset), nil
+}
+
+// unexpired is timeline without the messages whose TTL ran out or that are past the retention period of their
+// chat, which MessageRepo hides before the purge job deleted them.
+func (r *MemoryMessageRepo) unexpired(ctx context.Context, limit, offset int, match func(domain.Message) bool) ([]domain.Message, error) {
+	retention, err := r.retentionPeriods(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	return r.timeline(ctx, limit, offset, func(msg domain.Message) bool {
+		return match(msg) && !isExpired(msg, retention, now)
+	})
+}
+
+// retentionPeriods reads the retention period of every chat that has messages, deleted chats included.
+func (r *MemoryMessageRepo) retentionPeriods(ctx context.Context) (map[string]time.Duration, error) {
+	retention := make(map[string]time.Duration)
+	for _, chatID := range r.chatIDs() {
+		period, err := r.retentionPeriod(ctx, chatID)
+		if err != nil {
+			return nil, err
+		}
+		retention[chatID] = period
+	}
+	return retention, nil
+}
+
+func (r *MemoryMessageRepo) retentionPeriod(ctx context.Context, chatID string) (time.Duration, error) {
+	chat, err := r.chats.GetChatById(ctx, chatID)
+	if errors.Is(err, chatDomain.ErrChatNotFound) {
+		chat, err = r.chats.GetDeletedChatById(ctx, chatID)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return chat.Settings.RetentionPeriod(), nil
+}
+
+func isExpired(msg domain.Message, retention map[string]time.Duration, now time.Time) bool {
+	period := retention[msg.ChatID]
+	return (msg.ExpiresAt != nil && !msg.ExpiresAt.After(now)) || (period > 0 && !msg.CreatedAt.After(now.Add(-period)))
+}
+
+// chatIDs lists the chats that have messages. Chat lookups happen outside the lock, so the two repositories
+// never wait on each other.
+func (r *MemoryMessageRepo) chatIDs() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	chatIDs := make([]string, 0)
+	for _, msg := range r.messages {
+		if !seen[msg.ChatID] {
+			seen[msg.ChatID] = true
+			chatIDs = append(chatIDs, msg.ChatID)
+		}
+	}
+	return chatIDs
+}
+
+// mentionIndex must be called with the lock held.
+func (r *MemoryMessageRepo) mentionIndex(messageID, userID string) int {
+	for i, mention := range r.mentions {
+		if mention.messageID == messageID && mention.userID == userID {
+			return i
+		}
+	}
+	return -1
+}
+
+// reactionIndex must be called with the lock held.
+func (r *MemoryMessageRepo) reactionIndex(messageID, userID, emoji string) int {
+	for i, reaction := range r.reactions {
+		if reaction.MessageID == messageID && reaction.UserID == userID && reaction.Emoji == emoji {
+			return i
+		}
+	}
+	return -1
+}
+
+// paginate applies LIMIT and OFFSET, a limit of 0 or less returns everything after offset.
+func paginate[T any](items []T, limit, offset int) []T {
+	if offset >= len(items) {
+		return items[:0]
+	}
+	items = items[offset:]
+	if limit > 0 && len(items) > limit {
+		items = items[:limit]
+	}
+	return items
+}
+
+func searchTerms(text string) (include, exclude []string) {
+	for _, field := range strings.Fields(strings.ToLower(text)) {
+		negated := strings.HasPrefix(field, "-")
+		term := strings.Trim(field, `-"`)
+		if term == "" || term == "or" {
+			continue
+		}
+		if negated {
+			exclude = append(exclude, term)
+		} else {
+			include = append(include, term)
+		}
+	}
+	return include, exclude
+}
+
+func containsAll(content string, terms []string) bool {
+	for _, term := range terms {
+		if !strings.Contains(content, term) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsAny(content string, terms []string) bool {
+	for _, term := range terms {
+		if strings.Contains(content, term) {
+			return true
+		}
+	}
+	return false
+}
+
+// markTerms wraps the occurrences of the terms in the highlight delimiters understood by highlightSnippet.
+// Content whose byte length changes when lowercased is left unmarked, as the offsets would not line up.
+func markTerms(content string, terms []string) string {
+	lower := strings.ToLower(content)
+	if len(lower) != len(content) {
+		return content
+	}
+	var marked strings.Builder
+	for i := 0; i < len(content); {
+		length := 0
+		for _, term := range terms {
+			if strings.HasPrefix(lower[i:], term) && len(term) > length {
+				length = len(term)
+			}
+		}
+		if length == 0 {
+			marked.WriteByte(content[i])
+			i++
+			continue
+		}
+		marked.WriteString(highlightStart + content[i:i+length] + highlightStop)
+		i += length
+	}
+	return marked.String()
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/HappYness-Project/ChatBackendServer/dbs"
+	domain "github.com/HappYness-Project/ChatBackendServer/internal/message/domain"
+)
+
+func insertMentionRecipients(ctx context.Context, tx dbs.Querier, message domain.Message) error {
+	for _, recipient := range message.MentionRecipients {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO message_mention (message_id, user_id, mention_type, created_at)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (message_id, user_id) DO NOTHING`,
+			message.ID, recipient.UserID, recipient.Type, message.CreatedAt)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetMentionsForUser lists the messages mentioning the user, newest first, limited to chats the user can still read.
+// Mentions in archived chats are left out like the chats themselves.
+func (r *MessageRepo) GetMentionsForUser(ctx context.Context, userID string, limit, offset int) ([]domain.MentionNotification, error) {
+	rows, err := r.conn(ctx).QueryContext(ctx, `
+		SELECT `+messageColumns+`, mm.mention_type, mm.created_at
+		FROM message_mention mm
+		INNER JOIN message m ON m.id = mm.message_id
+		INNER JOIN chat_participant cp ON cp.chat_id = m.chat_id AND cp.user_id = mm.user_id
+		INNER JOIN chat c ON c.id = m.chat_id AND c.deleted_at IS NULL AND c.archived_at IS NULL
+		WHERE mm.user_id = $1 AND cp.status IN ('active', 'muted')
+		ORDER BY mm.created_at DESC
+		LIMIT $2 OFFSET $3`, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := []domain.MentionNotification{}
+	for rows.Next() {
+		var notification domain.MentionNotification
+		targets := append(messageScanTargets(&notification.Message), &notification.MentionType, &notification.MentionedAt)
+		if err := rows.Scan(targets...); err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, notification)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/HappYness-Project/ChatBackendServer/common"
+	"github.com/HappYness-Project/ChatBackendServer/dbs"
+	domain "github.com/HappYness-Project/ChatBackendServer/internal/message/domain"
+)
+
+var ErrPinLimitReached = common.Conflict("PinLimitReached", "the chat has reached the maximum number of pinned messages")
+
+// PinMessage appends the message to the chat's pins and reports whether it was newly pinned.
+// The chat row is locked so concurrent pins get distinct positions and respect the pin limit.
+func (r *MessageRepo) PinMessage(ctx context.Context, pin *domain.Pin) (bool, error) {
+	tx, err := dbs.BeginTx(ctx, r.db)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.ExecContext(ctx, `SELECT id FROM chat WHERE id = $1 FOR UPDATE`, pin.ChatID); err != nil {
+		return false, err
+	}
+
+	var count, position int
+	err = tx.QueryRowContext(ctx, `
+		SELECT COUNT(*), COALESCE(MAX(position), 0) + 1
+		FROM message_pin
+		WHERE chat_id = $1`, pin.ChatID).Scan(&count, &position)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO message_pin (chat_id, message_id, pinned_by, pinned_at, position)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (chat_id, message_id) DO NOTHING`,
+		pin.ChatID, pin.MessageID, pin.PinnedBy, pin.PinnedAt, position)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected == 0 {
+		return false, nil
+	}
+	// Checked after the insert so pinning an already pinned message stays a no-op when the chat is full.
+	if count >= domain.MaxPinsPerChat {
+		return false, ErrPinLimitReached
+	}
+
+	pin.Position = position
+	return true, tx.Commit()
+}
+
+// UnpinMessage removes the pin and reports whether the message was pinned.
+func (r *MessageRepo) UnpinMessage(ctx context.Context, chatID, messageID string) (bool, error) {
+	result, err := r.conn(ctx).ExecContext(ctx, `DELETE FROM message_pin WHERE chat_id = $1 AND message_id = $2`, chatID, messageID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// GetPins lists the pinned messages of the chat in pin order.
+func (r *MessageRepo) GetPins(ctx context.Context, chatID string) ([]domain.Pin, error) {
+	rows, err := r.conn(ctx).QueryContext(ctx, `
+		SELECT p.chat_id, p.message_id, p.pinned_by, p.pinned_at, p.position, `+messageColumns+`
+		FROM message_pin p
+		INNER JOIN message m ON m.id = p.message_id
+		WHERE p.chat_id = $1
+		ORDER BY p.position ASC`, chatID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pins := []domain.Pin{}
+	for rows.Next() {
+		pin := domain.Pin{Message: new(domain.Message)}
+		targets := append([]any{&pin.ChatID, &pin.MessageID, &pin.PinnedBy, &pin.PinnedAt, &pin.Position},
+			messageScanTargets(pin.Message)...)
+		if err := rows.Scan(targets...); err != nil {
+			return nil, err
+		}
+		pins = append(pins, pin)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return pins, nil
+}
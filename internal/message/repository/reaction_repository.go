@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	domain "github.com/HappYness-Project/ChatBackendServer/internal/message/domain"
+)
+
+// AddReaction stores the reaction and reports whether it was new, adding the same reaction twice is a no-op.
+func (r *MessageRepo) AddReaction(ctx context.Context, reaction domain.Reaction) (bool, error) {
+	result, err := r.conn(ctx).ExecContext(ctx, `
+		INSERT INTO message_reaction (message_id, user_id, emoji, created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (message_id, user_id, emoji) DO NOTHING`,
+		reaction.MessageID, reaction.UserID, reaction.Emoji, reaction.CreatedAt)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// RemoveReaction deletes the reaction and reports whether it existed.
+func (r *MessageRepo) RemoveReaction(ctx context.Context, messageID, userID, emoji string) (bool, error) {
+	result, err := r.conn(ctx).ExecContext(ctx, `
+		DELETE FROM message_reaction
+		WHERE message_id = $1 AND user_id = $2 AND emoji = $3`, messageID, userID, emoji)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+func (r *MessageRepo) CountReactions(ctx context.Context, messageID, emoji string) (int, error) {
+	var count int
+	err := r.conn(ctx).QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM message_reaction
+		WHERE message_id = $1 AND emoji = $2`, messageID, emoji).Scan(&count)
+	return count, err
+}
+
+// GetReactionSummaries aggregates reactions per message and emoji, flagging the ones added by userID.
+func (r *MessageRepo) GetReactionSummaries(ctx context.Context, messageIDs []string, userID string) (map[string][]domain.ReactionSummary, error) {
+	summaries := make(map[string][]domain.ReactionSummary)
+	if len(messageIDs) == 0 {
+		return summaries, nil
+	}
+
+	rows, err := r.conn(ctx).QueryContext(ctx, `
+		SELECT message_id, emoji, COUNT(*), BOOL_OR(user_id::text = $2)
+		FROM message_reaction
+		WHERE message_id = ANY($1)
+		GROUP BY message_id, emoji
+		ORDER BY message_id, MIN(created_at)`, messageIDs, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var messageID string
+		var summary domain.ReactionSummary
+		if err := rows.Scan(&messageID, &summary.Emoji, &summary.Count, &summary.ReactedByMe); err != nil {
+			return nil, err
+		}
+		summaries[messageID] = append(summaries[messageID], summary)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return summaries, nil
+}
@@ -1,91 +1,266 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"time"
 
+	"github.com/HappYness-Project/ChatBackendServer/dbs"
 	domain "github.com/HappYness-Project/ChatBackendServer/internal/message/domain"
 )
 
 type MessageRepository interface {
-	Create(message domain.Message) error
-	GetByChatID(chatID string, limit, offset int) ([]domain.Message, error)
-	GetByGroupId(groupID int, limit, offset int) ([]domain.Message, error)
-	GetByUserGroup(userIDs []string, limit, offset int) ([]domain.Message, error)
+	Create(ctx context.Context, message domain.Message) error
+	GetByID(ctx context.Context, messageID string) (*domain.Message, error)
+	GetByChatID(ctx context.Context, chatID string, limit, offset int) ([]domain.Message, error)
+	GetByChatIDUntil(ctx context.Context, chatID string, until time.Time, limit, offset int) ([]domain.Message, error)
+	GetThreadMessages(ctx context.Context, rootID string, until *time.Time, limit, offset int) ([]domain.Message, error)
+	GetByGroupId(ctx context.Context, groupID int, limit, offset int) ([]domain.Message, error)
+	GetByUserGroup(ctx context.Context, userIDs []string, limit, offset int) ([]domain.Message, error)
+	AddReaction(ctx context.Context, reaction domain.Reaction) (bool, error)
+	RemoveReaction(ctx context.Context, messageID, userID, emoji string) (bool, error)
+	CountReactions(ctx context.Context, messageID, emoji string) (int, error)
+	GetReactionSummaries(ctx context.Context, messageIDs []string, userID string) (map[string][]domain.ReactionSummary, error)
+	CreateAttachment(ctx context.Context, attachment domain.Attachment) error
+	GetAttachmentByID(ctx context.Context, attachmentID string) (*domain.Attachment, error)
+	GetAttachmentsByMessageIDs(ctx context.Context, messageIDs []string) (map[string][]domain.Attachment, error)
+	Search(ctx context.Context, userID string, query domain.SearchQuery) ([]domain.SearchResult, error)
+	GetMentionsForUser(ctx context.Context, userID string, limit, offset int) ([]domain.MentionNotification, error)
+	PinMessage(ctx context.Context, pin *domain.Pin) (bool, error)
+	UnpinMessage(ctx context.Context, chatID, messageID string) (bool, error)
+	GetPins(ctx context.Context, chatID string) ([]domain.Pin, error)
+	CreateScheduledMessage(ctx context.Context, scheduled domain.ScheduledMessage) error
+	GetScheduledMessage(ctx context.Context, scheduledID string) (*domain.ScheduledMessage, error)
+	GetPendingScheduledMessages(ctx context.Context, chatID, senderID string) ([]domain.ScheduledMessage, error)
+	UpdateScheduledMessage(ctx context.Context, scheduled domain.ScheduledMessage) (bool, error)
+	CancelScheduledMessage(ctx context.Context, scheduledID string) (bool, error)
+	ClaimDueScheduledMessages(ctx context.Context, now, staleBefore time.Time, limit int) ([]domain.ScheduledMessage, error)
+	CompleteScheduledMessage(ctx context.Context, scheduledID string, status domain.ScheduledStatus, failureReason *string) error
+	PurgeExpiredMessages(ctx context.Context, now time.Time, limit int) (*domain.PurgeResult, error)
+	GetLinkPreviewCache(ctx context.Context, url string) (*domain.LinkPreviewCacheEntry, error)
+	SaveLinkPreviewCache(ctx context.Context, entry domain.LinkPreviewCacheEntry) error
+	SetLinkPreviews(ctx context.Context, messageID string, previews domain.LinkPreviews) error
 }
 
+const messageColumns = `m.id, m.chat_id, m.sender_id, m.content, m.message_type, m.created_at, m.read_status,
+		m.reply_to_id, m.thread_root_id, m.thread_reply_count, m.thread_last_reply_at, m.mentions, m.expires_at, m.link_previews`
+
+// notExpired hides messages whose TTL ran out or that are past the retention period of their chat, so they are
+// gone from the moment they expire and not only once PurgeExpiredMessages deleted them.
+const notExpired = `(m.expires_at IS NULL OR m.expires_at > now())
+		AND NOT EXISTS (
+			SELECT 1 FROM chat rc
+			WHERE rc.id = m.chat_id
+				AND COALESCE((rc.settings->>'retention_seconds')::int, 0) > 0
+				AND m.created_at <= now() - make_interval(secs => (rc.settings->>'retention_seconds')::int))`
+
 type MessageRepo struct {
 	db *sql.DB
 }
 
+var _ MessageRepository = (*MessageRepo)(nil)
+
 func NewRepository(db *sql.DB) *MessageRepo {
 	return &MessageRepo{db: db}
 }
-func (r *MessageRepo) Create(message domain.Message) error {
+
+// conn returns the connection to run statements on, the transaction of a unit of work if ctx carries one.
+func (r *MessageRepo) conn(ctx context.Context) dbs.Querier {
+	return dbs.Conn(ctx, r.db)
+}
+
+// Create stores the message with its mention recipients and links its attachments. Thread replies also bump the reply count and
+// last reply time of the thread root in the same transaction.
+func (r *MessageRepo) Create(ctx context.Context, message domain.Message) error {
+	tx, err := dbs.BeginTx(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	query := `
-		INSERT INTO message (id, chat_id, sender_id, content, message_type, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6)`
-	_, err := r.db.Exec(query, message.ID, message.ChatID, message.SenderID, message.Content, message.MessageType, message.CreatedAt)
+		INSERT INTO message (id, chat_id, sender_id, content, message_type, created_at, reply_to_id, thread_root_id, mentions, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+	_, err = tx.ExecContext(ctx, query, message.ID, message.ChatID, message.SenderID, message.Content, message.MessageType, message.CreatedAt,
+		message.ReplyToID, message.ThreadRootID, message.Mentions, message.ExpiresAt)
+	if err != nil {
+		return dbs.TranslateError(err)
+	}
+	if err = linkAttachments(ctx, tx, message); err != nil {
+		return err
+	}
+	if err = insertMentionRecipients(ctx, tx, message); err != nil {
+		return err
+	}
+
+	if message.ThreadRootID != nil {
+		_, err = tx.ExecContext(ctx, `
+			UPDATE message
+			SET thread_reply_count = thread_reply_count + 1,
+				thread_last_reply_at = GREATEST(COALESCE(thread_last_reply_at, $2), $2)
+			WHERE id = $1`, *message.ThreadRootID, message.CreatedAt)
+		if err != nil {
+			return err
+		}
+	}
 
-	return err
+	return tx.Commit()
 }
-func (r *MessageRepo) GetByChatID(chatID string, limit, offset int) ([]domain.Message, error) {
+
+func (r *MessageRepo) GetByID(ctx context.Context, messageID string) (*domain.Message, error) {
 	query := `
-		SELECT id, chat_id, sender_id, content, message_type, created_at, read_status
-		FROM message
-		WHERE chat_id = $1
-		ORDER BY created_at ASC
-		LIMIT $2 OFFSET $3
+		SELECT ` + messageColumns + `
+		FROM message m
+		WHERE m.id = $1 AND ` + notExpired + `
 	`
 
-	rows, err := r.db.Query(query, chatID, limit, offset)
+	rows, err := r.conn(ctx).QueryContext(ctx, query, messageID)
 	if err != nil {
-		return nil, err
+		return nil, dbs.TranslateError(err)
 	}
 	defer rows.Close()
 
-	var messages []domain.Message
+	msg := new(domain.Message)
 	for rows.Next() {
-		var msg domain.Message
-		err := rows.Scan(&msg.ID, &msg.ChatID, &msg.SenderID, &msg.Content,
-			&msg.MessageType, &msg.CreatedAt, &msg.ReadStatus)
-		if err != nil {
+		if msg, err = scanMessage(rows); err != nil {
 			return nil, err
 		}
-		messages = append(messages, msg)
 	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if msg.ID == "" {
+		return nil, domain.ErrMessageNotFound
+	}
+	return msg, nil
+}
 
-	return messages, nil
+// GetByChatID returns the chat timeline. Thread replies are only listed through GetThreadMessages.
+func (r *MessageRepo) GetByChatID(ctx context.Context, chatID string, limit, offset int) ([]domain.Message, error) {
+	query := `
+		SELECT ` + messageColumns + `
+		FROM message m
+		WHERE m.chat_id = $1 AND m.thread_root_id IS NULL AND ` + notExpired + `
+		ORDER BY m.created_at ASC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.conn(ctx).QueryContext(ctx, query, chatID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanMessages(rows)
+}
+
+// GetByChatIDUntil returns the chat messages created up to the given time, used for participants that left the chat.
+func (r *MessageRepo) GetByChatIDUntil(ctx context.Context, chatID string, until time.Time, limit, offset int) ([]domain.Message, error) {
+	query := `
+		SELECT ` + messageColumns + `
+		FROM message m
+		WHERE m.chat_id = $1 AND m.thread_root_id IS NULL AND m.created_at <= $2 AND ` + notExpired + `
+		ORDER BY m.created_at ASC
+		LIMIT $3 OFFSET $4
+	`
+
+	rows, err := r.conn(ctx).QueryContext(ctx, query, chatID, until, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanMessages(rows)
+}
+
+// GetThreadMessages returns the replies of a thread, oldest first. A non-nil until hides replies posted after it.
+func (r *MessageRepo) GetThreadMessages(ctx context.Context, rootID string, until *time.Time, limit, offset int) ([]domain.Message, error) {
+	query := `
+		SELECT ` + messageColumns + `
+		FROM message m
+		WHERE m.thread_root_id = $1 AND ($2::timestamptz IS NULL OR m.created_at <= $2) AND ` + notExpired + `
+		ORDER BY m.created_at ASC
+		LIMIT $3 OFFSET $4
+	`
+
+	rows, err := r.conn(ctx).QueryContext(ctx, query, rootID, until, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanMessages(rows)
+}
+
+// GetByGroupId returns the timeline of the chat belonging to the user group.
+func (r *MessageRepo) GetByGroupId(ctx context.Context, groupID int, limit, offset int) ([]domain.Message, error) {
+	query := `
+		SELECT ` + messageColumns + `
+		FROM message m
+		INNER JOIN chat c ON c.id = m.chat_id AND c.deleted_at IS NULL
+		WHERE c.usergroup_id = $1 AND m.thread_root_id IS NULL AND ` + notExpired + `
+		ORDER BY m.created_at ASC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.conn(ctx).QueryContext(ctx, query, groupID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanMessages(rows)
 }
-func (r *MessageRepo) GetByUserGroup(userIDs []string, limit, offset int) ([]domain.Message, error) {
+
+func (r *MessageRepo) GetByUserGroup(ctx context.Context, userIDs []string, limit, offset int) ([]domain.Message, error) {
 	if len(userIDs) == 0 {
 		return []domain.Message{}, nil
 	}
 
 	query := `
-		SELECT DISTINCT m.id, m.chat_id, m.sender_id, m.content, m.message_type, m.created_at, m.read_status
+		SELECT DISTINCT ` + messageColumns + `
 		FROM message m
 		INNER JOIN chat_participant cp ON m.chat_id = cp.chat_id
-		WHERE cp.user_id = ANY($1)
+		WHERE cp.user_id = ANY($1) AND ` + notExpired + `
 		ORDER BY m.created_at ASC
 		LIMIT $2 OFFSET $3
 	`
 
-	rows, err := r.db.Query(query, userIDs, limit, offset)
+	rows, err := r.conn(ctx).QueryContext(ctx, query, userIDs, limit, offset)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
+	return scanMessages(rows)
+}
+
+func scanMessages(rows *sql.Rows) ([]domain.Message, error) {
 	var messages []domain.Message
 	for rows.Next() {
-		var msg domain.Message
-		err := rows.Scan(&msg.ID, &msg.ChatID, &msg.SenderID, &msg.Content,
-			&msg.MessageType, &msg.CreatedAt, &msg.ReadStatus)
+		msg, err := scanMessage(rows)
 		if err != nil {
 			return nil, err
 		}
-		messages = append(messages, msg)
+		messages = append(messages, *msg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
-
 	return messages, nil
 }
+
+func scanMessage(rows *sql.Rows) (*domain.Message, error) {
+	msg := new(domain.Message)
+	if err := rows.Scan(messageScanTargets(msg)...); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// messageScanTargets lists the fields matching messageColumns, for queries that select additional columns.
+func messageScanTargets(msg *domain.Message) []any {
+	return []any{&msg.ID, &msg.ChatID, &msg.SenderID, &msg.Content,
+		&msg.MessageType, &msg.CreatedAt, &msg.ReadStatus,
+		&msg.ReplyToID, &msg.ThreadRootID, &msg.ThreadReplyCount, &msg.ThreadLastReplyAt, &msg.Mentions, &msg.ExpiresAt, &msg.LinkPreviews}
+}
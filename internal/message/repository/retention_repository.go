@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/HappYness-Project/ChatBackendServer/dbs"
+	domain "github.com/HappYness-Project/ChatBackendServer/internal/message/domain"
+)
+
+// PurgeExpiredMessages deletes up to limit messages whose TTL ran out or that are older than the retention period
+// of their chat. Thread replies are removed with their root, and threads losing replies get their summary recomputed.
+// Rows locked by a concurrent purge are skipped, so several instances can run the job at once.
+func (r *MessageRepo) PurgeExpiredMessages(ctx context.Context, now time.Time, limit int) (*domain.PurgeResult, error) {
+	result := &domain.PurgeResult{Messages: []domain.ExpiredMessage{}, StorageKeys: []string{}}
+
+	tx, err := dbs.BeginTx(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT m.id
+		FROM message m
+		JOIN chat c ON c.id = m.chat_id
+		WHERE m.expires_at <= $1
+			OR (COALESCE((c.settings->>'retention_seconds')::int, 0) > 0
+				AND m.created_at <= $1 - make_interval(secs => (c.settings->>'retention_seconds')::int))
+		ORDER BY m.created_at ASC
+		LIMIT $2
+		FOR UPDATE OF m SKIP LOCKED`, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	expiredIDs := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		expiredIDs = append(expiredIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(expiredIDs) == 0 {
+		return result, nil
+	}
+
+	// Attachment rows would cascade with their message, delete them first to learn which blobs to remove.
+	rows, err = tx.QueryContext(ctx, `
+		DELETE FROM message_attachment a
+		USING message m
+		WHERE a.message_id = m.id AND (m.id = ANY($1) OR m.thread_root_id = ANY($1))
+		RETURNING a.storage_key`, expiredIDs)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		result.StorageKeys = append(result.StorageKeys, key)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = tx.QueryContext(ctx, `
+		DELETE FROM message
+		WHERE id = ANY($1) OR thread_root_id = ANY($1)
+		RETURNING id, chat_id, thread_root_id`, expiredIDs)
+	if err != nil {
+		return nil, err
+	}
+	deleted := make(map[string]bool)
+	affectedRoots := []string{}
+	for rows.Next() {
+		var expired domain.ExpiredMessage
+		var threadRootID *string
+		if err := rows.Scan(&expired.ID, &expired.ChatID, &threadRootID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		result.Messages = append(result.Messages, expired)
+		deleted[expired.ID] = true
+		if threadRootID != nil {
+			affectedRoots = append(affectedRoots, *threadRootID)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	remainingRoots := []string{}
+	for _, rootID := range affectedRoots {
+		if !deleted[rootID] {
+			remainingRoots = append(remainingRoots, rootID)
+		}
+	}
+	if len(remainingRoots) > 0 {
+		_, err = tx.ExecContext(ctx, `
+			UPDATE message root
+			SET thread_reply_count = (SELECT COUNT(*) FROM message WHERE thread_root_id = root.id),
+				thread_last_reply_at = (SELECT MAX(created_at) FROM message WHERE thread_root_id = root.id)
+			WHERE root.id = ANY($1)`, remainingRoots)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/HappYness-Project/ChatBackendServer/dbs"
+	domain "github.com/HappYness-Project/ChatBackendServer/internal/message/domain"
+)
+
+const scheduledColumns = `s.id, s.chat_id, s.sender_id, s.content, s.message_type, s.reply_to_id, s.thread_root_id,
+		s.send_at, s.status, s.failure_reason, s.created_at, s.updated_at`
+
+func (r *MessageRepo) CreateScheduledMessage(ctx context.Context, scheduled domain.ScheduledMessage) error {
+	_, err := r.conn(ctx).ExecContext(ctx, `
+		INSERT INTO scheduled_message (id, chat_id, sender_id, content, message_type, reply_to_id, thread_root_id,
+			send_at, status, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		scheduled.ID, scheduled.ChatID, scheduled.SenderID, scheduled.Content, scheduled.MessageType,
+		scheduled.ReplyToID, scheduled.ThreadRootID, scheduled.SendAt, scheduled.Status, scheduled.CreatedAt, scheduled.UpdatedAt)
+	return dbs.TranslateError(err)
+}
+
+func (r *MessageRepo) GetScheduledMessage(ctx context.Context, scheduledID string) (*domain.ScheduledMessage, error) {
+	rows, err := r.conn(ctx).QueryContext(ctx, `
+		SELECT `+scheduledColumns+`
+		FROM scheduled_message s
+		WHERE s.id = $1`, scheduledID)
+	if err != nil {
+		return nil, dbs.TranslateError(err)
+	}
+	defer rows.Close()
+
+	scheduled := new(domain.ScheduledMessage)
+	for rows.Next() {
+		if scheduled, err = scanScheduledMessage(rows); err != nil {
+			return nil, err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if scheduled.ID == "" {
+		return nil, domain.ErrScheduledMessageNotFound
+	}
+	return scheduled, nil
+}
+
+// GetPendingScheduledMessages lists the sender's pending scheduled messages for the chat, soonest first.
+func (r *MessageRepo) GetPendingScheduledMessages(ctx context.Context, chatID, senderID string) ([]domain.ScheduledMessage, error) {
+	rows, err := r.conn(ctx).QueryContext(ctx, `
+		SELECT `+scheduledColumns+`
+		FROM scheduled_message s
+		WHERE s.chat_id = $1 AND s.sender_id = $2 AND s.status = 'pending'
+		ORDER BY s.send_at ASC`, chatID, senderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanScheduledMessages(rows)
+}
+
+// UpdateScheduledMessage saves an edit, reporting false when the message was claimed for delivery or cancelled meanwhile.
+func (r *MessageRepo) UpdateScheduledMessage(ctx context.Context, scheduled domain.ScheduledMessage) (bool, error) {
+	result, err := r.conn(ctx).ExecContext(ctx, `
+		UPDATE scheduled_message
+		SET content = $2, send_at = $3, updated_at = $4
+		WHERE id = $1 AND status = 'pending'`,
+		scheduled.ID, scheduled.Content, scheduled.SendAt, scheduled.UpdatedAt)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+func (r *MessageRepo) CancelScheduledMessage(ctx context.Context, scheduledID string) (bool, error) {
+	result, err := r.conn(ctx).ExecContext(ctx, `
+		UPDATE scheduled_message
+		SET status = 'cancelled', updated_at = $2
+		WHERE id = $1 AND status = 'pending'`, scheduledID, time.Now().UTC())
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// ClaimDueScheduledMessages marks due messages as sending and returns them. Messages left in sending since
+// before staleBefore, by an instance that stopped mid delivery, are claimed again.
+func (r *MessageRepo) ClaimDueScheduledMessages(ctx context.Context, now, staleBefore time.Time, limit int) ([]domain.ScheduledMessage, error) {
+	rows, err := r.conn(ctx).QueryContext(ctx, `
+		UPDATE scheduled_message s
+		SET status = 'sending', claimed_at = $1, updated_at = $1
+		WHERE s.id IN (
+			SELECT id FROM scheduled_message
+			WHERE (status = 'pending' AND send_at <= $1) OR (status = 'sending' AND claimed_at < $2)
+			ORDER BY send_at ASC
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+scheduledColumns, now, staleBefore, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanScheduledMessages(rows)
+}
+
+// CompleteScheduledMessage records the outcome of a delivery attempt.
+func (r *MessageRepo) CompleteScheduledMessage(ctx context.Context, scheduledID string, status domain.ScheduledStatus, failureReason *string) error {
+	_, err := r.conn(ctx).ExecContext(ctx, `
+		UPDATE scheduled_message
+		SET status = $2, failure_reason = $3, updated_at = $4
+		WHERE id = $1`, scheduledID, status, failureReason, time.Now().UTC())
+	return err
+}
+
+func scanScheduledMessages(rows *sql.Rows) ([]domain.ScheduledMessage, error) {
+	scheduled := []domain.ScheduledMessage{}
+	for rows.Next() {
+		item, err := scanScheduledMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		scheduled = append(scheduled, *item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return scheduled, nil
+}
+
+func scanScheduledMessage(rows *sql.Rows) (*domain.ScheduledMessage, error) {
+	scheduled := new(domain.ScheduledMessage)
+	err := rows.Scan(&scheduled.ID, &scheduled.ChatID, &scheduled.SenderID, &scheduled.Content, &scheduled.MessageType,
+		&scheduled.ReplyToID, &scheduled.ThreadRootID, &scheduled.SendAt, &scheduled.Status, &scheduled.FailureReason,
+		&scheduled.CreatedAt, &scheduled.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return scheduled, nil
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+
+	domain "github.com/HappYness-Project/ChatBackendServer/internal/message/domain"
+)
+
+// Matched terms are delimited with control characters, which message validation keeps out of the content,
+// so the snippet can be HTML escaped before the delimiters are turned into <mark> tags.
+const (
+	highlightStart = "\x01"
+	highlightStop  = "\x02"
+)
+
+var headlineOptions = fmt.Sprintf(`StartSel="%s", StopSel="%s", MaxFragments=2, MaxWords=20, MinWords=5`, highlightStart, highlightStop)
+
+// Search ranks the messages matching the query in chats whose history userID may read, as an active or muted
+// participant.
+// Archived chats are only searched when the query is limited to them.
+func (r *MessageRepo) Search(ctx context.Context, userID string, query domain.SearchQuery) ([]domain.SearchResult, error) {
+	rows, err := r.conn(ctx).QueryContext(ctx, `
+		SELECT `+messageColumns+`,
+			ts_rank(m.content_tsv, q.query) AS rank,
+			ts_headline('english', m.content, q.query, $10)
+		FROM message m
+		CROSS JOIN websearch_to_tsquery('english', $2) AS q(query)
+		INNER JOIN chat_participant cp ON cp.chat_id = m.chat_id AND cp.user_id = $1 AND cp.status IN ('active', 'muted')
+		INNER JOIN chat c ON c.id = m.chat_id AND c.deleted_at IS NULL
+		WHERE m.content_tsv @@ q.query AND `+notExpired+`
+			AND (c.archived_at IS NULL OR m.chat_id = $3)
+			AND ($3::uuid IS NULL OR m.chat_id = $3)
+			AND ($4::uuid IS NULL OR m.sender_id = $4)
+			AND ($5::varchar IS NULL OR m.message_type = $5)
+			AND ($6::timestamptz IS NULL OR m.created_at >= $6)
+			AND ($7::timestamptz IS NULL OR m.created_at <= $7)
+		ORDER BY rank DESC, m.created_at DESC
+		LIMIT $8 OFFSET $9`,
+		userID, query.Text, nullableString(query.ChatID), nullableString(query.SenderID), nullableString(query.MessageType),
+		query.From, query.To, query.Limit, query.Offset, headlineOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []domain.SearchResult{}
+	for rows.Next() {
+		var result domain.SearchResult
+		targets := append(messageScanTargets(&result.Message), &result.Rank, &result.Snippet)
+		if err := rows.Scan(targets...); err != nil {
+			return nil, err
+		}
+		result.Snippet = highlightSnippet(result.Snippet)
+		results = append(results, result)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func highlightSnippet(snippet string) string {
+	escaped := html.EscapeString(snippet)
+	escaped = strings.ReplaceAll(escaped, highlightStart, "<mark>")
+	return strings.ReplaceAll(escaped, highlightStop, "</mark>")
+}
+
+func nullableString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
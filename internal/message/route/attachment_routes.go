@@ -0,0 +1,281 @@
+package route
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/HappYness-Project/ChatBackendServer/common"
+	domain "github.com/HappYness-Project/ChatBackendServer/internal/message/domain"
+	"github.com/HappYness-Project/ChatBackendServer/internal/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+const attachmentURLExpiry = 5 * time.Minute
+
+var errAttachmentTooLarge = errors.New("attachment exceeds the maximum size")
+
+// UploadAttachment stores a multipart "file" upload. The returned attachment id is sent with
+// the next message to link the file to it.
+func (h *Handler) UploadAttachment(w http.ResponseWriter, r *http.Request) {
+	chatID := chi.URLParam(r, "chatID")
+	userId, ok := h.requireActiveParticipant(w, r, chatID)
+	if !ok {
+		return
+	}
+	if !h.requireWritableChat(r.Context(), w, chatID) {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, domain.MaxAttachmentSize+(1<<20))
+	fileName, declaredType, data, err := readUploadedFile(r)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) || errors.Is(err, errAttachmentTooLarge) {
+			common.ErrorResponse(w, http.StatusRequestEntityTooLarge, common.ProblemDetails{
+				Title:     "Payload Too Large",
+				ErrorCode: "AttachmentTooLarge",
+				Detail:    fmt.Sprintf("Attachments are limited to %d bytes", domain.MaxAttachmentSize),
+			})
+			return
+		}
+		common.ErrorResponse(w, http.StatusBadRequest, common.ProblemDetails{
+			Title:     "Invalid Request",
+			ErrorCode: "InvalidUpload",
+			Detail:    err.Error(),
+		})
+		return
+	}
+
+	checksum := sha256.Sum256(data)
+	attachment, err := domain.NewAttachment(chatID, userId, fileName, detectContentType(data, declaredType),
+		int64(len(data)), hex.EncodeToString(checksum[:]))
+	if err != nil {
+		common.ErrorResponse(w, http.StatusBadRequest, common.ProblemDetails{
+			Title:     "Invalid Request",
+			ErrorCode: "InvalidUpload",
+			Detail:    err.Error(),
+		})
+		return
+	}
+	if attachment.Kind() == domain.TypeImage {
+		if config, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+			attachment.Width, attachment.Height = &config.Width, &config.Height
+		}
+	}
+
+	if err := h.blobStore.Put(r.Context(), attachment.StorageKey, bytes.NewReader(data), attachment.SizeBytes, attachment.ContentType); err != nil {
+		h.writeError(w, err, "Failed to store attachment")
+		return
+	}
+	if err := h.messageRepo.CreateAttachment(r.Context(), *attachment); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to create attachment")
+		if err := h.blobStore.Delete(r.Context(), attachment.StorageKey); err != nil {
+			h.logger.Error().Err(err).Msg("Failed to delete orphaned attachment blob")
+		}
+		common.ErrorResponse(w, http.StatusInternalServerError, common.ProblemDetails{
+			Title:  "Internal Server Error",
+			Detail: "Error occurred while creating attachment",
+		})
+		return
+	}
+
+	withDownloadURL(attachment)
+	common.WriteJsonWithEncode(w, http.StatusCreated, attachment)
+}
+
+func (h *Handler) GetAttachment(w http.ResponseWriter, r *http.Request) {
+	attachment, ok := h.findReadableAttachment(w, r)
+	if !ok {
+		return
+	}
+	common.WriteJsonWithEncode(w, http.StatusOK, attachment)
+}
+
+// DownloadAttachment redirects to a short lived signed URL when the blob store supports it,
+// otherwise the content is streamed through the server.
+func (h *Handler) DownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	attachment, ok := h.findReadableAttachment(w, r)
+	if !ok {
+		return
+	}
+
+	if signer, ok := h.blobStore.(storage.URLSigner); ok {
+		signedURL, err := signer.SignedURL(r.Context(), attachment.StorageKey, attachmentURLExpiry)
+		if err == nil {
+			http.Redirect(w, r, signedURL, http.StatusFound)
+			return
+		}
+		h.logger.Error().Err(err).Msg("Failed to sign attachment URL, streaming instead")
+	}
+
+	body, err := h.blobStore.Get(r.Context(), attachment.StorageKey)
+	if errors.Is(err, storage.ErrBlobNotFound) {
+		common.ErrorResponse(w, http.StatusNotFound, common.ProblemDetails{
+			Title:     "Not Found",
+			ErrorCode: "AttachmentNotFound",
+			Detail:    "Attachment content is no longer available",
+		})
+		return
+	}
+	if err != nil {
+		h.writeError(w, err, "Failed to read attachment")
+		return
+	}
+	defer body.Close()
+
+	disposition := "attachment"
+	if kind := attachment.Kind(); kind == domain.TypeImage || kind == domain.TypeVideo || kind == domain.TypeAudio {
+		disposition = "inline"
+	}
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(attachment.SizeBytes, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": attachment.FileName}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, body); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to stream attachment")
+	}
+}
+
+// findReadableAttachment applies the chat history rules to an attachment. Uploads that are not linked to
+// a message yet are only visible to their uploader.
+func (h *Handler) findReadableAttachment(w http.ResponseWriter, r *http.Request) (*domain.Attachment, bool) {
+	chatID := chi.URLParam(r, "chatID")
+	userId, until, ok := h.authorizeRead(w, r, chatID)
+	if !ok {
+		return nil, false
+	}
+
+	attachment, err := h.messageRepo.GetAttachmentByID(r.Context(), chi.URLParam(r, "attachmentID"))
+	if err == nil && (attachment.ChatID != chatID ||
+		(!attachment.IsLinked() && attachment.UploaderID != userId) ||
+		(until != nil && attachment.CreatedAt.After(*until))) {
+		err = domain.ErrAttachmentNotFound
+	}
+	if err != nil {
+		h.writeError(w, err, "Failed to retrieve attachment")
+		return nil, false
+	}
+
+	withDownloadURL(attachment)
+	return attachment, true
+}
+
+// validateAttachments resolves the attachment ids a client sent with a message.
+func (h *Handler) validateAttachments(ctx context.Context, msg *domain.Message) (string, error) {
+	if len(msg.AttachmentIDs) > domain.MaxAttachmentsPerMessage {
+		return "TooManyAttachments", fmt.Errorf("a message can carry at most %d attachments", domain.MaxAttachmentsPerMessage)
+	}
+
+	seen := make(map[string]bool, len(msg.AttachmentIDs))
+	attachments := make([]domain.Attachment, 0, len(msg.AttachmentIDs))
+	for _, id := range msg.AttachmentIDs {
+		if seen[id] {
+			return "InvalidAttachment", errors.New("attachment_ids contains duplicates")
+		}
+		seen[id] = true
+
+		attachment, err := h.messageRepo.GetAttachmentByID(ctx, id)
+		// Unknown ids and ids that are not valid UUIDs are the client's mistake, not a failed lookup.
+		if errors.Is(err, domain.ErrAttachmentNotFound) || errors.Is(err, common.ErrValidation) {
+			return "InvalidAttachment", errors.New("attachment does not exist in this chat")
+		}
+		if err != nil {
+			h.logger.Error().Err(err).Msg("Failed to retrieve attachment")
+			return "AttachmentLookupFailed", errors.New("unable to verify the attachments")
+		}
+		if err := attachment.ValidateForMessage(msg.ChatID, msg.SenderID); err != nil {
+			return "InvalidAttachment", err
+		}
+		withDownloadURL(attachment)
+		attachments = append(attachments, *attachment)
+	}
+
+	msg.Attachments = attachments
+	return "", nil
+}
+
+// attachAttachments fills in the attachments of each message. Failures are logged and leave the messages without them.
+func (h *Handler) attachAttachments(ctx context.Context, messages []domain.Message) {
+	if len(messages) == 0 {
+		return
+	}
+	ids := make([]string, len(messages))
+	for i := range messages {
+		ids[i] = messages[i].ID
+	}
+	attachments, err := h.messageRepo.GetAttachmentsByMessageIDs(ctx, ids)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Failed to retrieve message attachments")
+		return
+	}
+	for i := range messages {
+		messages[i].Attachments = attachments[messages[i].ID]
+		for j := range messages[i].Attachments {
+			withDownloadURL(&messages[i].Attachments[j])
+		}
+	}
+}
+
+// readUploadedFile reads the "file" part of a multipart request into memory, bounded by the attachment size limit.
+func readUploadedFile(r *http.Request) (string, string, []byte, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return "", "", nil, errors.New("request must be multipart/form-data with a file field")
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return "", "", nil, errors.New("request does not contain a file field")
+		}
+		if err != nil {
+			return "", "", nil, err
+		}
+		if part.FormName() != "file" {
+			part.Close()
+			continue
+		}
+
+		data, err := io.ReadAll(io.LimitReader(part, domain.MaxAttachmentSize+1))
+		part.Close()
+		if err != nil {
+			return "", "", nil, err
+		}
+		if len(data) > domain.MaxAttachmentSize {
+			return "", "", nil, errAttachmentTooLarge
+		}
+		return part.FileName(), part.Header.Get("Content-Type"), data, nil
+	}
+}
+
+// detectContentType trusts the sniffed type and only falls back to the declared one when sniffing is inconclusive.
+// Declared markup types are ignored so uploads can never be served as active content.
+func detectContentType(data []byte, declared string) string {
+	sniffed := http.DetectContentType(data)
+	if sniffed != "application/octet-stream" || declared == "" {
+		return sniffed
+	}
+	mediaType, _, err := mime.ParseMediaType(declared)
+	if err != nil || mediaType == "text/html" || mediaType == "image/svg+xml" || mediaType == "application/xhtml+xml" {
+		return sniffed
+	}
+	return mediaType
+}
+
+func withDownloadURL(attachment *domain.Attachment) {
+	attachment.DownloadURL = fmt.Sprintf("/api/chats/%s/attachments/%s/content", attachment.ChatID, attachment.ID)
+}
@@ -0,0 +1,87 @@
+package route
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/HappYness-Project/ChatBackendServer/internal/linkpreview"
+	domain "github.com/HappYness-Project/ChatBackendServer/internal/message/domain"
+)
+
+const (
+	EventMessageUpdated = "message.updated"
+
+	linkPreviewFetchTimeout = 5 * time.Second
+	// Limits the pages fetched at the same time across all messages.
+	maxConcurrentLinkFetches = 8
+)
+
+// unfurlLinks attaches previews of the URLs in a delivered message and pushes the updated message to the chat.
+// It runs in the background, messages are never held back waiting for previews.
+func (h *Handler) unfurlLinks(ctx context.Context, msg domain.Message) {
+	if h.linkFetcher == nil {
+		return
+	}
+	urls := domain.ExtractURLs(msg.Content)
+	if len(urls) == 0 {
+		return
+	}
+
+	previews := domain.LinkPreviews{}
+	for _, url := range urls {
+		if preview := h.linkPreview(ctx, url); preview != nil {
+			previews = append(previews, *preview)
+		}
+	}
+	if len(previews) == 0 {
+		return
+	}
+
+	if err := h.messageRepo.SetLinkPreviews(ctx, msg.ID, previews); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to store link previews")
+		return
+	}
+	msg.LinkPreviews = previews
+	h.wsManager.PublishToChat(msg.ChatID, EventMessageUpdated, msg)
+}
+
+// linkPreview returns the preview of the URL from the cache or by fetching it, nil when there is nothing to show.
+func (h *Handler) linkPreview(ctx context.Context, url string) *domain.LinkPreview {
+	now := time.Now().UTC()
+	cached, err := h.messageRepo.GetLinkPreviewCache(ctx, url)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Failed to read link preview cache")
+		return nil
+	}
+	if cached.URL != "" && cached.IsFresh(now) {
+		return cached.Preview
+	}
+
+	h.linkFetchSlots <- struct{}{}
+	fetchCtx, cancel := context.WithTimeout(ctx, linkPreviewFetchTimeout)
+	metadata, err := h.linkFetcher.Fetch(fetchCtx, url)
+	cancel()
+	<-h.linkFetchSlots
+
+	entry := domain.LinkPreviewCacheEntry{URL: url, FetchedAt: now}
+	switch {
+	case err == nil:
+		entry.Preview = &domain.LinkPreview{
+			URL:         url,
+			Title:       metadata.Title,
+			Description: metadata.Description,
+			ImageURL:    metadata.ImageURL,
+			SiteName:    metadata.SiteName,
+		}
+	case errors.Is(err, linkpreview.ErrNoMetadata), errors.Is(err, linkpreview.ErrUnsafeURL):
+		// Expected for many links, not worth logging. Like other failures it is cached as a miss.
+	default:
+		h.logger.Info().Err(err).Str("url", url).Msg("Failed to fetch link preview")
+	}
+
+	if err := h.messageRepo.SaveLinkPreviewCache(ctx, entry); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to cache link preview")
+	}
+	return entry.Preview
+}
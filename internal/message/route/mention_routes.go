@@ -0,0 +1,111 @@
+package route
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/HappYness-Project/ChatBackendServer/common"
+	chatDomain "github.com/HappYness-Project/ChatBackendServer/internal/chat/domain"
+	domain "github.com/HappYness-Project/ChatBackendServer/internal/message/domain"
+	"github.com/gorilla/websocket"
+)
+
+const EventMentionCreated = "mention.created"
+
+// HandleUserConnection opens a socket that is not joined to any chat. It only receives events addressed
+// to the user, such as mentions, so clients are notified without holding a socket for every chat.
+func (h *Handler) HandleUserConnection(w http.ResponseWriter, r *http.Request) {
+	userId, ok := h.authenticateRequest(w, r)
+	if !ok {
+		common.ErrorResponse(w, http.StatusUnauthorized, common.ProblemDetails{
+			Title:     "Unauthorized",
+			ErrorCode: "AuthenticationFailure",
+			Detail:    "Invalid authentication token",
+		})
+		return
+	}
+
+	conn, err := h.wsManager.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		h.logger.Error().Err(err).Msg(err.Error())
+		return
+	}
+	defer h.wsManager.RemoveClient(conn)
+	h.wsManager.AddClient(conn, "", userId)
+
+	for {
+		if _, _, err := conn.NextReader(); err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				h.logger.Error().Err(err).Msg("User websocket closed")
+			}
+			return
+		}
+	}
+}
+
+func (h *Handler) GetMyMentions(w http.ResponseWriter, r *http.Request) {
+	userId, ok := h.authenticateRequest(w, r)
+	if !ok {
+		common.ErrorResponse(w, http.StatusUnauthorized, common.ProblemDetails{
+			Title:     "Unauthorized",
+			ErrorCode: "AuthenticationFailure",
+			Detail:    "Invalid authentication token",
+		})
+		return
+	}
+
+	limit := 50
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 {
+		limit = l
+	}
+	offset := 0
+	if o, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && o >= 0 {
+		offset = o
+	}
+
+	mentions, err := h.messageRepo.GetMentionsForUser(r.Context(), userId, limit, offset)
+	if err != nil {
+		h.writeError(w, err, "Failed to retrieve mentions")
+		return
+	}
+
+	common.WriteJsonWithEncode(w, http.StatusOK, map[string]interface{}{
+		"mentions": mentions,
+		"count":    len(mentions),
+	})
+}
+
+// resolveMentions records the valid mentions of a message and who they notify. Participants that left or
+// were banned cannot be mentioned.
+func (h *Handler) resolveMentions(ctx context.Context, msg *domain.Message, chat *chatDomain.Chat) error {
+	msg.Mentions = nil
+	msg.MentionRecipients = nil
+	if !strings.Contains(msg.Content, "@") {
+		return nil
+	}
+
+	participants, err := h.chatRepo.GetChatParticipants(ctx, chat.Id)
+	if err != nil {
+		return err
+	}
+	candidates := make([]domain.MentionCandidate, 0, len(participants))
+	for i := range participants {
+		if participants[i].CanReadMessages() {
+			candidates = append(candidates, domain.MentionCandidate{UserID: participants[i].UserId, IsAdmin: participants[i].IsAdmin()})
+		}
+	}
+	msg.MentionRecipients = msg.ResolveMentions(candidates, chat.Type != chatDomain.ChatTypePrivate)
+	return nil
+}
+
+func (h *Handler) publishMentions(msg domain.Message) {
+	for _, recipient := range msg.MentionRecipients {
+		h.wsManager.PublishToUser(recipient.UserID, msg.ChatID, EventMentionCreated, domain.MentionNotification{
+			Message:     msg,
+			MentionType: recipient.Type,
+			MentionedAt: msg.CreatedAt,
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package route
+
+import (
+	"net/http"
+
+	"github.com/HappYness-Project/ChatBackendServer/common"
+	domain "github.com/HappYness-Project/ChatBackendServer/internal/message/domain"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	EventMessagePinned   = "message.pinned"
+	EventMessageUnpinned = "message.unpinned"
+)
+
+func (h *Handler) PinMessage(w http.ResponseWriter, r *http.Request) {
+	chatID := chi.URLParam(r, "chatID")
+	userId, ok := h.requireChatAdmin(w, r, chatID)
+	if !ok {
+		return
+	}
+	if !h.requireWritableChat(r.Context(), w, chatID) {
+		return
+	}
+	message, ok := h.findChatMessage(r.Context(), w, chatID, chi.URLParam(r, "messageID"))
+	if !ok {
+		return
+	}
+
+	pin, err := domain.NewPin(message, userId)
+	if err != nil {
+		common.ErrorResponse(w, http.StatusBadRequest, common.ProblemDetails{
+			Title:     "Invalid Request",
+			ErrorCode: "InvalidPin",
+			Detail:    err.Error(),
+		})
+		return
+	}
+
+	pinned, err := h.messageRepo.PinMessage(r.Context(), pin)
+	if err != nil {
+		h.writeError(w, err, "Failed to pin message")
+		return
+	}
+	if !pinned {
+		common.ErrorResponse(w, http.StatusConflict, common.ProblemDetails{
+			Title:     "Conflict",
+			ErrorCode: "AlreadyPinned",
+			Detail:    "The message is already pinned",
+		})
+		return
+	}
+
+	pin.Message = message
+	h.wsManager.PublishToChat(chatID, EventMessagePinned, pin)
+	common.WriteJsonWithEncode(w, http.StatusCreated, pin)
+}
+
+func (h *Handler) UnpinMessage(w http.ResponseWriter, r *http.Request) {
+	chatID := chi.URLParam(r, "chatID")
+	userId, ok := h.requireChatAdmin(w, r, chatID)
+	if !ok {
+		return
+	}
+	if !h.requireWritableChat(r.Context(), w, chatID) {
+		return
+	}
+	messageID := chi.URLParam(r, "messageID")
+
+	unpinned, err := h.messageRepo.UnpinMessage(r.Context(), chatID, messageID)
+	if err != nil {
+		h.writeError(w, err, "Failed to unpin message")
+		return
+	}
+	if !unpinned {
+		common.ErrorResponse(w, http.StatusNotFound, common.ProblemDetails{
+			Title:     "Not Found",
+			ErrorCode: "PinNotFound",
+			Detail:    "The message is not pinned in this chat",
+		})
+		return
+	}
+
+	h.wsManager.PublishToChat(chatID, EventMessageUnpinned, map[string]string{
+		"message_id":  messageID,
+		"unpinned_by": userId,
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetPins lists the pinned messages. Participants that left only see pins of messages posted before they left.
+func (h *Handler) GetPins(w http.ResponseWriter, r *http.Request) {
+	chatID := chi.URLParam(r, "chatID")
+	_, until, ok := h.authorizeRead(w, r, chatID)
+	if !ok {
+		return
+	}
+
+	pins, err := h.messageRepo.GetPins(r.Context(), chatID)
+	if err != nil {
+		h.writeError(w, err, "Failed to retrieve pins")
+		return
+	}
+	if until != nil {
+		visible := pins[:0]
+		for _, pin := range pins {
+			if !pin.Message.CreatedAt.After(*until) {
+				visible = append(visible, pin)
+			}
+		}
+		pins = visible
+	}
+
+	common.WriteJsonWithEncode(w, http.StatusOK, map[string]interface{}{
+		"pins":  pins,
+		"count": len(pins),
+	})
+}
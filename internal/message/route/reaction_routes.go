@@ -0,0 +1,121 @@
+package route
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/HappYness-Project/ChatBackendServer/common"
+	domain "github.com/HappYness-Project/ChatBackendServer/internal/message/domain"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	EventReactionAdded   = "reaction.added"
+	EventReactionRemoved = "reaction.removed"
+)
+
+type AddReactionRequest struct {
+	Emoji string `json:"emoji"`
+}
+
+func (h *Handler) AddReaction(w http.ResponseWriter, r *http.Request) {
+	chatID := chi.URLParam(r, "chatID")
+	userId, ok := h.requireActiveParticipant(w, r, chatID)
+	if !ok {
+		return
+	}
+	if !h.requireWritableChat(r.Context(), w, chatID) {
+		return
+	}
+	message, ok := h.findChatMessage(r.Context(), w, chatID, chi.URLParam(r, "messageID"))
+	if !ok {
+		return
+	}
+
+	var request AddReactionRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		common.ErrorResponse(w, http.StatusBadRequest, common.ProblemDetails{
+			Title:     "Invalid Request Body",
+			ErrorCode: "InvalidJSON",
+			Detail:    "Unable to decode request body as JSON",
+		})
+		return
+	}
+
+	reaction, err := domain.NewReaction(message.ID, userId, request.Emoji)
+	if err != nil {
+		common.ErrorResponse(w, http.StatusBadRequest, common.ProblemDetails{
+			Title:     "Invalid Request",
+			ErrorCode: "InvalidReaction",
+			Detail:    err.Error(),
+		})
+		return
+	}
+
+	added, err := h.messageRepo.AddReaction(r.Context(), *reaction)
+	if err != nil {
+		h.writeError(w, err, "Failed to add reaction")
+		return
+	}
+
+	status := http.StatusOK
+	if added {
+		status = http.StatusCreated
+		h.publishReaction(r.Context(), chatID, EventReactionAdded, reaction.MessageID, userId, reaction.Emoji)
+	}
+	common.WriteJsonWithEncode(w, status, reaction)
+}
+
+func (h *Handler) RemoveReaction(w http.ResponseWriter, r *http.Request) {
+	chatID := chi.URLParam(r, "chatID")
+	userId, ok := h.requireActiveParticipant(w, r, chatID)
+	if !ok {
+		return
+	}
+	if !h.requireWritableChat(r.Context(), w, chatID) {
+		return
+	}
+	message, ok := h.findChatMessage(r.Context(), w, chatID, chi.URLParam(r, "messageID"))
+	if !ok {
+		return
+	}
+	emoji := chi.URLParam(r, "emoji")
+
+	removed, err := h.messageRepo.RemoveReaction(r.Context(), message.ID, userId, emoji)
+	if err != nil {
+		h.writeError(w, err, "Failed to remove reaction")
+		return
+	}
+
+	if removed {
+		h.publishReaction(r.Context(), chatID, EventReactionRemoved, message.ID, userId, emoji)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) publishReaction(ctx context.Context, chatID, event, messageID, userId, emoji string) {
+	count, err := h.messageRepo.CountReactions(ctx, messageID, emoji)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Failed to count reactions")
+		return
+	}
+	h.wsManager.PublishToChat(chatID, event, domain.ReactionEvent{
+		MessageID: messageID,
+		UserID:    userId,
+		Emoji:     emoji,
+		Count:     count,
+	})
+}
+
+func (h *Handler) findChatMessage(ctx context.Context, w http.ResponseWriter, chatID, messageID string) (*domain.Message, bool) {
+	message, err := h.messageRepo.GetByID(ctx, messageID)
+	if err == nil && message.ChatID != chatID {
+		err = domain.ErrMessageNotFound
+	}
+	if err != nil {
+		h.writeError(w, err, "Failed to retrieve message")
+		return nil, false
+	}
+	return message, true
+}
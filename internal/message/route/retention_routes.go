@@ -0,0 +1,103 @@
+package route
+
+import (
+	"errors"
+	"time"
+
+	chatDomain "github.com/HappYness-Project/ChatBackendServer/internal/chat/domain"
+	"github.com/HappYness-Project/ChatBackendServer/internal/storage"
+)
+
+const (
+	EventMessagesExpired = "messages.expired"
+
+	retentionPurgeInterval = 30 * time.Second
+	retentionPurgeBatch    = 500
+	// Caps the work done per tick so a large backlog is worked off over several ticks.
+	retentionMaxBatchesPerRun = 20
+	// Each purged chat takes its whole history with it, so chats are purged in small batches.
+	deletedChatPurgeBatch = 20
+)
+
+// RunRetentionPurge periodically deletes messages that outlived their TTL or the retention period of their chat,
+// and chats that were deleted longer than the restore window ago.
+func (h *Handler) RunRetentionPurge(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-h.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		now := time.Now().UTC()
+		h.purgeExpiredMessages(now)
+		h.purgeDeletedChats(now)
+	}
+}
+
+// purgeExpiredMessages deletes expired messages batch by batch and tells the connected clients of each chat
+// which messages are gone.
+func (h *Handler) purgeExpiredMessages(now time.Time) {
+	for i := 0; i < retentionMaxBatchesPerRun; i++ {
+		ctx, cancel := h.operationContext()
+		result, err := h.messageRepo.PurgeExpiredMessages(ctx, now, retentionPurgeBatch)
+		cancel()
+		if err != nil {
+			h.logger.Error().Err(err).Msg("Failed to purge expired messages")
+			return
+		}
+
+		expiredByChat := make(map[string][]string)
+		for _, expired := range result.Messages {
+			expiredByChat[expired.ChatID] = append(expiredByChat[expired.ChatID], expired.ID)
+		}
+		for chatID, messageIDs := range expiredByChat {
+			h.wsManager.PublishToChat(chatID, EventMessagesExpired, map[string]interface{}{
+				"message_ids": messageIDs,
+			})
+		}
+		h.deleteBlobs(result.StorageKeys)
+
+		if len(result.Messages) > 0 {
+			h.logger.Info().Int("count", len(result.Messages)).Msg("Purged expired messages")
+		}
+		// Replies removed with their root are counted as well, so this may run one batch more than needed.
+		if len(result.Messages) < retentionPurgeBatch {
+			return
+		}
+	}
+}
+
+// purgeDeletedChats hard deletes the chats whose restore window ended, batch by batch.
+func (h *Handler) purgeDeletedChats(now time.Time) {
+	for i := 0; i < retentionMaxBatchesPerRun; i++ {
+		ctx, cancel := h.operationContext()
+		result, err := h.chatRepo.PurgeDeletedChats(ctx, now.Add(-chatDomain.ChatRestoreWindow), deletedChatPurgeBatch)
+		cancel()
+		if err != nil {
+			h.logger.Error().Err(err).Msg("Failed to purge deleted chats")
+			return
+		}
+		h.deleteBlobs(result.StorageKeys)
+
+		if len(result.ChatIDs) > 0 {
+			h.logger.Info().Int("count", len(result.ChatIDs)).Msg("Purged deleted chats")
+		}
+		if len(result.ChatIDs) < deletedChatPurgeBatch {
+			return
+		}
+	}
+}
+
+// deleteBlobs removes the stored files of purged attachments. Blobs that are already gone are not an error.
+func (h *Handler) deleteBlobs(keys []string) {
+	for _, key := range keys {
+		ctx, cancel := h.operationContext()
+		err := h.blobStore.Delete(ctx, key)
+		cancel()
+		if err != nil && !errors.Is(err, storage.ErrBlobNotFound) {
+			h.logger.Error().Err(err).Str("storage_key", key).Msg("Failed to delete attachment blob")
+		}
+	}
+}
@@ -1,52 +1,108 @@
 package route
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/HappYness-Project/ChatBackendServer/common"
+	"github.com/HappYness-Project/ChatBackendServer/dbs"
 	domain "github.com/HappYness-Project/ChatBackendServer/internal/message/domain"
 	"github.com/HappYness-Project/ChatBackendServer/loggers"
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 
+	chatDomain "github.com/HappYness-Project/ChatBackendServer/internal/chat/domain"
 	chatRepo "github.com/HappYness-Project/ChatBackendServer/internal/chat/repository"
+	"github.com/HappYness-Project/ChatBackendServer/internal/linkpreview"
 	msgRepo "github.com/HappYness-Project/ChatBackendServer/internal/message/repository"
+	"github.com/HappYness-Project/ChatBackendServer/internal/storage"
 	"github.com/go-chi/chi/v5"
 )
 
+const (
+	EventMessageRejected = "message.rejected"
+	EventThreadUpdated   = "thread.updated"
+)
+
 type Handler struct {
 	logger      *loggers.AppLogger
-	messageRepo msgRepo.MessageRepo
-	chatRepo    chatRepo.ChatRepo
+	messageRepo msgRepo.MessageRepository
+	chatRepo    chatRepo.ChatRepository
+	unitOfWork  dbs.UnitOfWork
 	wsManager   *WebSocketManager
+	blobStore   storage.BlobStore
+	linkFetcher linkpreview.Fetcher
 	jwtSecret   []byte
+
+	// ctx lives as long as the server. Websocket connections and background jobs outlive the request that
+	// started them, so their queries derive from it and are cancelled on shutdown.
+	ctx     context.Context
+	timeout time.Duration
+
+	linkFetchSlots chan struct{}
+	// broadcasting is set while HandleMessages stores and sends the messages clients post.
+	broadcasting atomic.Bool
 }
 
-func NewHandler(logger *loggers.AppLogger, repo msgRepo.MessageRepo, chatRepo chatRepo.ChatRepo, secretKey string) *Handler {
-	wsManager := NewWebSocketManager(logger)
+// NewHandler starts the message delivery loop and the background jobs, which stop when ctx is cancelled.
+// Every query they run is bounded by timeout, like the queries of a request.
+func NewHandler(ctx context.Context, logger *loggers.AppLogger, repo msgRepo.MessageRepository, chatRepo chatRepo.ChatRepository,
+	unitOfWork dbs.UnitOfWork, wsManager *WebSocketManager, blobStore storage.BlobStore, linkFetcher linkpreview.Fetcher, secretKey string,
+	timeout time.Duration) *Handler {
 	handler := &Handler{
-		logger:      logger,
-		messageRepo: repo,
-		chatRepo:    chatRepo,
-		wsManager:   wsManager,
-		jwtSecret:   []byte(secretKey),
+		ctx:            ctx,
+		timeout:        timeout,
+		logger:         logger,
+		messageRepo:    repo,
+		chatRepo:       chatRepo,
+		unitOfWork:     unitOfWork,
+		wsManager:      wsManager,
+		blobStore:      blobStore,
+		linkFetcher:    linkFetcher,
+		jwtSecret:      []byte(secretKey),
+		linkFetchSlots: make(chan struct{}, maxConcurrentLinkFetches),
 	}
 	go handler.HandleMessages()
+	go handler.RunScheduledMessages(scheduledMessageInterval)
+	go handler.RunRetentionPurge(retentionPurgeInterval)
 	return handler
 }
 
 func (h *Handler) RegisterRoutes(router chi.Router) {
 	router.Get("/api/chats/{chatID}/ws", h.HandleConnectionsByChatID)
 	router.Get("/api/chats/{chatID}/messages", h.GetMessagesByChatID)
+	router.Get("/api/chats/{chatID}/messages/{messageID}/thread", h.GetThreadMessages)
+	router.Post("/api/chats/{chatID}/messages/{messageID}/reactions", h.AddReaction)
+	router.Delete("/api/chats/{chatID}/messages/{messageID}/reactions/{emoji}", h.RemoveReaction)
+	router.Get("/api/chats/{chatID}/pins", h.GetPins)
+	router.Post("/api/chats/{chatID}/messages/{messageID}/pin", h.PinMessage)
+	router.Delete("/api/chats/{chatID}/messages/{messageID}/pin", h.UnpinMessage)
+	router.Get("/api/chats/{chatID}/scheduled-messages", h.GetScheduledMessages)
+	router.Post("/api/chats/{chatID}/scheduled-messages", h.CreateScheduledMessage)
+	router.Patch("/api/chats/{chatID}/scheduled-messages/{scheduledID}", h.UpdateScheduledMessage)
+	router.Delete("/api/chats/{chatID}/scheduled-messages/{scheduledID}", h.CancelScheduledMessage)
+	router.Get("/api/chats/{chatID}/attachments/{attachmentID}", h.GetAttachment)
 	router.Get("/api/user-groups/{groupID}/messages", h.GetMessagesByGroupID)
+	router.Get("/api/search/messages", h.SearchMessages)
+	router.Get("/api/me/ws", h.HandleUserConnection)
+	router.Get("/api/me/mentions", h.GetMyMentions)
+}
+
+// RegisterStreamingRoutes registers the routes that stream attachment content. Their transfers can take longer
+// than the request timeout, so they are registered outside of it.
+func (h *Handler) RegisterStreamingRoutes(router chi.Router) {
+	router.Post("/api/chats/{chatID}/attachments", h.UploadAttachment)
+	router.Get("/api/chats/{chatID}/attachments/{attachmentID}/content", h.DownloadAttachment)
 }
 
 func (h *Handler) HandleConnectionsByChatID(w http.ResponseWriter, r *http.Request) {
-	if !h.authenticateRequest(w, r) {
+	userId, ok := h.authenticateRequest(w, r)
+	if !ok {
 		common.ErrorResponse(w, http.StatusUnauthorized, common.ProblemDetails{
 			Title:     "Unauthorized",
 			ErrorCode: "AuthenticationFailure",
@@ -62,20 +118,30 @@ func (h *Handler) HandleConnectionsByChatID(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	conn, err := h.wsManager.upgrader.Upgrade(w, r, nil)
+	chat, err := h.chatRepo.GetChatById(r.Context(), chatID)
 	if err != nil {
-		h.logger.Error().Err(err).Msg(err.Error())
+		h.writeError(w, err, "Failed to retrieve chat")
+		return
+	}
+	participant, err := h.chatRepo.GetChatParticipant(r.Context(), chat.Id, userId)
+	if errors.Is(err, chatDomain.ErrParticipantNotFound) || err == nil && !participant.CanReadMessages() {
+		err = chatDomain.ErrNotChatParticipant
+	}
+	if err != nil {
+		h.writeError(w, err, "Failed to retrieve chat participant")
 		return
 	}
-	defer h.wsManager.RemoveClient(conn)
 
-	h.wsManager.AddClient(conn)
-	chat, err := h.chatRepo.GetChatById(chatID)
+	conn, err := h.wsManager.upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		h.logger.Error().Err(err).Msg("Error occurred during getting chat by user group. " + err.Error())
+		h.logger.Error().Err(err).Msg(err.Error())
 		return
 	}
+	defer h.wsManager.RemoveClient(conn)
+
+	client := h.wsManager.AddClient(conn, chat.Id, userId)
 
+	var lastSentAt time.Time
 	for {
 		var msg domain.Message
 		err := conn.ReadJSON(&msg)
@@ -92,28 +158,246 @@ func (h *Handler) HandleConnectionsByChatID(w http.ResponseWriter, r *http.Reque
 				return
 			}
 		}
+
 		msg.ChatID = chat.Id
+		msg.SenderID = userId
 		msg.CreatedAt = time.Now().UTC()
-		msg.MessageType = "text"
+		// The upgraded connection outlives the request, each message is handled under the server context.
+		ctx, cancel := h.operationContext()
+		errorCode, err := h.admitMessage(ctx, &msg, participant, lastSentAt)
+		cancel()
+		if errorCode == "ChatNotFound" {
+			h.rejectMessage(client, errorCode, err.Error())
+			return
+		}
+		if err != nil {
+			h.rejectMessage(client, errorCode, err.Error())
+			continue
+		}
 
+		lastSentAt = msg.CreatedAt
 		h.wsManager.BroadcastMessage(msg)
 	}
 }
 
+// admitMessage checks a message received on a websocket against the current chat and prepares it for delivery.
+// Settings may change while the socket is open, so they are not taken from the chat the connection was opened for.
+func (h *Handler) admitMessage(ctx context.Context, msg *domain.Message, participant *chatDomain.ChatParticipant,
+	lastSentAt time.Time) (string, error) {
+	current, err := h.chatRepo.GetChatById(ctx, msg.ChatID)
+	if errors.Is(err, chatDomain.ErrChatNotFound) {
+		return "ChatNotFound", errors.New("the chat was deleted")
+	}
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Failed to retrieve chat settings")
+		return "ChatLookupFailed", errors.New("unable to retrieve the chat")
+	}
+	if !current.CanPost(participant) {
+		return "PostPermissionRequired", errors.New("the chat settings do not allow this user to post")
+	}
+	if interval := current.Settings.SlowModeInterval(); interval > 0 && participant.Role != chatDomain.RoleAdmin &&
+		msg.CreatedAt.Sub(lastSentAt) < interval {
+		return "SlowModeActive", fmt.Errorf("slow mode allows one message every %d seconds", current.Settings.SlowModeSeconds)
+	}
+	return h.prepareMessage(ctx, msg, current)
+}
+
+// rejectMessage tells the sender why a message was not accepted.
+func (h *Handler) rejectMessage(client *Client, errorCode, detail string) {
+	event := SocketEvent{
+		Event:  EventMessageRejected,
+		ChatId: client.chatId,
+		Data: common.ProblemDetails{
+			Title:     "Message Rejected",
+			ErrorCode: errorCode,
+			Detail:    detail,
+		},
+	}
+	if err := client.WriteJSON(event); err != nil {
+		h.logger.Error().Err(err).Msg("Unable to write a rejection")
+	}
+}
+
 func (h *Handler) HandleMessages() {
+	h.broadcasting.Store(true)
+	defer h.broadcasting.Store(false)
 	for {
-		msg := <-h.wsManager.broadcast
+		var msg domain.Message
+		select {
+		case <-h.ctx.Done():
+			return
+		case msg = <-h.wsManager.broadcast:
+		}
 		id, _ := uuid.NewV7()
 		msg.ID = id.String()
-		if err := h.messageRepo.Create(msg); err != nil {
+		ctx, cancel := h.operationContext()
+		if err := h.deliverMessage(ctx, msg); err != nil {
 			h.logger.Error().Err(err).Msg("Unable to create a message")
-			continue
 		}
-		fmt.Printf("Broadcasting message to %d clients\n", len(h.wsManager.clients))
-		fmt.Printf("[ChatID:%s]|[SenderID:%s]|Message: %s\n", msg.ChatID, msg.SenderID, msg.Content)
-		fmt.Println("-------------------------------------------------------------")
-		h.wsManager.SendToClients(msg, h.logger)
+		cancel()
+	}
+}
+
+// Broadcasting reports whether messages posted by clients are being stored and sent to the chats.
+func (h *Handler) Broadcasting() bool {
+	return h.broadcasting.Load()
+}
+
+// operationContext bounds one unit of websocket or background work by the configured timeout.
+func (h *Handler) operationContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(h.ctx, h.timeout)
+}
+
+// prepareMessage validates a message on behalf of its sender and fills in what the server derives from it.
+// Server managed fields sent by the client are reset. On failure the returned code is sent back as the rejection code.
+func (h *Handler) prepareMessage(ctx context.Context, msg *domain.Message, chat *chatDomain.Chat) (string, error) {
+	if chat.IsArchived() {
+		return "ChatArchived", chatDomain.ErrChatArchived
+	}
+	msg.ReadStatus = false
+	msg.ThreadReplyCount = 0
+	msg.ThreadLastReplyAt = nil
+	msg.Reactions = nil
+	msg.Attachments = nil
+	msg.ExpiresAt = nil
+	msg.LinkPreviews = nil
+	if errorCode, err := h.validateThreading(ctx, msg); err != nil {
+		return errorCode, err
+	}
+	if errorCode, err := h.validateAttachments(ctx, msg); err != nil {
+		return errorCode, err
+	}
+	msg.Normalize()
+	if err := msg.Validate(); err != nil {
+		var validationErr *domain.ValidationError
+		if errors.As(err, &validationErr) {
+			return validationErr.Code, err
+		}
+		return "InvalidMessage", err
+	}
+	if err := msg.ApplyTTL(); err != nil {
+		return "InvalidTTL", err
+	}
+	if err := h.resolveMentions(ctx, msg, chat); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to resolve mentions")
+		return "MentionLookupFailed", errors.New("unable to resolve the mentions of the message")
+	}
+	return "", nil
+}
+
+// deliverMessage stores a prepared message and pushes it, with its thread and mention events, to connected clients.
+func (h *Handler) deliverMessage(ctx context.Context, msg domain.Message) error {
+	if err := h.messageRepo.Create(ctx, msg); err != nil {
+		return err
+	}
+	h.publishMessage(ctx, msg)
+	return nil
+}
+
+// publishMessage sends a stored message to the clients of its chat and starts unfurling its links.
+func (h *Handler) publishMessage(ctx context.Context, msg domain.Message) {
+	for i := range msg.Attachments {
+		msg.Attachments[i].MessageID = &msg.ID
+	}
+	h.logger.Debug().Str("chat_id", msg.ChatID).Str("message_id", msg.ID).Msg("Broadcasting message")
+	h.wsManager.SendToClients(msg, h.logger)
+	if msg.IsThreadReply() {
+		h.publishThreadUpdate(ctx, msg)
+	}
+	h.publishMentions(msg)
+	// Unfurling outlasts the delivery, so it runs under the server context rather than the delivery's.
+	go h.unfurlLinks(h.ctx, msg)
+}
+
+// validateThreading checks the reply and thread references a client sent with a message. Unknown and malformed
+// ids are rejected like references to messages of another chat.
+func (h *Handler) validateThreading(ctx context.Context, msg *domain.Message) (string, error) {
+	if msg.ReplyToID != nil {
+		parent, err := h.messageRepo.GetByID(ctx, *msg.ReplyToID)
+		if err != nil && !errors.Is(err, domain.ErrMessageNotFound) && !errors.Is(err, common.ErrValidation) {
+			h.logger.Error().Err(err).Msg("Failed to retrieve replied message")
+			return "ReplyLookupFailed", errors.New("unable to verify the replied message")
+		}
+		if err := msg.ValidateReplyTarget(parent); err != nil {
+			return "InvalidReplyTarget", err
+		}
+	}
+	if msg.ThreadRootID != nil {
+		root, err := h.messageRepo.GetByID(ctx, *msg.ThreadRootID)
+		if err != nil && !errors.Is(err, domain.ErrMessageNotFound) && !errors.Is(err, common.ErrValidation) {
+			h.logger.Error().Err(err).Msg("Failed to retrieve thread root")
+			return "ThreadLookupFailed", errors.New("unable to verify the thread root")
+		}
+		if err := msg.ValidateThreadRoot(root); err != nil {
+			return "InvalidThreadRoot", err
+		}
+	}
+	return "", nil
+}
+
+func (h *Handler) publishThreadUpdate(ctx context.Context, reply domain.Message) {
+	root, err := h.messageRepo.GetByID(ctx, *reply.ThreadRootID)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Failed to retrieve thread root for thread update")
+		return
+	}
+	h.wsManager.PublishToChat(reply.ChatID, EventThreadUpdated, domain.ThreadSummary{
+		RootID:        root.ID,
+		ReplyCount:    root.ThreadReplyCount,
+		LastReplyAt:   root.ThreadLastReplyAt,
+		LatestReplyID: reply.ID,
+	})
+}
+
+func (h *Handler) GetThreadMessages(w http.ResponseWriter, r *http.Request) {
+	chatID := chi.URLParam(r, "chatID")
+	messageID := chi.URLParam(r, "messageID")
+
+	limitStr := r.URL.Query().Get("limit")
+	limit := 120
+	if limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
+			limit = l
+		}
+	}
+
+	offsetStr := r.URL.Query().Get("offset")
+	offset := 0
+	if offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
+			offset = o
+		}
+	}
+
+	userId, until, ok := h.authorizeRead(w, r, chatID)
+	if !ok {
+		return
+	}
+
+	root, err := h.messageRepo.GetByID(r.Context(), messageID)
+	if errors.Is(err, domain.ErrMessageNotFound) ||
+		err == nil && (root.ChatID != chatID || root.IsThreadReply() || (until != nil && root.CreatedAt.After(*until))) {
+		err = domain.ErrThreadNotFound
+	}
+	if err != nil {
+		h.writeError(w, err, "Failed to retrieve thread root")
+		return
+	}
+
+	messages, err := h.messageRepo.GetThreadMessages(r.Context(), root.ID, until, limit, offset)
+	if err != nil {
+		h.writeError(w, err, "Failed to retrieve thread messages")
+		return
 	}
+
+	withRoot := append([]domain.Message{*root}, messages...)
+	h.decorateMessages(r.Context(), withRoot, userId)
+	root, messages = &withRoot[0], withRoot[1:]
+	common.WriteJsonWithEncode(w, http.StatusOK, map[string]interface{}{
+		"root":     root,
+		"messages": messages,
+		"count":    len(messages),
+	})
 }
 
 func (h *Handler) GetMessagesByChatID(w http.ResponseWriter, r *http.Request) {
@@ -139,16 +423,18 @@ func (h *Handler) GetMessagesByChatID(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	messages, err := h.messageRepo.GetByChatID(chatID, limit, offset)
+	userId, until, ok := h.authorizeRead(w, r, chatID)
+	if !ok {
+		return
+	}
+
+	messages, err := h.getMessages(r.Context(), chatID, until, limit, offset)
 	if err != nil {
-		h.logger.Error().Err(err).Msg("Failed to retrieve messages by chatID")
-		common.ErrorResponse(w, http.StatusInternalServerError, common.ProblemDetails{
-			Title:  "Internal Server Error",
-			Detail: "Error occurred during getting chat ID",
-		})
+		h.writeError(w, err, "Failed to retrieve messages by chatID")
 		return
 	}
 
+	h.decorateMessages(r.Context(), messages, userId)
 	common.WriteJsonWithEncode(w, http.StatusOK, map[string]interface{}{
 		"messages": messages,
 		"count":    len(messages),
@@ -188,65 +474,177 @@ func (h *Handler) GetMessagesByGroupID(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	chat, err := h.chatRepo.GetChatByUserGroupId(groupID)
+	chat, err := h.chatRepo.GetChatByUserGroupId(r.Context(), groupID)
 	if err != nil {
-		h.logger.Error().Err(err).Msg("Failed to retrieve chat by groupID")
-		common.ErrorResponse(w, http.StatusInternalServerError, common.ProblemDetails{
-			Title:  "Internal Server Error",
-			Detail: "Error occurred during getting chat by group ID",
-		})
+		h.writeError(w, err, "Failed to retrieve chat by groupID")
 		return
 	}
 
-	messages, err := h.messageRepo.GetByChatID(chat.Id, limit, offset)
+	userId, until, ok := h.authorizeRead(w, r, chat.Id)
+	if !ok {
+		return
+	}
+
+	messages, err := h.getMessages(r.Context(), chat.Id, until, limit, offset)
 	if err != nil {
-		h.logger.Error().Err(err).Msg("Failed to retrieve messages by groupID")
-		common.ErrorResponse(w, http.StatusInternalServerError, common.ProblemDetails{
-			Title:  "Internal Server Error",
-			Detail: "Error occurred during getting messages by group ID",
-		})
+		h.writeError(w, err, "Failed to retrieve messages by groupID")
 		return
 	}
 
+	h.decorateMessages(r.Context(), messages, userId)
 	common.WriteJsonWithEncode(w, http.StatusOK, map[string]interface{}{
 		"messages": messages,
 		"count":    len(messages),
 	})
 }
 
-func (h *Handler) authenticateRequest(_ http.ResponseWriter, r *http.Request) bool {
-	token := r.URL.Query().Get("token")
-	if token == "" {
-		h.logger.Error().Msg("Missing jwt token")
-		return false
+// authenticateRequest returns the id of the user the request token was issued for.
+func (h *Handler) authenticateRequest(_ http.ResponseWriter, r *http.Request) (string, bool) {
+	userId, err := common.UserIdFromToken(common.TokenFromRequest(r), h.jwtSecret)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Invalid jwt token")
+		return "", false
 	}
+	return userId, true
+}
 
-	if !h.validateJWTToken(token) {
-		h.logger.Error().Msg("Invalid jwt token")
-		return false
+// authorizeRead checks that the caller may read the chat history and returns the caller's user id.
+// Participants that left the chat only see messages posted before they left, which is returned as the upper bound.
+func (h *Handler) authorizeRead(w http.ResponseWriter, r *http.Request, chatID string) (string, *time.Time, bool) {
+	userId, ok := h.authenticateRequest(w, r)
+	if !ok {
+		common.ErrorResponse(w, http.StatusUnauthorized, common.ProblemDetails{
+			Title:     "Unauthorized",
+			ErrorCode: "AuthenticationFailure",
+			Detail:    "Invalid authentication token",
+		})
+		return "", nil, false
 	}
-	return true
+
+	// Participants are kept while a chat is deleted, its history must not be readable until it is restored.
+	if _, err := h.chatRepo.GetChatById(r.Context(), chatID); err != nil {
+		h.writeError(w, err, "Failed to retrieve chat")
+		return "", nil, false
+	}
+	participant, err := h.chatRepo.GetChatParticipant(r.Context(), chatID, userId)
+	if errors.Is(err, chatDomain.ErrParticipantNotFound) {
+		err = chatDomain.ErrNotChatParticipant
+	}
+	if err != nil {
+		h.writeError(w, err, "Failed to retrieve chat participant")
+		return "", nil, false
+	}
+	if participant.Status == chatDomain.StatusLeft && participant.LeftAt != nil {
+		return userId, participant.LeftAt, true
+	}
+	if !participant.CanReadMessages() {
+		common.WriteError(w, chatDomain.ErrNotChatParticipant)
+		return "", nil, false
+	}
+	return userId, nil, true
 }
 
-func (h *Handler) validateJWTToken(tokenString string) bool {
-	// TODO: Implement real JWT validation
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if token.Method != jwt.SigningMethodHS512 {
-			return nil, fmt.Errorf("unexpected signing method: %v, expected HS512", token.Header["alg"])
-		}
-		return h.jwtSecret, nil
-	})
+// requireActiveParticipant authenticates the caller and checks that they are an active participant of the chat.
+func (h *Handler) requireActiveParticipant(w http.ResponseWriter, r *http.Request, chatID string) (string, bool) {
+	return h.requireParticipant(w, r, chatID, func(p *chatDomain.ChatParticipant) bool {
+		return p.Status == chatDomain.StatusActive
+	}, chatDomain.ErrNotChatParticipant)
+}
+
+// requireChatAdmin authenticates the caller and checks that they are an active admin of the chat.
+func (h *Handler) requireChatAdmin(w http.ResponseWriter, r *http.Request, chatID string) (string, bool) {
+	return h.requireParticipant(w, r, chatID, (*chatDomain.ChatParticipant).IsAdmin, chatDomain.ErrChatAdminRequired)
+}
+
+func (h *Handler) requireParticipant(w http.ResponseWriter, r *http.Request, chatID string,
+	allowed func(*chatDomain.ChatParticipant) bool, forbidden error) (string, bool) {
+	userId, ok := h.authenticateRequest(w, r)
+	if !ok {
+		common.ErrorResponse(w, http.StatusUnauthorized, common.ProblemDetails{
+			Title:     "Unauthorized",
+			ErrorCode: "AuthenticationFailure",
+			Detail:    "Invalid authentication token",
+		})
+		return "", false
+	}
+
+	participant, err := h.chatRepo.GetChatParticipant(r.Context(), chatID, userId)
+	if errors.Is(err, chatDomain.ErrParticipantNotFound) || err == nil && !allowed(participant) {
+		err = forbidden
+	}
+	if err != nil {
+		h.writeError(w, err, "Failed to retrieve chat participant")
+		return "", false
+	}
+	return userId, true
+}
 
+// requireWritableChat checks that the chat exists and is not archived, archived chats are read-only.
+func (h *Handler) requireWritableChat(ctx context.Context, w http.ResponseWriter, chatID string) bool {
+	chat, err := h.chatRepo.GetChatById(ctx, chatID)
 	if err != nil {
-		h.logger.Error().Err(err).Msg("JWT parsing error")
+		h.writeError(w, err, "Failed to retrieve chat")
 		return false
 	}
+	if chat.IsArchived() {
+		common.WriteError(w, chatDomain.ErrChatArchived)
+		return false
+	}
+	return true
+}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		h.logger.Info().Interface("claims", claims).Msg("JWT token validated successfully")
-		return true
+// writeError answers with the problem details mapped from err. Internal errors are logged with msg first,
+// the client only gets a generic detail for them.
+func (h *Handler) writeError(w http.ResponseWriter, err error, msg string) {
+	if common.StatusCode(err) == http.StatusInternalServerError {
+		h.logger.Error().Err(err).Msg(msg)
 	}
+	common.WriteError(w, err)
+}
+
+// writeValidationError reports a domain validation failure, using its code as the error code.
+func writeValidationError(w http.ResponseWriter, err error) {
+	problem := common.ProblemDetails{
+		Title:     "Invalid Request",
+		ErrorCode: "InvalidRequest",
+		Detail:    err.Error(),
+	}
+	var validationErr *domain.ValidationError
+	if errors.As(err, &validationErr) {
+		problem.ErrorCode = validationErr.Code
+	}
+	common.ErrorResponse(w, http.StatusBadRequest, problem)
+}
+
+// decorateMessages fills in the reactions and attachments returned with listed messages.
+func (h *Handler) decorateMessages(ctx context.Context, messages []domain.Message, userId string) {
+	h.attachReactions(ctx, messages, userId)
+	h.attachAttachments(ctx, messages)
+}
 
-	h.logger.Error().Msg("Invalid JWT token claims")
-	return false
+// attachReactions fills in the aggregated reactions of each message as seen by userId.
+// Failures are logged and leave the messages without reactions rather than failing the request.
+func (h *Handler) attachReactions(ctx context.Context, messages []domain.Message, userId string) {
+	if len(messages) == 0 {
+		return
+	}
+	ids := make([]string, len(messages))
+	for i := range messages {
+		ids[i] = messages[i].ID
+	}
+	summaries, err := h.messageRepo.GetReactionSummaries(ctx, ids, userId)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Failed to retrieve reaction summaries")
+		return
+	}
+	for i := range messages {
+		messages[i].Reactions = summaries[messages[i].ID]
+	}
+}
+
+func (h *Handler) getMessages(ctx context.Context, chatID string, until *time.Time, limit, offset int) ([]domain.Message, error) {
+	if until != nil {
+		return h.messageRepo.GetByChatIDUntil(ctx, chatID, *until, limit, offset)
+	}
+	return h.messageRepo.GetByChatID(ctx, chatID, limit, offset)
 }
@@ -0,0 +1,300 @@
+package route
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/HappYness-Project/ChatBackendServer/common"
+	chatDomain "github.com/HappYness-Project/ChatBackendServer/internal/chat/domain"
+	domain "github.com/HappYness-Project/ChatBackendServer/internal/message/domain"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	EventScheduledMessageFailed = "scheduled_message.failed"
+
+	scheduledMessageInterval = 5 * time.Second
+	scheduledMessageBatch    = 50
+	// A delivery claimed longer ago than this is assumed abandoned and retried.
+	scheduledClaimTimeout = time.Minute
+)
+
+type ScheduleMessageRequest struct {
+	Content      string    `json:"content"`
+	SendAt       time.Time `json:"send_at"`
+	ReplyToID    *string   `json:"reply_to_id,omitempty"`
+	ThreadRootID *string   `json:"thread_root_id,omitempty"`
+}
+
+type UpdateScheduledMessageRequest struct {
+	Content *string    `json:"content,omitempty"`
+	SendAt  *time.Time `json:"send_at,omitempty"`
+}
+
+func (h *Handler) CreateScheduledMessage(w http.ResponseWriter, r *http.Request) {
+	chatID := chi.URLParam(r, "chatID")
+	userId, ok := h.requireActiveParticipant(w, r, chatID)
+	if !ok {
+		return
+	}
+
+	var request ScheduleMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		common.ErrorResponse(w, http.StatusBadRequest, common.ProblemDetails{
+			Title:     "Invalid Request Body",
+			ErrorCode: "InvalidJSON",
+			Detail:    "Unable to decode request body as JSON",
+		})
+		return
+	}
+
+	now := time.Now().UTC()
+	scheduled, err := domain.NewScheduledMessage(chatID, userId, request.Content, request.ReplyToID, request.ThreadRootID, request.SendAt, now)
+	if err != nil {
+		writeValidationError(w, err)
+		return
+	}
+	if !h.validateScheduledContent(r.Context(), w, scheduled, now) {
+		return
+	}
+
+	if err := h.messageRepo.CreateScheduledMessage(r.Context(), *scheduled); err != nil {
+		h.writeError(w, err, "Failed to create scheduled message")
+		return
+	}
+	common.WriteJsonWithEncode(w, http.StatusCreated, scheduled)
+}
+
+// GetScheduledMessages lists the caller's pending scheduled messages for the chat.
+func (h *Handler) GetScheduledMessages(w http.ResponseWriter, r *http.Request) {
+	chatID := chi.URLParam(r, "chatID")
+	userId, ok := h.authenticateRequest(w, r)
+	if !ok {
+		common.ErrorResponse(w, http.StatusUnauthorized, common.ProblemDetails{
+			Title:     "Unauthorized",
+			ErrorCode: "AuthenticationFailure",
+			Detail:    "Invalid authentication token",
+		})
+		return
+	}
+
+	scheduled, err := h.messageRepo.GetPendingScheduledMessages(r.Context(), chatID, userId)
+	if err != nil {
+		h.writeError(w, err, "Failed to retrieve scheduled messages")
+		return
+	}
+	common.WriteJsonWithEncode(w, http.StatusOK, map[string]interface{}{
+		"scheduled_messages": scheduled,
+		"count":              len(scheduled),
+	})
+}
+
+func (h *Handler) UpdateScheduledMessage(w http.ResponseWriter, r *http.Request) {
+	chatID := chi.URLParam(r, "chatID")
+	userId, ok := h.requireActiveParticipant(w, r, chatID)
+	if !ok {
+		return
+	}
+	scheduled, ok := h.findOwnScheduledMessage(r.Context(), w, chatID, chi.URLParam(r, "scheduledID"), userId)
+	if !ok {
+		return
+	}
+
+	var request UpdateScheduledMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		common.ErrorResponse(w, http.StatusBadRequest, common.ProblemDetails{
+			Title:     "Invalid Request Body",
+			ErrorCode: "InvalidJSON",
+			Detail:    "Unable to decode request body as JSON",
+		})
+		return
+	}
+
+	now := time.Now().UTC()
+	if !scheduled.IsPending() {
+		writeScheduledNotPending(w)
+		return
+	}
+	if err := scheduled.Edit(request.Content, request.SendAt, now); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+	if !h.validateScheduledContent(r.Context(), w, scheduled, now) {
+		return
+	}
+
+	updated, err := h.messageRepo.UpdateScheduledMessage(r.Context(), *scheduled)
+	if err != nil {
+		h.writeError(w, err, "Failed to update scheduled message")
+		return
+	}
+	if !updated {
+		writeScheduledNotPending(w)
+		return
+	}
+	common.WriteJsonWithEncode(w, http.StatusOK, scheduled)
+}
+
+func (h *Handler) CancelScheduledMessage(w http.ResponseWriter, r *http.Request) {
+	chatID := chi.URLParam(r, "chatID")
+	userId, ok := h.authenticateRequest(w, r)
+	if !ok {
+		common.ErrorResponse(w, http.StatusUnauthorized, common.ProblemDetails{
+			Title:     "Unauthorized",
+			ErrorCode: "AuthenticationFailure",
+			Detail:    "Invalid authentication token",
+		})
+		return
+	}
+	scheduled, ok := h.findOwnScheduledMessage(r.Context(), w, chatID, chi.URLParam(r, "scheduledID"), userId)
+	if !ok {
+		return
+	}
+
+	cancelled, err := h.messageRepo.CancelScheduledMessage(r.Context(), scheduled.ID)
+	if err != nil {
+		h.writeError(w, err, "Failed to cancel scheduled message")
+		return
+	}
+	if !cancelled {
+		writeScheduledNotPending(w)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RunScheduledMessages delivers due scheduled messages. Schedules are persisted, so messages that came due
+// while the server was down are delivered on the first tick after it starts.
+func (h *Handler) RunScheduledMessages(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-h.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		h.sendDueScheduledMessages(time.Now().UTC())
+	}
+}
+
+func (h *Handler) sendDueScheduledMessages(now time.Time) {
+	ctx, cancel := h.operationContext()
+	due, err := h.messageRepo.ClaimDueScheduledMessages(ctx, now, now.Add(-scheduledClaimTimeout), scheduledMessageBatch)
+	cancel()
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Failed to claim scheduled messages")
+		return
+	}
+	for i := range due {
+		ctx, cancel := h.operationContext()
+		h.sendScheduledMessage(ctx, &due[i], now)
+		cancel()
+	}
+}
+
+// sendScheduledMessage delivers one claimed schedule. The sender must still be allowed to post when it comes due.
+func (h *Handler) sendScheduledMessage(ctx context.Context, scheduled *domain.ScheduledMessage, now time.Time) {
+	_, err := h.messageRepo.GetByID(ctx, scheduled.ID)
+	if err == nil {
+		// Delivered by an earlier attempt that stopped before recording it.
+		h.completeScheduledMessage(ctx, scheduled, domain.ScheduledSent, "")
+		return
+	}
+	if !errors.Is(err, domain.ErrMessageNotFound) {
+		h.logger.Error().Err(err).Msg("Failed to check scheduled message delivery")
+		return
+	}
+
+	chat, err := h.chatRepo.GetChatById(ctx, scheduled.ChatID)
+	var participant *chatDomain.ChatParticipant
+	if err == nil {
+		participant, err = h.chatRepo.GetChatParticipant(ctx, scheduled.ChatID, scheduled.SenderID)
+	}
+	if err != nil && !errors.Is(err, common.ErrNotFound) {
+		h.logger.Error().Err(err).Msg("Failed to retrieve chat and sender of scheduled message")
+		return
+	}
+	if err != nil || !chat.CanPost(participant) {
+		h.completeScheduledMessage(ctx, scheduled, domain.ScheduledFailed, "the sender can no longer post in this chat")
+		return
+	}
+
+	msg := scheduled.ToMessage(now)
+	if _, err := h.prepareMessage(ctx, &msg, chat); err != nil {
+		h.completeScheduledMessage(ctx, scheduled, domain.ScheduledFailed, err.Error())
+		return
+	}
+	// The message is stored and the schedule marked sent together, so the message is never sent twice.
+	err = h.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		if err := h.messageRepo.Create(ctx, msg); err != nil {
+			return err
+		}
+		return h.messageRepo.CompleteScheduledMessage(ctx, scheduled.ID, domain.ScheduledSent, nil)
+	})
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Unable to deliver scheduled message")
+		h.completeScheduledMessage(ctx, scheduled, domain.ScheduledFailed, "the message could not be stored")
+		return
+	}
+	h.publishMessage(ctx, msg)
+}
+
+func (h *Handler) completeScheduledMessage(ctx context.Context, scheduled *domain.ScheduledMessage, status domain.ScheduledStatus, failureReason string) {
+	var reason *string
+	if failureReason != "" {
+		reason = &failureReason
+	}
+	if err := h.messageRepo.CompleteScheduledMessage(ctx, scheduled.ID, status, reason); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to record scheduled message outcome")
+		return
+	}
+	if status == domain.ScheduledFailed {
+		scheduled.Status, scheduled.FailureReason = status, reason
+		h.wsManager.PublishToUser(scheduled.SenderID, scheduled.ChatID, EventScheduledMessageFailed, scheduled)
+	}
+}
+
+// validateScheduledContent runs the checks a message gets when it is sent, so obvious problems surface
+// when scheduling rather than at delivery time. The normalized content is kept.
+func (h *Handler) validateScheduledContent(ctx context.Context, w http.ResponseWriter, scheduled *domain.ScheduledMessage, now time.Time) bool {
+	chat, err := h.chatRepo.GetChatById(ctx, scheduled.ChatID)
+	if err != nil {
+		h.writeError(w, err, "Failed to retrieve chat")
+		return false
+	}
+	msg := scheduled.ToMessage(now)
+	if errorCode, err := h.prepareMessage(ctx, &msg, chat); err != nil {
+		common.ErrorResponse(w, http.StatusBadRequest, common.ProblemDetails{
+			Title:     "Invalid Request",
+			ErrorCode: errorCode,
+			Detail:    err.Error(),
+		})
+		return false
+	}
+	scheduled.Content = msg.Content
+	return true
+}
+
+func (h *Handler) findOwnScheduledMessage(ctx context.Context, w http.ResponseWriter, chatID, scheduledID, userId string) (*domain.ScheduledMessage, bool) {
+	scheduled, err := h.messageRepo.GetScheduledMessage(ctx, scheduledID)
+	if err == nil && (scheduled.ChatID != chatID || scheduled.SenderID != userId) {
+		err = domain.ErrScheduledMessageNotFound
+	}
+	if err != nil {
+		h.writeError(w, err, "Failed to retrieve scheduled message")
+		return nil, false
+	}
+	return scheduled, true
+}
+
+func writeScheduledNotPending(w http.ResponseWriter) {
+	common.ErrorResponse(w, http.StatusConflict, common.ProblemDetails{
+		Title:     "Conflict",
+		ErrorCode: "ScheduledMessageNotPending",
+		Detail:    "The scheduled message was already sent or cancelled",
+	})
+}
@@ -0,0 +1,74 @@
+package route
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/HappYness-Project/ChatBackendServer/common"
+	domain "github.com/HappYness-Project/ChatBackendServer/internal/message/domain"
+)
+
+// SearchMessages runs a full-text search over the chats the caller actively participates in.
+// Supported filters are chat_id, sender_id, type and an RFC 3339 from/to range.
+func (h *Handler) SearchMessages(w http.ResponseWriter, r *http.Request) {
+	userId, ok := h.authenticateRequest(w, r)
+	if !ok {
+		common.ErrorResponse(w, http.StatusUnauthorized, common.ProblemDetails{
+			Title:     "Unauthorized",
+			ErrorCode: "AuthenticationFailure",
+			Detail:    "Invalid authentication token",
+		})
+		return
+	}
+
+	params := r.URL.Query()
+	query := domain.SearchQuery{
+		Text:        params.Get("q"),
+		ChatID:      params.Get("chat_id"),
+		SenderID:    params.Get("sender_id"),
+		MessageType: params.Get("type"),
+	}
+	if limit, err := strconv.Atoi(params.Get("limit")); err == nil {
+		query.Limit = limit
+	}
+	if offset, err := strconv.Atoi(params.Get("offset")); err == nil {
+		query.Offset = offset
+	}
+
+	var err error
+	if query.From, err = parseTimeParam(params.Get("from")); err != nil {
+		writeValidationError(w, &domain.ValidationError{Code: "InvalidFilter", Field: "from", Detail: "from must be an RFC 3339 timestamp"})
+		return
+	}
+	if query.To, err = parseTimeParam(params.Get("to")); err != nil {
+		writeValidationError(w, &domain.ValidationError{Code: "InvalidFilter", Field: "to", Detail: "to must be an RFC 3339 timestamp"})
+		return
+	}
+	if err := query.Validate(); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	results, err := h.messageRepo.Search(r.Context(), userId, query)
+	if err != nil {
+		h.writeError(w, err, "Failed to search messages")
+		return
+	}
+
+	common.WriteJsonWithEncode(w, http.StatusOK, map[string]interface{}{
+		"results": results,
+		"count":   len(results),
+	})
+}
+
+func parseTimeParam(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &parsed, nil
+}
@@ -1,7 +1,6 @@
 package route
 
 import (
-	"fmt"
 	"net/http"
 	"strconv"
 	"sync"
@@ -11,8 +10,37 @@ import (
 	"github.com/gorilla/websocket"
 )
 
+// Client is a websocket connection joined to a single chat on behalf of an authenticated user.
+type Client struct {
+	conn      *websocket.Conn
+	chatId    string
+	userId    string
+	writeLock sync.Mutex
+}
+
+// WriteJSON serialises writes, gorilla connections support only one concurrent writer.
+func (c *Client) WriteJSON(v interface{}) error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	return c.conn.WriteJSON(v)
+}
+
+func (c *Client) close(code int, reason string) {
+	c.writeLock.Lock()
+	_ = c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason))
+	c.writeLock.Unlock()
+	c.conn.Close()
+}
+
+// SocketEvent is pushed to websocket clients for anything other than a new chat message.
+type SocketEvent struct {
+	Event  string      `json:"event"`
+	ChatId string      `json:"chat_id"`
+	Data   interface{} `json:"data,omitempty"`
+}
+
 type WebSocketManager struct {
-	clients   map[*websocket.Conn]bool
+	clients   map[*websocket.Conn]*Client
 	broadcast chan domain.Message
 	upgrader  websocket.Upgrader
 	mutex     sync.RWMutex
@@ -21,7 +49,7 @@ type WebSocketManager struct {
 
 func NewWebSocketManager(logger *loggers.AppLogger) *WebSocketManager {
 	return &WebSocketManager{
-		clients:   make(map[*websocket.Conn]bool),
+		clients:   make(map[*websocket.Conn]*Client),
 		broadcast: make(chan domain.Message, 256),
 		upgrader: websocket.Upgrader{
 			ReadBufferSize:  1024,
@@ -34,12 +62,39 @@ func NewWebSocketManager(logger *loggers.AppLogger) *WebSocketManager {
 	}
 }
 
-func (wsm *WebSocketManager) AddClient(conn *websocket.Conn) {
+// ConnectionStats counts the open websocket connections and the chats and users they belong to.
+type ConnectionStats struct {
+	Connections    int `json:"connections"`
+	Chats          int `json:"chats"`
+	Users          int `json:"users"`
+	QueuedMessages int `json:"queued_messages"`
+	QueueCapacity  int `json:"queue_capacity"`
+}
+
+func (wsm *WebSocketManager) Stats() ConnectionStats {
+	wsm.mutex.RLock()
+	defer wsm.mutex.RUnlock()
+	chats, users := make(map[string]struct{}), make(map[string]struct{})
+	for _, client := range wsm.clients {
+		chats[client.chatId] = struct{}{}
+		users[client.userId] = struct{}{}
+	}
+	return ConnectionStats{
+		Connections:    len(wsm.clients),
+		Chats:          len(chats),
+		Users:          len(users),
+		QueuedMessages: len(wsm.broadcast),
+		QueueCapacity:  cap(wsm.broadcast),
+	}
+}
+
+func (wsm *WebSocketManager) AddClient(conn *websocket.Conn, chatId, userId string) *Client {
+	client := &Client{conn: conn, chatId: chatId, userId: userId}
 	wsm.mutex.Lock()
 	defer wsm.mutex.Unlock()
-	wsm.clients[conn] = true
+	wsm.clients[conn] = client
 	wsm.logger.Info().Msg("New client connected - clients: " + conn.LocalAddr().String())
-
+	return client
 }
 
 func (wsm *WebSocketManager) RemoveClient(conn *websocket.Conn) {
@@ -50,27 +105,85 @@ func (wsm *WebSocketManager) RemoveClient(conn *websocket.Conn) {
 	wsm.logger.Info().Msg("Client disconnected - client number: " + strconv.Itoa(len(wsm.clients)))
 }
 
+// DisconnectUser closes every connection the user has open for the chat.
+func (wsm *WebSocketManager) DisconnectUser(chatId, userId string) {
+	for _, client := range wsm.chatClients(chatId) {
+		if client.userId != userId {
+			continue
+		}
+		client.close(websocket.ClosePolicyViolation, "no longer a participant of this chat")
+		wsm.RemoveClient(client.conn)
+	}
+}
+
+// DisconnectChat closes every connection joined to the chat.
+func (wsm *WebSocketManager) DisconnectChat(chatId string) {
+	for _, client := range wsm.chatClients(chatId) {
+		client.close(websocket.CloseGoingAway, "chat was deleted")
+		wsm.RemoveClient(client.conn)
+	}
+}
+
 func (wsm *WebSocketManager) BroadcastMessage(msg domain.Message) {
 	select {
 	case wsm.broadcast <- msg:
 	default:
-		fmt.Println("Broadcast channel full, dropping message")
+		wsm.logger.Error().Str("chat_id", msg.ChatID).Str("message_id", msg.ID).Msg("Broadcast channel full, dropping message")
 	}
 }
 
 func (wsm *WebSocketManager) SendToClients(msg domain.Message, logger *loggers.AppLogger) {
-	wsm.mutex.RLock()
-	clientsCopy := make([]*websocket.Conn, 0, len(wsm.clients))
-	for client := range wsm.clients {
-		clientsCopy = append(clientsCopy, client)
-	}
-	wsm.mutex.RUnlock()
-
-	for _, client := range clientsCopy {
+	for _, client := range wsm.chatClients(msg.ChatID) {
 		err := client.WriteJSON(msg)
 		if err != nil {
 			logger.Error().Err(err).Msg("Unable to write a message")
-			wsm.RemoveClient(client)
+			wsm.RemoveClient(client.conn)
+		}
+	}
+}
+
+// PublishToChat sends an event to every client connected to the chat.
+func (wsm *WebSocketManager) PublishToChat(chatId string, event string, data interface{}) {
+	payload := SocketEvent{Event: event, ChatId: chatId, Data: data}
+	for _, client := range wsm.chatClients(chatId) {
+		if err := client.WriteJSON(payload); err != nil {
+			wsm.logger.Error().Err(err).Msg("Unable to write an event")
+			wsm.RemoveClient(client.conn)
+		}
+	}
+}
+
+// PublishToUser sends an event to every connection the user has open, whichever chat it is joined to.
+func (wsm *WebSocketManager) PublishToUser(userId string, chatId string, event string, data interface{}) {
+	payload := SocketEvent{Event: event, ChatId: chatId, Data: data}
+	for _, client := range wsm.userClients(userId) {
+		if err := client.WriteJSON(payload); err != nil {
+			wsm.logger.Error().Err(err).Msg("Unable to write an event")
+			wsm.RemoveClient(client.conn)
+		}
+	}
+}
+
+func (wsm *WebSocketManager) userClients(userId string) []*Client {
+	wsm.mutex.RLock()
+	defer wsm.mutex.RUnlock()
+	clients := make([]*Client, 0)
+	for _, client := range wsm.clients {
+		if client.userId == userId {
+			clients = append(clients, client)
+		}
+	}
+	return clients
+}
+
+func (wsm *WebSocketManager) chatClients(chatId string) []*Client {
+	wsm.mutex.RLock()
+	defer wsm.mutex.RUnlock()
+	clients := make([]*Client, 0)
+	for _, client := range wsm.clients {
+		if client.chatId == chatId {
+			clients = append(clients, client)
 		}
 	}
+	return clients
 }
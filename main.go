@@ -1,12 +1,22 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 
 	"github.com/HappYness-Project/ChatBackendServer/api"
 	"github.com/HappYness-Project/ChatBackendServer/configs"
 	"github.com/HappYness-Project/ChatBackendServer/dbs"
+	chatRepo "github.com/HappYness-Project/ChatBackendServer/internal/chat/repository"
+	"github.com/HappYness-Project/ChatBackendServer/internal/linkpreview"
+	messageRepo "github.com/HappYness-Project/ChatBackendServer/internal/message/repository"
+	"github.com/HappYness-Project/ChatBackendServer/internal/storage"
 	"github.com/HappYness-Project/ChatBackendServer/loggers"
 )
 
@@ -15,6 +25,22 @@ func main() {
 	fmt.Println("Current Environment : " + current_env)
 	env := configs.InitConfig(current_env)
 	logger := loggers.Setup(env)
+	// Cancelled on SIGINT or SIGTERM, which stops the server, its background jobs and running queries.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if env.DataStore == "memory" {
+		if len(os.Args) > 1 && os.Args[1] == "migrate" {
+			logger.Error().Msg("The memory data store has no database to migrate.")
+			os.Exit(1)
+		}
+		logger.Info().Msg("Using the in-memory data store, data is lost when the server stops.")
+		chats := chatRepo.NewMemoryRepository()
+		messages := messageRepo.NewMemoryRepository(chats)
+		runServer(ctx, env, logger, chats, messages, dbs.NewMemoryUnitOfWork(chats, messages), nil)
+		return
+	}
+
 	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s timezone=UTC connect_timeout=5 ",
 		env.DBHost, env.DBPort, env.DBUser, env.DBPwd, env.DBName)
 	if current_env == "local" || current_env == "" {
@@ -24,16 +50,135 @@ func main() {
 	}
 
 	logger.Info().Msg("Database connection string: " + connStr)
-	database, err := dbs.ConnectToDb(connStr)
+	database, err := dbs.Connect(ctx, connStr, newPoolConfig(env))
 	if err != nil {
 		logger.Error().Err(err).Msg("Unable to connect to the database.")
 		return
 	}
+	migrator, err := dbs.NewMigrator(database)
+	if err != nil {
+		logger.Error().Err(err).Msg("Unable to load the database migrations.")
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(ctx, migrator, os.Args[2:]); err != nil {
+			logger.Error().Err(err).Msg("Migration failed.")
+			os.Exit(1)
+		}
+		return
+	}
+	if !env.DBSkipMigrations {
+		if err := migrator.Up(ctx); err != nil {
+			logger.Error().Err(err).Msg("Unable to migrate the database.")
+			return
+		}
+	}
+	if env.DBSeed {
+		if err := migrator.Seed(ctx); err != nil {
+			logger.Error().Err(err).Msg("Unable to seed the database.")
+			return
+		}
+	}
+
+	runServer(ctx, env, logger, chatRepo.NewRepository(database), messageRepo.NewRepository(database), dbs.NewUnitOfWork(database),
+		dbs.NewMonitor(database, migrator))
+}
+
+func runServer(ctx context.Context, env configs.Env, logger *loggers.AppLogger, chats chatRepo.ChatRepository,
+	messages messageRepo.MessageRepository, unitOfWork dbs.UnitOfWork, database api.DatabaseMonitor) {
+	blobStore, err := newBlobStore(env)
+	if err != nil {
+		logger.Error().Err(err).Msg("Unable to set up the blob store.")
+		return
+	}
 
-	server := api.NewApiServer(fmt.Sprintf("%s:%s", env.Host, env.Port), env.AccessTokenSecret, database, logger)
-	r := server.Setup()
-	if err := server.Run(r); err != nil {
+	server := api.NewApiServer(fmt.Sprintf("%s:%s", env.Host, env.Port), env.AccessTokenSecret, env.WebhookSecret, chats, messages,
+		unitOfWork, database, blobStore, newLinkFetcher(env), env.RequestTimeout(), logger)
+	r := server.Setup(ctx)
+	if err := server.Run(ctx, r); err != nil {
 		logger.Error().Err(err).Msg("Unable to set up the server.")
 		return
 	}
 }
+
+// runMigrate handles the migrate subcommand: "up" (the default), "down [steps]", "status" and "seed".
+func runMigrate(ctx context.Context, migrator *dbs.Migrator, args []string) error {
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	switch command {
+	case "up":
+		return migrator.Up(ctx)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			parsed, err := strconv.Atoi(args[1])
+			if err != nil || parsed < 1 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+			steps = parsed
+		}
+		return migrator.Down(ctx, steps)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			applied := "pending"
+			if status.AppliedAt != nil {
+				applied = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%s\t%s\n", status.Version, status.Name, applied)
+		}
+		return nil
+	case "seed":
+		return migrator.Seed(ctx)
+	default:
+		return errors.New("unknown migrate command " + command + ", expected up, down, status or seed")
+	}
+}
+
+// newPoolConfig sizes the database connection pool from the DB_* settings, lifetimes being given in seconds.
+func newPoolConfig(env configs.Env) dbs.PoolConfig {
+	return dbs.PoolConfig{
+		MaxOpenConns:    env.DBMaxOpenConns,
+		MaxIdleConns:    env.DBMaxIdleConns,
+		ConnMaxLifetime: time.Duration(env.DBConnMaxLifetime) * time.Second,
+		ConnMaxIdleTime: time.Duration(env.DBConnMaxIdleTime) * time.Second,
+		ConnectAttempts: env.DBConnectAttempts,
+	}
+}
+
+// newBlobStore selects where attachment bytes are kept, the local filesystem unless BLOB_STORE is "s3".
+func newBlobStore(env configs.Env) (storage.BlobStore, error) {
+	switch env.BlobStore {
+	case "s3":
+		return storage.NewS3Store(storage.S3Config{
+			Endpoint:  env.S3Endpoint,
+			Region:    env.S3Region,
+			Bucket:    env.S3Bucket,
+			AccessKey: env.S3AccessKey,
+			SecretKey: env.S3SecretKey,
+		})
+	case "", "local":
+		dir := env.BlobLocalDir
+		if dir == "" {
+			dir = "blobs"
+		}
+		return storage.NewLocalStore(dir)
+	default:
+		return nil, fmt.Errorf("unknown blob store %q", env.BlobStore)
+	}
+}
+
+// newLinkFetcher returns the fetcher used for link previews, none when LINK_PREVIEWS is "off".
+func newLinkFetcher(env configs.Env) linkpreview.Fetcher {
+	if env.LinkPreviews == "off" {
+		return nil
+	}
+	return linkpreview.NewHTTPFetcher()
+}
@@ -0,0 +1,44 @@
+package api_tests
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"testing"
+
+	entity "github.com/HappYness-Project/ChatBackendServer/internal/message/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttachmentAPI_UploadAndDownload(t *testing.T) {
+	server := newTestServer(t)
+	alice := newUserId()
+	chatId := server.createChat(alice)
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", "notes.txt")
+	require.NoError(t, err)
+	_, err = part.Write([]byte("meeting notes"))
+	require.NoError(t, err)
+	require.NoError(t, form.Close())
+
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/api/chats/"+chatId+"/attachments", &body)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token(t, alice))
+	resp, err := server.Client().Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	attachment := decode[entity.Attachment](t, resp)
+	assert.Equal(t, "notes.txt", attachment.FileName)
+
+	resp = server.request(http.MethodGet, "/api/chats/"+chatId+"/attachments/"+attachment.ID+"/content", token(t, alice), nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	content, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "meeting notes", string(content))
+}
@@ -0,0 +1,300 @@
+package api_tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/HappYness-Project/ChatBackendServer/internal/chat/domain"
+	entity "github.com/HappYness-Project/ChatBackendServer/internal/message/domain"
+	messageRepository "github.com/HappYness-Project/ChatBackendServer/internal/message/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatAPI_CreateAndGetChat(t *testing.T) {
+	server := newTestServer(t)
+	alice, bob := newUserId(), newUserId()
+	chatId := server.createChat(alice, bob)
+
+	resp := server.request(http.MethodGet, "/api/chats/"+chatId, "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	chat := decode[domain.Chat](t, resp)
+	assert.Equal(t, chatId, chat.Id)
+	assert.Equal(t, domain.ChatTypePrivate, chat.Type)
+
+	resp = server.request(http.MethodGet, "/api/chats/"+chatId+"/chat-participants", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	body := decode[struct {
+		Participants []domain.ChatParticipant `json:"participants"`
+	}](t, resp)
+	roles := make(map[string]domain.ParticipantRole)
+	for _, participant := range body.Participants {
+		roles[participant.UserId] = participant.Role
+	}
+	assert.Equal(t, map[string]domain.ParticipantRole{alice: domain.RoleAdmin, bob: domain.RoleMember}, roles)
+}
+
+func TestChatAPI_ErrorResponses(t *testing.T) {
+	server := newTestServer(t)
+	alice, bob := newUserId(), newUserId()
+	chatId := server.createChat(alice, bob)
+
+	t.Run("invalid JSON", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/api/chats", strings.NewReader("{"))
+		require.NoError(t, err)
+		resp, err := server.Client().Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		requireProblem(t, resp, http.StatusBadRequest, "InvalidJSON")
+	})
+
+	t.Run("invalid chat configuration", func(t *testing.T) {
+		resp := server.request(http.MethodPost, "/api/chats", "", map[string]any{"type": "group"})
+		requireProblem(t, resp, http.StatusBadRequest, "InvalidChatConfiguration")
+	})
+
+	t.Run("unknown chat", func(t *testing.T) {
+		resp := server.request(http.MethodGet, "/api/chats/"+newUserId(), "", nil)
+		requireProblem(t, resp, http.StatusNotFound, "ChatNotFound")
+	})
+
+	t.Run("unknown invitation", func(t *testing.T) {
+		resp := server.request(http.MethodPost, "/api/invitations/"+newUserId()+"/join", token(t, bob), nil)
+		requireProblem(t, resp, http.StatusNotFound, "InvitationNotFound")
+	})
+
+	t.Run("revoking an invitation twice", func(t *testing.T) {
+		resp := server.request(http.MethodPost, "/api/chats/"+chatId+"/invitations", token(t, alice), map[string]any{})
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		invitationId := decode[domain.ChatInvitation](t, resp).Id
+
+		resp = server.request(http.MethodDelete, "/api/chats/"+chatId+"/invitations/"+invitationId, token(t, alice), nil)
+		require.Equal(t, http.StatusNoContent, resp.StatusCode)
+		resp = server.request(http.MethodDelete, "/api/chats/"+chatId+"/invitations/"+invitationId, token(t, alice), nil)
+		requireProblem(t, resp, http.StatusNotFound, "InvitationNotFound")
+	})
+
+	t.Run("revoking an unknown invitation", func(t *testing.T) {
+		resp := server.request(http.MethodDelete, "/api/chats/"+chatId+"/invitations/"+newUserId(), token(t, alice), nil)
+		requireProblem(t, resp, http.StatusNotFound, "InvitationNotFound")
+	})
+
+	t.Run("restoring a chat that is not deleted", func(t *testing.T) {
+		resp := server.request(http.MethodPost, "/api/chats/"+chatId+"/restore", token(t, alice), nil)
+		requireProblem(t, resp, http.StatusNotFound, "DeletedChatNotFound")
+	})
+
+	t.Run("history requires a token", func(t *testing.T) {
+		resp := server.request(http.MethodGet, "/api/chats/"+chatId+"/messages", "", nil)
+		requireProblem(t, resp, http.StatusUnauthorized, "AuthenticationFailure")
+	})
+
+	t.Run("only admins update the chat", func(t *testing.T) {
+		resp := server.request(http.MethodPatch, "/api/chats/"+chatId, token(t, bob), map[string]any{"name": "renamed"})
+		requireProblem(t, resp, http.StatusForbidden, "ChatAdminRequired")
+	})
+
+	t.Run("archived chats are read-only", func(t *testing.T) {
+		resp := server.request(http.MethodPost, "/api/chats/"+chatId+"/archive", token(t, alice), nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		resp = server.request(http.MethodPatch, "/api/chats/"+chatId, token(t, alice), map[string]any{"name": "renamed"})
+		requireProblem(t, resp, http.StatusConflict, "ChatArchived")
+	})
+}
+
+func TestChatAPI_UpdateNotifiesConnectedMembers(t *testing.T) {
+	server := newTestServer(t)
+	alice, bob := newUserId(), newUserId()
+	chatId := server.createChat(alice, bob)
+	bobClient := server.connect(chatId, bob)
+
+	resp := server.request(http.MethodPatch, "/api/chats/"+chatId, token(t, alice), map[string]any{"name": "Project"})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	frame := bobClient.next()
+	assert.Equal(t, "chat.updated", frame.Event)
+	assert.Contains(t, string(frame.Data), `"name":"Project"`)
+}
+
+// failingMessageRepo fails storing messages while failing is set.
+type failingMessageRepo struct {
+	messageRepository.MessageRepository
+	failing atomic.Bool
+}
+
+func (r *failingMessageRepo) Create(ctx context.Context, message entity.Message) error {
+	if r.failing.Load() {
+		return errors.New("message store unavailable")
+	}
+	return r.MessageRepository.Create(ctx, message)
+}
+
+func TestChatAPI_MembershipChangesRollBackWithTheSystemMessage(t *testing.T) {
+	chats, messages, unitOfWork, database := newRepositories(t)
+	failing := &failingMessageRepo{MessageRepository: messages}
+	server := startTestServer(t, chats, failing, unitOfWork, database)
+	alice, bob, carol := newUserId(), newUserId(), newUserId()
+	chatId := server.createChat(alice, bob, carol)
+	failing.failing.Store(true)
+
+	t.Run("creating a chat", func(t *testing.T) {
+		groupId := int(time.Now().UnixNano() % 1_000_000_000)
+		resp := server.request(http.MethodPost, "/api/chats", "", map[string]any{
+			"type": "group", "usergroup_id": groupId, "user_id": alice,
+		})
+		require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+
+		resp = server.request(http.MethodGet, "/api/user-groups/"+strconv.Itoa(groupId)+"/chat", "", nil)
+		requireProblem(t, resp, http.StatusNotFound, "ChatNotFound")
+	})
+
+	t.Run("leaving", func(t *testing.T) {
+		resp := server.request(http.MethodPost, "/api/chats/"+chatId+"/leave", token(t, bob), nil)
+		require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	})
+
+	t.Run("removing a member", func(t *testing.T) {
+		resp := server.request(http.MethodDelete, "/api/chats/"+chatId+"/chat-participants/"+carol, token(t, alice), nil)
+		require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	})
+
+	resp := server.request(http.MethodGet, "/api/chats/"+chatId+"/chat-participants", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	body := decode[struct {
+		Participants []domain.ChatParticipant `json:"participants"`
+	}](t, resp)
+	statuses := make(map[string]domain.ParticipantStatus)
+	for _, participant := range body.Participants {
+		statuses[participant.UserId] = participant.Status
+	}
+	assert.Equal(t, map[string]domain.ParticipantStatus{
+		alice: domain.StatusActive, bob: domain.StatusActive, carol: domain.StatusActive,
+	}, statuses)
+}
+
+func TestChatAPI_LeaveAndRejoin(t *testing.T) {
+	server := newTestServer(t)
+	alice, bob := newUserId(), newUserId()
+	chatId := server.createChat(alice, bob)
+	aliceClient := server.connect(chatId, alice)
+
+	history := func(userId string) []string {
+		t.Helper()
+		resp := server.request(http.MethodGet, "/api/chats/"+chatId+"/messages", token(t, userId), nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		contents := make([]string, 0)
+		for _, message := range decode[struct {
+			Messages []entity.Message `json:"messages"`
+		}](t, resp).Messages {
+			contents = append(contents, message.Content)
+		}
+		return contents
+	}
+
+	aliceClient.send(map[string]any{"content": "before"})
+	aliceClient.nextMessage()
+	resp := server.request(http.MethodPost, "/api/chats/"+chatId+"/leave", token(t, bob), nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	aliceClient.nextMessage()
+	aliceClient.send(map[string]any{"content": "while away"})
+	aliceClient.nextMessage()
+
+	assert.Contains(t, history(bob), "before")
+	assert.NotContains(t, history(bob), "while away")
+
+	// Rejoining makes the messages posted while away readable again, like the history before a member joined.
+	resp = server.request(http.MethodPost, "/api/chats/"+chatId+"/rejoin", token(t, bob), nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, history(bob), "while away")
+}
+
+func TestChatAPI_RemovedMembersCannotComeBackByThemselves(t *testing.T) {
+	server := newTestServer(t)
+	alice, bob := newUserId(), newUserId()
+	chatId := server.createChat(alice, bob)
+
+	resp := server.request(http.MethodDelete, "/api/chats/"+chatId+"/chat-participants/"+bob, token(t, alice), nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp = server.request(http.MethodDelete, "/api/chats/"+chatId+"/chat-participants/"+bob, token(t, alice), nil)
+	requireProblem(t, resp, http.StatusNotFound, "ParticipantNotFound")
+
+	resp = server.request(http.MethodGet, "/api/chats/"+chatId+"/messages", token(t, bob), nil)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+	resp = server.request(http.MethodPost, "/api/chats/"+chatId+"/rejoin", token(t, bob), nil)
+	requireProblem(t, resp, http.StatusConflict, "CannotRejoinChat")
+	resp = server.request(http.MethodPost, "/api/chats/"+chatId+"/join-requests", token(t, bob), nil)
+	requireProblem(t, resp, http.StatusForbidden, "UserRemoved")
+
+	participantStatus := func() domain.ParticipantStatus {
+		t.Helper()
+		resp := server.request(http.MethodGet, "/api/chats/"+chatId+"/chat-participants", "", nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		for _, participant := range decode[struct {
+			Participants []domain.ChatParticipant `json:"participants"`
+		}](t, resp).Participants {
+			if participant.UserId == bob {
+				return participant.Status
+			}
+		}
+		return ""
+	}
+	assert.Equal(t, domain.StatusRemoved, participantStatus(), "the participant is kept")
+
+	resp = server.request(http.MethodPost, "/api/chats/"+chatId+"/chat-participants", token(t, alice),
+		map[string]any{"participants": []map[string]string{{"user_id": bob}}})
+	require.Less(t, resp.StatusCode, 300)
+	assert.Equal(t, domain.StatusActive, participantStatus(), "admins can add them again")
+}
+
+func TestChatAPI_AddingParticipantsRequiresPermission(t *testing.T) {
+	server := newTestServer(t)
+	alice, bob, carol := newUserId(), newUserId(), newUserId()
+	chatId := server.createChat(alice, bob)
+	add := func(bearer, userId, role string) *http.Response {
+		return server.request(http.MethodPost, "/api/chats/"+chatId+"/chat-participants", bearer,
+			map[string]any{"participants": []map[string]string{{"user_id": userId, "role": role}}})
+	}
+
+	t.Run("anonymous callers", func(t *testing.T) {
+		requireProblem(t, add("", carol, "admin"), http.StatusUnauthorized, "AuthenticationFailure")
+	})
+
+	t.Run("users outside the chat", func(t *testing.T) {
+		requireProblem(t, add(token(t, carol), carol, "admin"), http.StatusForbidden, "NotChatParticipant")
+	})
+
+	t.Run("members while only admins may invite", func(t *testing.T) {
+		requireProblem(t, add(token(t, bob), carol, "member"), http.StatusForbidden, "InvitePermissionRequired")
+	})
+
+	t.Run("members cannot add admins", func(t *testing.T) {
+		resp := server.request(http.MethodPatch, "/api/chats/"+chatId, token(t, alice),
+			map[string]any{"settings": map[string]any{"who_can_invite": "members"}})
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		resp = add(token(t, bob), carol, "admin")
+		require.Less(t, resp.StatusCode, 300)
+		body := decode[struct {
+			Results []struct {
+				Result string `json:"result"`
+			} `json:"results"`
+		}](t, resp)
+		require.Len(t, body.Results, 1)
+		assert.Equal(t, "invalid", body.Results[0].Result)
+
+		resp = server.request(http.MethodGet, "/api/chats/"+chatId+"/chat-participants", "", nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		for _, participant := range decode[struct {
+			Participants []domain.ChatParticipant `json:"participants"`
+		}](t, resp).Participants {
+			assert.NotEqual(t, carol, participant.UserId)
+		}
+	})
+}
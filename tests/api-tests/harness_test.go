@@ -0,0 +1,249 @@
+package api_tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/HappYness-Project/ChatBackendServer/api"
+	"github.com/HappYness-Project/ChatBackendServer/common"
+	"github.com/HappYness-Project/ChatBackendServer/configs"
+	"github.com/HappYness-Project/ChatBackendServer/dbs"
+	chatRepository "github.com/HappYness-Project/ChatBackendServer/internal/chat/repository"
+	messageRepository "github.com/HappYness-Project/ChatBackendServer/internal/message/repository"
+	"github.com/HappYness-Project/ChatBackendServer/internal/storage"
+	"github.com/HappYness-Project/ChatBackendServer/loggers"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testSecret        = "api-tests-access-token-secret"
+	testWebhookSecret = "api-tests-webhook-secret"
+	// receiveTimeout bounds how long a client waits for a frame it expects, silenceTimeout how long it
+	// listens to make sure nothing arrives.
+	receiveTimeout = 5 * time.Second
+	silenceTimeout = 300 * time.Millisecond
+)
+
+// testServer runs ApiServer.Setup in process. It uses the in-memory repositories unless TEST_DATABASE_URL
+// points at a Postgres database, which is migrated before use.
+type testServer struct {
+	*httptest.Server
+	t *testing.T
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	chats, messages, unitOfWork, database := newRepositories(t)
+	return startTestServer(t, chats, messages, unitOfWork, database)
+}
+
+func startTestServer(t *testing.T, chats chatRepository.ChatRepository, messages messageRepository.MessageRepository,
+	unitOfWork dbs.UnitOfWork, database api.DatabaseMonitor) *testServer {
+	t.Helper()
+	blobStore, err := storage.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	logger := loggers.Setup(configs.Env{LogLevel: "error"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	server := api.NewApiServer("", testSecret, testWebhookSecret, chats, messages, unitOfWork, database, blobStore, nil, receiveTimeout, logger)
+	httpServer := httptest.NewServer(server.Setup(ctx))
+	t.Cleanup(func() {
+		cancel()
+		httpServer.Close()
+	})
+	return &testServer{Server: httpServer, t: t}
+}
+
+func newRepositories(t *testing.T) (chatRepository.ChatRepository, messageRepository.MessageRepository, dbs.UnitOfWork,
+	api.DatabaseMonitor) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		chats := chatRepository.NewMemoryRepository()
+		messages := messageRepository.NewMemoryRepository(chats)
+		return chats, messages, dbs.NewMemoryUnitOfWork(chats, messages), nil
+	}
+
+	db, err := dbs.ConnectToDb(dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	migrator, err := dbs.NewMigrator(db)
+	require.NoError(t, err)
+	require.NoError(t, migrator.Up(t.Context()))
+	return chatRepository.NewRepository(db), messageRepository.NewRepository(db), dbs.NewUnitOfWork(db), dbs.NewMonitor(db, migrator)
+}
+
+// token mints an access token for the user, signed like the ones of the auth service.
+func token(t *testing.T, userId string) string {
+	return signToken(t, jwt.MapClaims{"user_id": userId, "exp": time.Now().Add(time.Hour).Unix()}, testSecret)
+}
+
+func signToken(t *testing.T, claims jwt.MapClaims, secret string) string {
+	t.Helper()
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS512, claims).SignedString([]byte(secret))
+	require.NoError(t, err)
+	return signed
+}
+
+func newUserId() string {
+	return uuid.New().String()
+}
+
+// request sends body as JSON, with the bearer token when one is given.
+func (s *testServer) request(method, path, bearer string, body any) *http.Response {
+	s.t.Helper()
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		require.NoError(s.t, err)
+		reader = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequest(method, s.URL+path, reader)
+	require.NoError(s.t, err)
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+	resp, err := s.Client().Do(req)
+	require.NoError(s.t, err)
+	s.t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func decode[T any](t *testing.T, resp *http.Response) T {
+	t.Helper()
+	var value T
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&value))
+	return value
+}
+
+// requireProblem checks that the response is a problem details document with the status and error code.
+func requireProblem(t *testing.T, resp *http.Response, status int, errorCode string) {
+	t.Helper()
+	require.Equal(t, status, resp.StatusCode)
+	require.Equal(t, "application/problem+json", resp.Header.Get("Content-Type"))
+	require.Equal(t, errorCode, decode[common.ProblemDetails](t, resp).ErrorCode)
+}
+
+// createChat creates a private chat administered by adminId, with the members added to it.
+func (s *testServer) createChat(adminId string, memberIds ...string) string {
+	s.t.Helper()
+	resp := s.request(http.MethodPost, "/api/chats", "", map[string]any{"type": "private", "user_id": adminId})
+	require.Equal(s.t, http.StatusCreated, resp.StatusCode)
+	chatId := decode[struct {
+		Id string `json:"id"`
+	}](s.t, resp).Id
+
+	if len(memberIds) > 0 {
+		participants := make([]map[string]string, 0, len(memberIds))
+		for _, memberId := range memberIds {
+			participants = append(participants, map[string]string{"user_id": memberId})
+		}
+		resp = s.request(http.MethodPost, "/api/chats/"+chatId+"/chat-participants", token(s.t, adminId),
+			map[string]any{"participants": participants})
+		require.Less(s.t, resp.StatusCode, 300)
+	}
+	return chatId
+}
+
+// socketFrame holds what a client receives: a chat message, or an event when Event is set.
+type socketFrame struct {
+	Event    string          `json:"event"`
+	ChatId   string          `json:"chat_id"`
+	Data     json.RawMessage `json:"data"`
+	Id       string          `json:"id"`
+	SenderId string          `json:"sender_id"`
+	Content  string          `json:"content"`
+}
+
+type wsClient struct {
+	t    *testing.T
+	conn *websocket.Conn
+}
+
+// dial opens a websocket on path, passing the token as query parameter like browser clients do.
+func (s *testServer) dial(path, bearer string) (*wsClient, *http.Response, error) {
+	url := "ws" + strings.TrimPrefix(s.URL, "http") + path
+	if bearer != "" {
+		url += "?token=" + bearer
+	}
+	conn, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		return nil, resp, err
+	}
+	s.t.Cleanup(func() { conn.Close() })
+	return &wsClient{t: s.t, conn: conn}, resp, nil
+}
+
+// connect joins the user to the chat socket and waits until the server registered the connection. The server
+// answers an empty message from its read loop, which only starts once the client receives broadcasts.
+func (s *testServer) connect(chatId, userId string) *wsClient {
+	s.t.Helper()
+	client, _, err := s.dial("/api/chats/"+chatId+"/ws", token(s.t, userId))
+	require.NoError(s.t, err)
+	client.send(map[string]any{"content": ""})
+	frame := client.next()
+	require.Equal(s.t, "message.rejected", frame.Event)
+	return client
+}
+
+func (c *wsClient) send(message any) {
+	c.t.Helper()
+	require.NoError(c.t, c.conn.WriteJSON(message))
+}
+
+func (c *wsClient) next() socketFrame {
+	c.t.Helper()
+	require.NoError(c.t, c.conn.SetReadDeadline(time.Now().Add(receiveTimeout)))
+	var frame socketFrame
+	require.NoError(c.t, c.conn.ReadJSON(&frame))
+	return frame
+}
+
+// nextMessage skips events until a chat message arrives.
+func (c *wsClient) nextMessage() socketFrame {
+	c.t.Helper()
+	for {
+		if frame := c.next(); frame.Event == "" {
+			return frame
+		}
+	}
+}
+
+// expectSilence fails when a frame arrives within silenceTimeout. A timed out connection cannot be read
+// again, so this has to be the last read of the client.
+func (c *wsClient) expectSilence() {
+	c.t.Helper()
+	require.NoError(c.t, c.conn.SetReadDeadline(time.Now().Add(silenceTimeout)))
+	var frame socketFrame
+	err := c.conn.ReadJSON(&frame)
+	require.Error(c.t, err, "unexpected frame %+v", frame)
+	var netErr interface{ Timeout() bool }
+	require.ErrorAs(c.t, err, &netErr)
+	require.True(c.t, netErr.Timeout(), "connection failed instead of staying silent: %v", err)
+}
+
+// expectClosed reads until the server closes the connection.
+func (c *wsClient) expectClosed() {
+	c.t.Helper()
+	require.NoError(c.t, c.conn.SetReadDeadline(time.Now().Add(receiveTimeout)))
+	for {
+		if _, _, err := c.conn.ReadMessage(); err != nil {
+			var netErr interface{ Timeout() bool }
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				c.t.Fatalf("connection was not closed: %v", err)
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package api_tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/HappYness-Project/ChatBackendServer/api"
+	"github.com/HappYness-Project/ChatBackendServer/buildinfo"
+	"github.com/HappYness-Project/ChatBackendServer/configs"
+	"github.com/HappYness-Project/ChatBackendServer/dbs"
+	messageRoute "github.com/HappYness-Project/ChatBackendServer/internal/message/route"
+	"github.com/HappYness-Project/ChatBackendServer/loggers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type readiness struct {
+	Status   string         `json:"status"`
+	Build    buildinfo.Info `json:"build"`
+	Database struct {
+		Status           string `json:"status"`
+		Error            string `json:"error"`
+		Store            string `json:"store"`
+		MigrationVersion *int64 `json:"migration_version"`
+	} `json:"database"`
+	Broadcaster struct {
+		Status string `json:"status"`
+	} `json:"broadcaster"`
+	Connections messageRoute.ConnectionStats `json:"connections"`
+}
+
+func TestHealthAPI(t *testing.T) {
+	server := newTestServer(t)
+
+	t.Run("home reports the api version", func(t *testing.T) {
+		resp := server.request(http.MethodGet, "/", "", nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		home := decode[struct {
+			Status  string `json:"status"`
+			Version string `json:"version"`
+		}](t, resp)
+		assert.Equal(t, "active", home.Status)
+		assert.Equal(t, "1.0.0", home.Version)
+	})
+
+	t.Run("health reports the pool of the database", func(t *testing.T) {
+		resp := server.request(http.MethodGet, "/health", "", nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		health := decode[struct {
+			Status       string         `json:"status"`
+			Version      string         `json:"version"`
+			DatabasePool *dbs.PoolStats `json:"database_pool"`
+		}](t, resp)
+		assert.Equal(t, "active", health.Status)
+		assert.Equal(t, buildinfo.Version, health.Version)
+
+		// The in-memory store has no connection pool to report.
+		if os.Getenv("TEST_DATABASE_URL") == "" {
+			assert.Nil(t, health.DatabasePool)
+			return
+		}
+		require.NotNil(t, health.DatabasePool)
+		assert.Positive(t, health.DatabasePool.MaxOpenConnections)
+	})
+
+	t.Run("liveness reports the build", func(t *testing.T) {
+		resp := server.request(http.MethodGet, "/healthz", "", nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		liveness := decode[struct {
+			Status string         `json:"status"`
+			Build  buildinfo.Info `json:"build"`
+		}](t, resp)
+		assert.Equal(t, "alive", liveness.Status)
+		assert.Equal(t, buildinfo.Get(), liveness.Build)
+	})
+
+	t.Run("readiness counts the connections", func(t *testing.T) {
+		alice := newUserId()
+		chatId := server.createChat(alice)
+		server.connect(chatId, alice)
+
+		resp := server.request(http.MethodGet, "/readyz", "", nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		report := decode[readiness](t, resp)
+		assert.Equal(t, "ready", report.Status)
+		assert.Equal(t, "up", report.Database.Status)
+		assert.Equal(t, "up", report.Broadcaster.Status)
+		assert.Equal(t, 1, report.Connections.Connections)
+		assert.Equal(t, 1, report.Connections.Chats)
+	})
+}
+
+type fakeDatabase struct {
+	pingErr         error
+	applied, latest int64
+}
+
+func (d fakeDatabase) Ping(context.Context) error { return d.pingErr }
+
+func (d fakeDatabase) SchemaVersion(context.Context) (int64, int64, error) {
+	return d.applied, d.latest, nil
+}
+
+func (d fakeDatabase) PoolStats() dbs.PoolStats { return dbs.PoolStats{} }
+
+type fakeBroadcaster bool
+
+func (b fakeBroadcaster) Broadcasting() bool { return bool(b) }
+
+type noConnections struct{}
+
+func (noConnections) Stats() messageRoute.ConnectionStats { return messageRoute.ConnectionStats{} }
+
+func TestReadiness(t *testing.T) {
+	logger := loggers.Setup(configs.Env{LogLevel: "error"})
+	probe := func(database api.DatabaseMonitor, broadcasting bool) (int, readiness) {
+		server := api.NewApiServer("", testSecret, testWebhookSecret, nil, nil, nil, database, nil, nil, receiveTimeout, logger)
+		recorder := httptest.NewRecorder()
+		server.Readiness(fakeBroadcaster(broadcasting), noConnections{}).ServeHTTP(recorder,
+			httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		return recorder.Code, decode[readiness](t, recorder.Result())
+	}
+
+	t.Run("ready with a migrated database", func(t *testing.T) {
+		status, report := probe(fakeDatabase{applied: 12, latest: 12}, true)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "postgres", report.Database.Store)
+		require.NotNil(t, report.Database.MigrationVersion)
+		assert.Equal(t, int64(12), *report.Database.MigrationVersion)
+	})
+
+	t.Run("not ready when the database is unreachable", func(t *testing.T) {
+		status, report := probe(fakeDatabase{pingErr: errors.New("connection refused")}, true)
+		assert.Equal(t, http.StatusServiceUnavailable, status)
+		assert.Equal(t, "not ready", report.Status)
+		assert.Equal(t, "down", report.Database.Status)
+		// The cause is logged, not reported.
+		assert.NotContains(t, report.Database.Error, "connection refused")
+	})
+
+	t.Run("not ready with pending migrations", func(t *testing.T) {
+		status, report := probe(fakeDatabase{applied: 11, latest: 12}, true)
+		assert.Equal(t, http.StatusServiceUnavailable, status)
+		assert.Equal(t, "the database has pending migrations", report.Database.Error)
+	})
+
+	t.Run("not ready when the broadcaster stopped", func(t *testing.T) {
+		status, report := probe(nil, false)
+		assert.Equal(t, http.StatusServiceUnavailable, status)
+		assert.Equal(t, "memory", report.Database.Store)
+		assert.Equal(t, "down", report.Broadcaster.Status)
+	})
+}
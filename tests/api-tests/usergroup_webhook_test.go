@@ -0,0 +1,159 @@
+package api_tests
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/HappYness-Project/ChatBackendServer/internal/chat/domain"
+	chatRoute "github.com/HappYness-Project/ChatBackendServer/internal/chat/route"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// deliver sends the user group event signed like the main application does and returns the reported status.
+func (s *testServer) deliver(event map[string]any) string {
+	s.t.Helper()
+	resp := s.post(event)
+	require.Equal(s.t, http.StatusOK, resp.StatusCode)
+	return decode[struct {
+		Status string `json:"status"`
+	}](s.t, resp).Status
+}
+
+// post sends the user group event signed like the main application does.
+func (s *testServer) post(event map[string]any) *http.Response {
+	s.t.Helper()
+	body, err := json.Marshal(event)
+	require.NoError(s.t, err)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	signature := chatRoute.SignWebhookPayload([]byte(testWebhookSecret), timestamp, body)
+
+	req, err := http.NewRequest(http.MethodPost, s.URL+"/api/webhooks/user-groups", bytes.NewReader(body))
+	require.NoError(s.t, err)
+	req.Header.Set(chatRoute.WebhookTimestampHeader, timestamp)
+	req.Header.Set(chatRoute.WebhookSignatureHeader, "sha256="+hex.EncodeToString(signature))
+	resp, err := s.Client().Do(req)
+	require.NoError(s.t, err)
+	return resp
+}
+
+// groupMembers returns the status of every participant of the group chat, nil when the group has no chat.
+func (s *testServer) groupMembers(groupId int) map[string]domain.ParticipantStatus {
+	s.t.Helper()
+	resp := s.request(http.MethodGet, "/api/user-groups/"+strconv.Itoa(groupId)+"/chat", "", nil)
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil
+	}
+	require.Equal(s.t, http.StatusOK, resp.StatusCode)
+	chatId := decode[domain.Chat](s.t, resp).Id
+
+	resp = s.request(http.MethodGet, "/api/chats/"+chatId+"/chat-participants", "", nil)
+	require.Equal(s.t, http.StatusOK, resp.StatusCode)
+	statuses := make(map[string]domain.ParticipantStatus)
+	for _, participant := range decode[struct {
+		Participants []domain.ChatParticipant `json:"participants"`
+	}](s.t, resp).Participants {
+		statuses[participant.UserId] = participant.Status
+	}
+	return statuses
+}
+
+func TestUserGroupWebhook_EventsOutOfOrder(t *testing.T) {
+	server := newTestServer(t)
+	alice, bob := newUserId(), newUserId()
+	base := time.Now().UTC().Add(-time.Hour)
+	nextGroupId := int(time.Now().UnixNano() % 1_000_000_000)
+	event := func(eventType domain.UserGroupEventType, groupId int, occurredAt time.Time, fields map[string]any) map[string]any {
+		event := map[string]any{
+			"event_id":     newUserId(),
+			"type":         eventType,
+			"usergroup_id": groupId,
+			"occurred_at":  occurredAt,
+		}
+		for key, value := range fields {
+			event[key] = value
+		}
+		return event
+	}
+
+	t.Run("redelivered events are applied once", func(t *testing.T) {
+		groupId := nextGroupId
+		created := event(domain.UserGroupCreated, groupId, base, map[string]any{"owner_id": alice, "member_ids": []string{bob}})
+		assert.Equal(t, "processed", server.deliver(created))
+		removed := event(domain.UserGroupMemberRemoved, groupId, base.Add(time.Minute), map[string]any{"user_id": bob})
+		assert.Equal(t, "processed", server.deliver(removed))
+
+		assert.Equal(t, "duplicate", server.deliver(created))
+		assert.Equal(t, map[string]domain.ParticipantStatus{alice: domain.StatusActive, bob: domain.StatusRemoved},
+			server.groupMembers(groupId))
+	})
+
+	t.Run("a member event older than the last one of the member is ignored", func(t *testing.T) {
+		groupId := nextGroupId + 1
+		assert.Equal(t, "processed", server.deliver(event(domain.UserGroupCreated, groupId, base,
+			map[string]any{"owner_id": alice})))
+		assert.Equal(t, "processed", server.deliver(event(domain.UserGroupMemberRemoved, groupId, base.Add(2*time.Minute),
+			map[string]any{"user_id": bob})))
+		assert.Equal(t, "stale", server.deliver(event(domain.UserGroupMemberAdded, groupId, base.Add(time.Minute),
+			map[string]any{"user_id": bob})))
+
+		assert.Equal(t, map[string]domain.ParticipantStatus{alice: domain.StatusActive}, server.groupMembers(groupId))
+	})
+
+	t.Run("a late creation keeps newer member changes", func(t *testing.T) {
+		groupId := nextGroupId + 2
+		assert.Equal(t, "processed", server.deliver(event(domain.UserGroupMemberRemoved, groupId, base.Add(time.Minute),
+			map[string]any{"user_id": bob})))
+		assert.Equal(t, "processed", server.deliver(event(domain.UserGroupCreated, groupId, base,
+			map[string]any{"owner_id": alice, "member_ids": []string{bob}})))
+
+		assert.Equal(t, map[string]domain.ParticipantStatus{alice: domain.StatusActive}, server.groupMembers(groupId))
+	})
+
+	t.Run("a group event older than the deletion is ignored", func(t *testing.T) {
+		groupId := nextGroupId + 3
+		assert.Equal(t, "processed", server.deliver(event(domain.UserGroupCreated, groupId, base,
+			map[string]any{"owner_id": alice})))
+		assert.Equal(t, "processed", server.deliver(event(domain.UserGroupDeleted, groupId, base.Add(2*time.Minute), nil)))
+		assert.Equal(t, "stale", server.deliver(event(domain.UserGroupCreated, groupId, base.Add(time.Minute),
+			map[string]any{"owner_id": alice})))
+		assert.Equal(t, "stale", server.deliver(event(domain.UserGroupMemberAdded, groupId, base.Add(time.Minute),
+			map[string]any{"user_id": bob})))
+
+		assert.Nil(t, server.groupMembers(groupId))
+	})
+}
+
+func TestUserGroupWebhook_RejectsInvalidUserIds(t *testing.T) {
+	server := newTestServer(t)
+	groupId := int(time.Now().UnixNano()%1_000_000_000) + 10
+	event := func(eventType domain.UserGroupEventType, fields map[string]any) map[string]any {
+		event := map[string]any{
+			"event_id":     newUserId(),
+			"type":         eventType,
+			"usergroup_id": groupId,
+			"occurred_at":  time.Now().UTC(),
+		}
+		for key, value := range fields {
+			event[key] = value
+		}
+		return event
+	}
+
+	for name, invalid := range map[string]map[string]any{
+		"owner_id":   event(domain.UserGroupCreated, map[string]any{"owner_id": "alice"}),
+		"member_ids": event(domain.UserGroupCreated, map[string]any{"owner_id": newUserId(), "member_ids": []string{"bob"}}),
+		"user_id":    event(domain.UserGroupMemberAdded, map[string]any{"user_id": "bob"}),
+	} {
+		t.Run(name, func(t *testing.T) {
+			requireProblem(t, server.post(invalid), http.StatusBadRequest, "InvalidUserGroupEvent")
+		})
+	}
+	assert.Nil(t, server.groupMembers(groupId))
+}
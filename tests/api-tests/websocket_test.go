@@ -0,0 +1,166 @@
+package api_tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/HappYness-Project/ChatBackendServer/common"
+	entity "github.com/HappYness-Project/ChatBackendServer/internal/message/domain"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebSocket_DeliversToChatParticipants(t *testing.T) {
+	server := newTestServer(t)
+	alice, bob, carol := newUserId(), newUserId(), newUserId()
+	chatId := server.createChat(alice, bob)
+	otherChatId := server.createChat(carol)
+
+	aliceClient := server.connect(chatId, alice)
+	bobClient := server.connect(chatId, bob)
+	carolClient := server.connect(otherChatId, carol)
+
+	aliceClient.send(map[string]any{"content": "hello bob", "sender_id": carol, "chat_id": otherChatId})
+
+	received := bobClient.nextMessage()
+	assert.NotEmpty(t, received.Id)
+	assert.Equal(t, chatId, received.ChatId)
+	assert.Equal(t, alice, received.SenderId, "the sender is taken from the token")
+	assert.Equal(t, "hello bob", received.Content)
+	assert.Equal(t, received.Id, aliceClient.nextMessage().Id, "the sender receives its own message")
+	carolClient.expectSilence()
+
+	resp := server.request(http.MethodGet, "/api/chats/"+chatId+"/messages", token(t, bob), nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	history := decode[struct {
+		Messages []entity.Message `json:"messages"`
+		Count    int              `json:"count"`
+	}](t, resp)
+	require.Equal(t, 2, history.Count, "the message and the notice that the chat was created")
+	ids := make(map[string]string)
+	for _, message := range history.Messages {
+		ids[message.MessageType] = message.ID
+	}
+	assert.Equal(t, received.Id, ids[entity.TypeText])
+	assert.Contains(t, ids, entity.TypeSystem)
+}
+
+func TestWebSocket_ConcurrentClients(t *testing.T) {
+	server := newTestServer(t)
+	users := []string{newUserId(), newUserId(), newUserId(), newUserId()}
+	chatId := server.createChat(users[0], users[1:]...)
+
+	clients := make([]*wsClient, len(users))
+	for i, user := range users {
+		clients[i] = server.connect(chatId, user)
+	}
+	for _, client := range clients {
+		client.send(map[string]any{"content": "hi"})
+	}
+
+	for _, client := range clients {
+		senders := make(map[string]bool)
+		for range users {
+			senders[client.nextMessage().SenderId] = true
+		}
+		assert.Len(t, senders, len(users), "every client receives one message from every sender")
+	}
+}
+
+func TestWebSocket_AuthenticationAndAccess(t *testing.T) {
+	server := newTestServer(t)
+	alice, outsider := newUserId(), newUserId()
+	chatId := server.createChat(alice)
+
+	tests := []struct {
+		name      string
+		path      string
+		token     string
+		status    int
+		errorCode string
+	}{
+		{"missing token", "/api/chats/" + chatId + "/ws", "", http.StatusUnauthorized, "AuthenticationFailure"},
+		{"token signed with another secret", "/api/chats/" + chatId + "/ws",
+			signToken(t, jwt.MapClaims{"user_id": alice}, "another-secret"), http.StatusUnauthorized, "AuthenticationFailure"},
+		{"expired token", "/api/chats/" + chatId + "/ws",
+			signToken(t, jwt.MapClaims{"user_id": alice, "exp": time.Now().Add(-time.Minute).Unix()}, testSecret),
+			http.StatusUnauthorized, "AuthenticationFailure"},
+		{"not a participant", "/api/chats/" + chatId + "/ws", token(t, outsider), http.StatusForbidden, "NotChatParticipant"},
+		{"unknown chat", "/api/chats/" + newUserId() + "/ws", token(t, alice), http.StatusNotFound, "ChatNotFound"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, resp, err := server.dial(tt.path, tt.token)
+			require.Error(t, err)
+			require.NotNil(t, resp)
+			defer resp.Body.Close()
+			requireProblem(t, resp, tt.status, tt.errorCode)
+		})
+	}
+}
+
+func TestWebSocket_RejectsInvalidMessages(t *testing.T) {
+	server := newTestServer(t)
+	alice, bob := newUserId(), newUserId()
+	chatId := server.createChat(alice, bob)
+	aliceClient := server.connect(chatId, alice)
+	bobClient := server.connect(chatId, bob)
+
+	aliceClient.send(map[string]any{"content": "bad", "message_type": "hologram"})
+	aliceClient.send(map[string]any{"content": "reply", "reply_to_id": newUserId()})
+	aliceClient.send(map[string]any{"content": "reply", "reply_to_id": "not-a-uuid"})
+	aliceClient.send(map[string]any{"content": "file", "attachment_ids": []string{newUserId()}})
+	aliceClient.send(map[string]any{"content": "file", "attachment_ids": []string{"not-a-uuid"}})
+
+	for _, errorCode := range []string{"InvalidMessageType", "InvalidReplyTarget", "InvalidReplyTarget", "InvalidAttachment",
+		"InvalidAttachment"} {
+		frame := aliceClient.next()
+		require.Equal(t, "message.rejected", frame.Event)
+		var problem common.ProblemDetails
+		require.NoError(t, json.Unmarshal(frame.Data, &problem))
+		assert.Equal(t, errorCode, problem.ErrorCode)
+	}
+	bobClient.expectSilence()
+}
+
+func TestWebSocket_ChatDeletionClosesConnections(t *testing.T) {
+	server := newTestServer(t)
+	alice, bob := newUserId(), newUserId()
+	chatId := server.createChat(alice, bob)
+	bobClient := server.connect(chatId, bob)
+
+	resp := server.request(http.MethodDelete, "/api/chats/"+chatId, token(t, alice), nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	frame := bobClient.next()
+	assert.Equal(t, "chat.deleted", frame.Event)
+	assert.Equal(t, chatId, frame.ChatId)
+	bobClient.expectClosed()
+
+	_, resp, err := server.dial("/api/chats/"+chatId+"/ws", token(t, bob))
+	require.Error(t, err)
+	defer resp.Body.Close()
+	requireProblem(t, resp, http.StatusNotFound, "ChatNotFound")
+}
+
+func TestWebSocket_LeavingDisconnectsOnlyThatUser(t *testing.T) {
+	server := newTestServer(t)
+	alice, bob := newUserId(), newUserId()
+	chatId := server.createChat(alice, bob)
+	aliceClient := server.connect(chatId, alice)
+	bobClient := server.connect(chatId, bob)
+
+	resp := server.request(http.MethodPost, "/api/chats/"+chatId+"/leave", token(t, bob), nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	bobClient.expectClosed()
+
+	notice := aliceClient.nextMessage()
+	assert.Equal(t, entity.NoticeMemberLeft, notice.Content)
+	assert.Equal(t, bob, notice.SenderId)
+
+	aliceClient.send(map[string]any{"content": "still here"})
+	assert.Equal(t, "still here", aliceClient.nextMessage().Content)
+}
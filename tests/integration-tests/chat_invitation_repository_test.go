@@ -0,0 +1,164 @@
+package integration_tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/HappYness-Project/ChatBackendServer/internal/chat/domain"
+	"github.com/HappYness-Project/ChatBackendServer/internal/chat/repository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatRepository_Invitations(t *testing.T) {
+	repo := repository.NewRepository(testDB)
+
+	userGroupID := 700
+	chat, err := domain.NewChat(domain.ChatTypeGroup, &userGroupID, nil)
+	require.NoError(t, err)
+	createdChat, err := repo.CreateChat(t.Context(), chat)
+	require.NoError(t, err)
+	defer func() {
+		_, _ = testDB.Exec(`DELETE FROM public.chat WHERE id = $1`, createdChat.Id)
+	}()
+
+	adminUUID, err := uuid.NewV7()
+	require.NoError(t, err)
+
+	t.Run("should create and find invitation by token", func(t *testing.T) {
+		expiresAt := time.Now().UTC().Add(time.Hour)
+		maxUses := 3
+		invitation, err := domain.NewChatInvitation(createdChat.Id, adminUUID.String(), &expiresAt, &maxUses)
+		require.NoError(t, err)
+
+		_, err = repo.CreateInvitation(t.Context(), invitation)
+		require.NoError(t, err)
+
+		found, err := repo.GetInvitationByToken(t.Context(), invitation.Token)
+		require.NoError(t, err)
+		assert.Equal(t, invitation.Id, found.Id)
+		assert.Equal(t, createdChat.Id, found.ChatId)
+		require.NotNil(t, found.MaxUses)
+		assert.Equal(t, 3, *found.MaxUses)
+		assert.Equal(t, 0, found.Uses)
+
+		invitations, err := repo.GetInvitationsByChatId(t.Context(), createdChat.Id)
+		require.NoError(t, err)
+		assert.NotEmpty(t, invitations)
+	})
+
+	t.Run("should return ErrInvitationNotFound for unknown token", func(t *testing.T) {
+		found, err := repo.GetInvitationByToken(t.Context(), "unknown-token")
+		require.ErrorIs(t, err, domain.ErrInvitationNotFound)
+		assert.Nil(t, found)
+	})
+
+	t.Run("should add pending participant and stop at max uses", func(t *testing.T) {
+		maxUses := 1
+		invitation, err := domain.NewChatInvitation(createdChat.Id, adminUUID.String(), nil, &maxUses)
+		require.NoError(t, err)
+		_, err = repo.CreateInvitation(t.Context(), invitation)
+		require.NoError(t, err)
+
+		firstUser, err := uuid.NewV7()
+		require.NoError(t, err)
+		participant, err := domain.NewChatParticipant(createdChat.Id, firstUser.String(), domain.RoleMember, domain.StatusPending)
+		require.NoError(t, err)
+
+		redeemed, err := repo.RedeemInvitation(t.Context(), invitation.Id, participant)
+		require.NoError(t, err)
+		assert.NotEmpty(t, redeemed.Id)
+
+		pending, err := repo.GetChatParticipantsByStatus(t.Context(), createdChat.Id, domain.StatusPending)
+		require.NoError(t, err)
+		require.Len(t, pending, 1)
+		assert.Equal(t, firstUser.String(), pending[0].UserId)
+
+		secondUser, err := uuid.NewV7()
+		require.NoError(t, err)
+		participant, err = domain.NewChatParticipant(createdChat.Id, secondUser.String(), domain.RoleMember, domain.StatusPending)
+		require.NoError(t, err)
+
+		_, err = repo.RedeemInvitation(t.Context(), invitation.Id, participant)
+		require.ErrorIs(t, err, domain.ErrInvitationExhausted)
+	})
+
+	t.Run("should not redeem for an existing participant", func(t *testing.T) {
+		invitation, err := domain.NewChatInvitation(createdChat.Id, adminUUID.String(), nil, nil)
+		require.NoError(t, err)
+		_, err = repo.CreateInvitation(t.Context(), invitation)
+		require.NoError(t, err)
+
+		userUUID, err := uuid.NewV7()
+		require.NoError(t, err)
+		participant, err := domain.NewChatParticipant(createdChat.Id, userUUID.String(), domain.RoleMember, domain.StatusPending)
+		require.NoError(t, err)
+		_, err = repo.AddParticipantToChat(t.Context(), participant)
+		require.NoError(t, err)
+
+		duplicate, err := domain.NewChatParticipant(createdChat.Id, userUUID.String(), domain.RoleMember, domain.StatusPending)
+		require.NoError(t, err)
+		_, err = repo.AddParticipantToChat(t.Context(), duplicate)
+		require.ErrorIs(t, err, domain.ErrAlreadyParticipant)
+
+		_, err = repo.RedeemInvitation(t.Context(), invitation.Id, duplicate)
+		require.ErrorIs(t, err, domain.ErrAlreadyParticipant)
+
+		found, err := repo.GetInvitationByToken(t.Context(), invitation.Token)
+		require.NoError(t, err)
+		assert.Equal(t, 0, found.Uses)
+	})
+
+	t.Run("should approve pending participant", func(t *testing.T) {
+		userUUID, err := uuid.NewV7()
+		require.NoError(t, err)
+		participant, err := domain.NewChatParticipant(createdChat.Id, userUUID.String(), domain.RoleMember, domain.StatusPending)
+		require.NoError(t, err)
+		_, err = repo.AddParticipantToChat(t.Context(), participant)
+		require.NoError(t, err)
+
+		err = repo.UpdateParticipantStatus(t.Context(), createdChat.Id, userUUID.String(), domain.StatusActive)
+		require.NoError(t, err)
+
+		found, err := repo.GetChatParticipant(t.Context(), createdChat.Id, userUUID.String())
+		require.NoError(t, err)
+		assert.Equal(t, domain.StatusActive, found.Status)
+	})
+
+	t.Run("should not redeem revoked invitation", func(t *testing.T) {
+		invitation, err := domain.NewChatInvitation(createdChat.Id, adminUUID.String(), nil, nil)
+		require.NoError(t, err)
+		_, err = repo.CreateInvitation(t.Context(), invitation)
+		require.NoError(t, err)
+
+		err = repo.RevokeInvitation(t.Context(), createdChat.Id, invitation.Id)
+		require.NoError(t, err)
+
+		found, err := repo.GetInvitationByToken(t.Context(), invitation.Token)
+		require.NoError(t, err)
+		assert.ErrorIs(t, found.CanBeRedeemed(time.Now().UTC()), domain.ErrInvitationRevoked)
+
+		err = repo.RevokeInvitation(t.Context(), createdChat.Id, invitation.Id)
+		assert.ErrorIs(t, err, domain.ErrInvitationNotFound, "already revoked")
+	})
+
+	t.Run("should not revoke unknown invitations or those of other chats", func(t *testing.T) {
+		invitation, err := domain.NewChatInvitation(createdChat.Id, adminUUID.String(), nil, nil)
+		require.NoError(t, err)
+		_, err = repo.CreateInvitation(t.Context(), invitation)
+		require.NoError(t, err)
+
+		unknownUUID, err := uuid.NewV7()
+		require.NoError(t, err)
+		err = repo.RevokeInvitation(t.Context(), createdChat.Id, unknownUUID.String())
+		assert.ErrorIs(t, err, domain.ErrInvitationNotFound)
+
+		err = repo.RevokeInvitation(t.Context(), unknownUUID.String(), invitation.Id)
+		assert.ErrorIs(t, err, domain.ErrInvitationNotFound)
+
+		found, err := repo.GetInvitationByToken(t.Context(), invitation.Token)
+		require.NoError(t, err)
+		assert.NoError(t, found.CanBeRedeemed(time.Now().UTC()))
+	})
+}
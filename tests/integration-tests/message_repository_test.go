@@ -62,7 +62,7 @@ func TestMessageRepository_Create(t *testing.T) {
 			CreatedAt:   time.Now(),
 		}
 
-		err = repo.Create(message)
+		err = repo.Create(t.Context(), message)
 		require.NoError(t, err)
 
 		// Verify message was created in database
@@ -106,7 +106,7 @@ func TestMessageRepository_Create(t *testing.T) {
 				CreatedAt:   time.Now(),
 			}
 
-			err = repo.Create(message)
+			err = repo.Create(t.Context(), message)
 			require.NoError(t, err)
 
 			// Verify message type was saved correctly
@@ -134,7 +134,7 @@ func TestMessageRepository_Create(t *testing.T) {
 			CreatedAt:   time.Now(),
 		}
 
-		err = repo.Create(message)
+		err = repo.Create(t.Context(), message)
 		require.Error(t, err)
 	})
 }
@@ -148,7 +148,7 @@ func TestMessageRepository_GetByChatID(t *testing.T) {
 	t.Run("should return messages for existing chat", func(t *testing.T) {
 		chatID := "01987073-0a87-7b32-9439-86868dfe9bd2"
 
-		messages, err := repo.GetByChatID(chatID, 10, 0)
+		messages, err := repo.GetByChatID(t.Context(), chatID, 10, 0)
 
 		require.NoError(t, err)
 		require.NotNil(t, messages)
@@ -173,7 +173,7 @@ func TestMessageRepository_GetByChatID(t *testing.T) {
 	t.Run("should return empty slice for non-existent chat", func(t *testing.T) {
 		nonExistentChatID := "01987073-0000-0000-0000-000000000000"
 
-		messages, err := repo.GetByChatID(nonExistentChatID, 10, 0)
+		messages, err := repo.GetByChatID(t.Context(), nonExistentChatID, 10, 0)
 
 		require.NoError(t, err)
 		assert.Equal(t, 0, len(messages))
@@ -183,7 +183,7 @@ func TestMessageRepository_GetByChatID(t *testing.T) {
 		chatID := "01987073-0a87-7b32-9439-86868dfe9bd2"
 
 		// Get first message only
-		messages, err := repo.GetByChatID(chatID, 1, 0)
+		messages, err := repo.GetByChatID(t.Context(), chatID, 1, 0)
 		require.NoError(t, err)
 		assert.LessOrEqual(t, len(messages), 1)
 
@@ -191,7 +191,7 @@ func TestMessageRepository_GetByChatID(t *testing.T) {
 			firstMessage := messages[0]
 
 			// Get second message with offset
-			messagesOffset, err := repo.GetByChatID(chatID, 1, 1)
+			messagesOffset, err := repo.GetByChatID(t.Context(), chatID, 1, 1)
 			require.NoError(t, err)
 
 			if len(messagesOffset) > 0 {
@@ -213,7 +213,7 @@ func TestMessageRepository_GetByUserGroup(t *testing.T) {
 		// User who is participant in chat '01987073-0a87-7b32-9439-86868dfe9bd2'
 		userIDs := []string{"01959b38-b3f9-7ec5-8ac8-e353bfe08a2d"}
 
-		messages, err := repo.GetByUserGroup(userIDs, 10, 0)
+		messages, err := repo.GetByUserGroup(t.Context(), userIDs, 10, 0)
 
 		require.NoError(t, err)
 		require.NotNil(t, messages)
@@ -235,7 +235,7 @@ func TestMessageRepository_GetByUserGroup(t *testing.T) {
 			"01959b39-febd-770d-9e1b-e5ee392fce54",
 		}
 
-		messages, err := repo.GetByUserGroup(userIDs, 10, 0)
+		messages, err := repo.GetByUserGroup(t.Context(), userIDs, 10, 0)
 
 		require.NoError(t, err)
 		require.NotNil(t, messages)
@@ -250,7 +250,7 @@ func TestMessageRepository_GetByUserGroup(t *testing.T) {
 	t.Run("should return empty slice for empty user IDs", func(t *testing.T) {
 		userIDs := []string{}
 
-		messages, err := repo.GetByUserGroup(userIDs, 10, 0)
+		messages, err := repo.GetByUserGroup(t.Context(), userIDs, 10, 0)
 
 		require.NoError(t, err)
 		require.NotNil(t, messages)
@@ -260,7 +260,7 @@ func TestMessageRepository_GetByUserGroup(t *testing.T) {
 	t.Run("should return empty slice for non-existent users", func(t *testing.T) {
 		userIDs := []string{"01987073-0000-0000-0000-000000000000"}
 
-		messages, err := repo.GetByUserGroup(userIDs, 10, 0)
+		messages, err := repo.GetByUserGroup(t.Context(), userIDs, 10, 0)
 
 		require.NoError(t, err)
 		assert.Equal(t, 0, len(messages))
@@ -270,7 +270,7 @@ func TestMessageRepository_GetByUserGroup(t *testing.T) {
 		userIDs := []string{"01959b38-b3f9-7ec5-8ac8-e353bfe08a2d"}
 
 		// Get first message only
-		messages, err := repo.GetByUserGroup(userIDs, 1, 0)
+		messages, err := repo.GetByUserGroup(t.Context(), userIDs, 1, 0)
 		require.NoError(t, err)
 		assert.LessOrEqual(t, len(messages), 1)
 
@@ -278,7 +278,7 @@ func TestMessageRepository_GetByUserGroup(t *testing.T) {
 			firstMessage := messages[0]
 
 			// Get with offset
-			messagesOffset, err := repo.GetByUserGroup(userIDs, 1, 1)
+			messagesOffset, err := repo.GetByUserGroup(t.Context(), userIDs, 1, 1)
 			require.NoError(t, err)
 
 			if len(messagesOffset) > 0 {
@@ -307,11 +307,11 @@ func TestMessageRepository_MessagePersistence(t *testing.T) {
 			CreatedAt:   time.Now().Truncate(time.Microsecond), // Truncate to match DB precision
 		}
 
-		err = repo.Create(originalMessage)
+		err = repo.Create(t.Context(), originalMessage)
 		require.NoError(t, err)
 
 		// Retrieve it back
-		messages, err := repo.GetByChatID(originalMessage.ChatID, 100, 0)
+		messages, err := repo.GetByChatID(t.Context(), originalMessage.ChatID, 100, 0)
 		require.NoError(t, err)
 
 		var foundMessage *entity.Message
@@ -335,3 +335,567 @@ func TestMessageRepository_MessagePersistence(t *testing.T) {
 		_, _ = testDB.Exec(`DELETE FROM public.message WHERE id = $1`, messageID)
 	})
 }
+
+func TestMessageRepository_GetByChatIDUntil(t *testing.T) {
+	setupMessageTestData(t)
+	defer cleanupMessageTestData(t)
+
+	repo := repository.NewRepository(testDB)
+
+	t.Run("should only return messages created up to the given time", func(t *testing.T) {
+		chatID := "01987073-0a87-7b32-9439-86868dfe9bd2"
+		until := time.Date(2024, 1, 1, 10, 0, 30, 0, time.UTC)
+
+		messages, err := repo.GetByChatIDUntil(t.Context(), chatID, until, 10, 0)
+
+		require.NoError(t, err)
+		for _, msg := range messages {
+			assert.False(t, msg.CreatedAt.After(until))
+		}
+		assert.NotContains(t, messageIDs(messages), "01987073-0a87-7b32-9439-86868dfe9bd5")
+	})
+}
+
+func messageIDs(messages []entity.Message) []string {
+	ids := make([]string, 0, len(messages))
+	for _, msg := range messages {
+		ids = append(ids, msg.ID)
+	}
+	return ids
+}
+
+func TestMessageRepository_Threads(t *testing.T) {
+	repo := repository.NewRepository(testDB)
+	chatID := "01987073-0a87-7b32-9439-86868dfe9bd2"
+	senderID := "01959b38-b3f9-7ec5-8ac8-e353bfe08a2d"
+
+	rootUUID, err := uuid.NewV7()
+	require.NoError(t, err)
+	root := entity.Message{
+		ID:          rootUUID.String(),
+		ChatID:      chatID,
+		SenderID:    senderID,
+		Content:     "Thread root",
+		MessageType: "text",
+		CreatedAt:   time.Now().UTC(),
+	}
+	require.NoError(t, repo.Create(t.Context(), root))
+	defer func() {
+		_, _ = testDB.Exec(`DELETE FROM public.message WHERE id = $1`, root.ID)
+	}()
+
+	t.Run("should update root counters when a reply is added", func(t *testing.T) {
+		replyUUID, err := uuid.NewV7()
+		require.NoError(t, err)
+		reply := entity.Message{
+			ID:           replyUUID.String(),
+			ChatID:       chatID,
+			SenderID:     senderID,
+			Content:      "First reply",
+			MessageType:  "text",
+			CreatedAt:    time.Now().UTC(),
+			ReplyToID:    &root.ID,
+			ThreadRootID: &root.ID,
+		}
+		require.NoError(t, repo.Create(t.Context(), reply))
+
+		found, err := repo.GetByID(t.Context(), root.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 1, found.ThreadReplyCount)
+		require.NotNil(t, found.ThreadLastReplyAt)
+
+		thread, err := repo.GetThreadMessages(t.Context(), root.ID, nil, 10, 0)
+		require.NoError(t, err)
+		require.Len(t, thread, 1)
+		assert.Equal(t, reply.ID, thread[0].ID)
+		require.NotNil(t, thread[0].ReplyToID)
+		assert.Equal(t, root.ID, *thread[0].ReplyToID)
+	})
+
+	t.Run("should keep thread replies out of the chat timeline", func(t *testing.T) {
+		messages, err := repo.GetByChatID(t.Context(), chatID, 1000, 0)
+		require.NoError(t, err)
+		for _, msg := range messages {
+			assert.Nil(t, msg.ThreadRootID)
+		}
+	})
+
+	t.Run("should return ErrMessageNotFound for unknown id", func(t *testing.T) {
+		found, err := repo.GetByID(t.Context(), "01987073-0000-0000-0000-000000000000")
+		require.ErrorIs(t, err, entity.ErrMessageNotFound)
+		assert.Nil(t, found)
+	})
+}
+
+func TestMessageRepository_Reactions(t *testing.T) {
+	setupMessageTestData(t)
+	defer cleanupMessageTestData(t)
+
+	repo := repository.NewRepository(testDB)
+	messageID := "01987073-0a87-7b32-9439-86868dfe9bd4"
+	userID := "01959b38-b3f9-7ec5-8ac8-e353bfe08a2d"
+	otherUserID := "01959b39-febd-770d-9e1b-e5ee392fce54"
+	defer func() {
+		_, _ = testDB.Exec(`DELETE FROM public.message_reaction WHERE message_id = $1`, messageID)
+	}()
+
+	t.Run("should add a reaction only once", func(t *testing.T) {
+		reaction, err := entity.NewReaction(messageID, userID, "👍")
+		require.NoError(t, err)
+
+		added, err := repo.AddReaction(t.Context(), *reaction)
+		require.NoError(t, err)
+		assert.True(t, added)
+
+		added, err = repo.AddReaction(t.Context(), *reaction)
+		require.NoError(t, err)
+		assert.False(t, added)
+
+		count, err := repo.CountReactions(t.Context(), messageID, "👍")
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+	})
+
+	t.Run("should summarise reactions per emoji", func(t *testing.T) {
+		reaction, err := entity.NewReaction(messageID, otherUserID, "👍")
+		require.NoError(t, err)
+		_, err = repo.AddReaction(t.Context(), *reaction)
+		require.NoError(t, err)
+
+		summaries, err := repo.GetReactionSummaries(t.Context(), []string{messageID}, otherUserID)
+		require.NoError(t, err)
+		require.Len(t, summaries[messageID], 1)
+		assert.Equal(t, "👍", summaries[messageID][0].Emoji)
+		assert.Equal(t, 2, summaries[messageID][0].Count)
+		assert.True(t, summaries[messageID][0].ReactedByMe)
+	})
+
+	t.Run("should remove a reaction", func(t *testing.T) {
+		removed, err := repo.RemoveReaction(t.Context(), messageID, userID, "👍")
+		require.NoError(t, err)
+		assert.True(t, removed)
+
+		removed, err = repo.RemoveReaction(t.Context(), messageID, userID, "👍")
+		require.NoError(t, err)
+		assert.False(t, removed)
+
+		count, err := repo.CountReactions(t.Context(), messageID, "👍")
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+	})
+}
+
+func TestMessageRepository_Attachments(t *testing.T) {
+	repo := repository.NewRepository(testDB)
+	chatID := "01987073-0a87-7b32-9439-86868dfe9bd2"
+	senderID := "01959b38-b3f9-7ec5-8ac8-e353bfe08a2d"
+
+	attachment, err := entity.NewAttachment(chatID, senderID, "photo.png", "image/png", 128,
+		"3a6eb0790f39ac87c94f3856b2dd2c5d110e6811602261a9a923d3bb23adc8b7")
+	require.NoError(t, err)
+	require.NoError(t, repo.CreateAttachment(t.Context(), *attachment))
+	defer func() {
+		_, _ = testDB.Exec(`DELETE FROM public.message_attachment WHERE id = $1`, attachment.ID)
+	}()
+
+	t.Run("should store an unlinked attachment", func(t *testing.T) {
+		found, err := repo.GetAttachmentByID(t.Context(), attachment.ID)
+		require.NoError(t, err)
+		assert.Equal(t, attachment.StorageKey, found.StorageKey)
+		assert.Equal(t, "image", found.Kind())
+		assert.False(t, found.IsLinked())
+	})
+
+	messageUUID, err := uuid.NewV7()
+	require.NoError(t, err)
+	message := entity.Message{
+		ID:            messageUUID.String(),
+		ChatID:        chatID,
+		SenderID:      senderID,
+		MessageType:   "image",
+		CreatedAt:     time.Now().UTC(),
+		AttachmentIDs: []string{attachment.ID},
+	}
+	defer func() {
+		_, _ = testDB.Exec(`DELETE FROM public.message WHERE id = $1`, message.ID)
+	}()
+
+	t.Run("should link attachments when the message is created", func(t *testing.T) {
+		require.NoError(t, repo.Create(t.Context(), message))
+
+		attachments, err := repo.GetAttachmentsByMessageIDs(t.Context(), []string{message.ID})
+		require.NoError(t, err)
+		require.Len(t, attachments[message.ID], 1)
+		assert.Equal(t, attachment.ID, attachments[message.ID][0].ID)
+	})
+
+	t.Run("should not link an attachment twice", func(t *testing.T) {
+		otherUUID, err := uuid.NewV7()
+		require.NoError(t, err)
+		other := message
+		other.ID = otherUUID.String()
+
+		err = repo.Create(t.Context(), other)
+		assert.ErrorIs(t, err, repository.ErrAttachmentUnavailable)
+
+		found, err := repo.GetByID(t.Context(), other.ID)
+		require.ErrorIs(t, err, entity.ErrMessageNotFound)
+		assert.Nil(t, found)
+	})
+}
+
+func TestMessageRepository_Search(t *testing.T) {
+	setupMessageTestData(t)
+	defer cleanupMessageTestData(t)
+
+	repo := repository.NewRepository(testDB)
+	kevinID := "01959b38-b3f9-7ec5-8ac8-e353bfe08a2d"
+
+	t.Run("should find and highlight messages in the caller's chats", func(t *testing.T) {
+		query := entity.SearchQuery{Text: "everyone"}
+		require.NoError(t, query.Validate())
+
+		results, err := repo.Search(t.Context(), kevinID, query)
+
+		require.NoError(t, err)
+		require.NotEmpty(t, results)
+		assert.Equal(t, "01987073-0a87-7b32-9439-86868dfe9bd4", results[0].Message.ID)
+		assert.Contains(t, results[0].Snippet, "<mark>everyone</mark>")
+	})
+
+	t.Run("should not return messages from chats the caller is not in", func(t *testing.T) {
+		query := entity.SearchQuery{Text: "private"}
+		require.NoError(t, query.Validate())
+
+		results, err := repo.Search(t.Context(), kevinID, query)
+
+		require.NoError(t, err)
+		assert.NotContains(t, searchResultIDs(results), "01987073-0a87-7b32-9439-86868dfe9bd6")
+	})
+
+	t.Run("should apply the sender filter", func(t *testing.T) {
+		query := entity.SearchQuery{Text: "hello OR hi", SenderID: "01959b39-febd-770d-9e1b-e5ee392fce54"}
+		require.NoError(t, query.Validate())
+
+		results, err := repo.Search(t.Context(), kevinID, query)
+
+		require.NoError(t, err)
+		for _, result := range results {
+			assert.Equal(t, query.SenderID, result.Message.SenderID)
+		}
+	})
+}
+
+func searchResultIDs(results []entity.SearchResult) []string {
+	ids := make([]string, 0, len(results))
+	for _, result := range results {
+		ids = append(ids, result.Message.ID)
+	}
+	return ids
+}
+
+func TestMessageRepository_Mentions(t *testing.T) {
+	repo := repository.NewRepository(testDB)
+	chatID := "01987073-0a87-7b32-9439-86868dfe9bd2"
+	senderID := "01959b38-b3f9-7ec5-8ac8-e353bfe08a2d"
+	mentionedID := "01959b39-febd-770d-9e1b-e5ee392fce54"
+
+	messageUUID, err := uuid.NewV7()
+	require.NoError(t, err)
+	message := entity.Message{
+		ID:          messageUUID.String(),
+		ChatID:      chatID,
+		SenderID:    senderID,
+		Content:     "hey @" + mentionedID,
+		MessageType: "text",
+		CreatedAt:   time.Now().UTC(),
+	}
+	message.MentionRecipients = message.ResolveMentions([]entity.MentionCandidate{{UserID: senderID}, {UserID: mentionedID}}, true)
+	require.NoError(t, repo.Create(t.Context(), message))
+	defer func() {
+		_, _ = testDB.Exec(`DELETE FROM public.message WHERE id = $1`, message.ID)
+	}()
+
+	t.Run("should store mention entities on the message", func(t *testing.T) {
+		found, err := repo.GetByID(t.Context(), message.ID)
+		require.NoError(t, err)
+		require.Len(t, found.Mentions, 1)
+		assert.Equal(t, mentionedID, found.Mentions[0].UserID)
+	})
+
+	t.Run("should list the message in the mentioned user's mentions", func(t *testing.T) {
+		mentions, err := repo.GetMentionsForUser(t.Context(), mentionedID, 50, 0)
+		require.NoError(t, err)
+		require.NotEmpty(t, mentions)
+		assert.Equal(t, message.ID, mentions[0].Message.ID)
+		assert.Equal(t, entity.MentionUser, mentions[0].MentionType)
+	})
+
+	t.Run("should not list the message for the sender", func(t *testing.T) {
+		mentions, err := repo.GetMentionsForUser(t.Context(), senderID, 50, 0)
+		require.NoError(t, err)
+		for _, mention := range mentions {
+			assert.NotEqual(t, message.ID, mention.Message.ID)
+		}
+	})
+}
+
+func TestMessageRepository_Pins(t *testing.T) {
+	setupMessageTestData(t)
+	defer cleanupMessageTestData(t)
+
+	repo := repository.NewRepository(testDB)
+	chatID := "01987073-0a87-7b32-9439-86868dfe9bd2"
+	adminID := "01959b38-b3f9-7ec5-8ac8-e353bfe08a2d"
+	defer func() {
+		_, _ = testDB.Exec(`DELETE FROM public.message_pin WHERE chat_id = $1`, chatID)
+	}()
+
+	first, err := repo.GetByID(t.Context(), "01987073-0a87-7b32-9439-86868dfe9bd4")
+	require.NoError(t, err)
+	second, err := repo.GetByID(t.Context(), "01987073-0a87-7b32-9439-86868dfe9bd5")
+	require.NoError(t, err)
+
+	t.Run("should pin messages in order", func(t *testing.T) {
+		for _, message := range []*entity.Message{first, second} {
+			pin, err := entity.NewPin(message, adminID)
+			require.NoError(t, err)
+			pinned, err := repo.PinMessage(t.Context(), pin)
+			require.NoError(t, err)
+			assert.True(t, pinned)
+		}
+
+		pins, err := repo.GetPins(t.Context(), chatID)
+		require.NoError(t, err)
+		require.Len(t, pins, 2)
+		assert.Equal(t, first.ID, pins[0].MessageID)
+		assert.Equal(t, second.ID, pins[1].MessageID)
+		assert.Less(t, pins[0].Position, pins[1].Position)
+		assert.Equal(t, first.Content, pins[0].Message.Content)
+	})
+
+	t.Run("should not pin a message twice", func(t *testing.T) {
+		pin, err := entity.NewPin(first, adminID)
+		require.NoError(t, err)
+		pinned, err := repo.PinMessage(t.Context(), pin)
+		require.NoError(t, err)
+		assert.False(t, pinned)
+	})
+
+	t.Run("should unpin a message", func(t *testing.T) {
+		unpinned, err := repo.UnpinMessage(t.Context(), chatID, first.ID)
+		require.NoError(t, err)
+		assert.True(t, unpinned)
+
+		pins, err := repo.GetPins(t.Context(), chatID)
+		require.NoError(t, err)
+		require.Len(t, pins, 1)
+		assert.Equal(t, second.ID, pins[0].MessageID)
+	})
+}
+
+func TestMessageRepository_ScheduledMessages(t *testing.T) {
+	setupMessageTestData(t)
+	defer cleanupMessageTestData(t)
+
+	repo := repository.NewRepository(testDB)
+	chatID := "01987073-0a87-7b32-9439-86868dfe9bd2"
+	senderID := "01959b38-b3f9-7ec5-8ac8-e353bfe08a2d"
+	defer func() {
+		_, _ = testDB.Exec(`DELETE FROM public.scheduled_message WHERE chat_id = $1`, chatID)
+	}()
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	scheduled, err := entity.NewScheduledMessage(chatID, senderID, "Reminder: standup", nil, nil, now.Add(time.Hour), now)
+	require.NoError(t, err)
+	require.NoError(t, repo.CreateScheduledMessage(t.Context(), *scheduled))
+
+	t.Run("should list pending scheduled messages", func(t *testing.T) {
+		pending, err := repo.GetPendingScheduledMessages(t.Context(), chatID, senderID)
+		require.NoError(t, err)
+		require.Len(t, pending, 1)
+		assert.Equal(t, scheduled.ID, pending[0].ID)
+		assert.Equal(t, entity.ScheduledPending, pending[0].Status)
+	})
+
+	t.Run("should update a pending scheduled message", func(t *testing.T) {
+		content := "Reminder: standup moved"
+		sendAt := now.Add(2 * time.Hour)
+		require.NoError(t, scheduled.Edit(&content, &sendAt, now))
+
+		updated, err := repo.UpdateScheduledMessage(t.Context(), *scheduled)
+		require.NoError(t, err)
+		assert.True(t, updated)
+
+		stored, err := repo.GetScheduledMessage(t.Context(), scheduled.ID)
+		require.NoError(t, err)
+		assert.Equal(t, content, stored.Content)
+		assert.True(t, sendAt.Equal(stored.SendAt))
+	})
+
+	t.Run("should claim due scheduled messages once", func(t *testing.T) {
+		notDue, err := repo.ClaimDueScheduledMessages(t.Context(), now, now.Add(-time.Minute), 10)
+		require.NoError(t, err)
+		assert.Empty(t, notDue)
+
+		later := now.Add(3 * time.Hour)
+		claimed, err := repo.ClaimDueScheduledMessages(t.Context(), later, later.Add(-time.Minute), 10)
+		require.NoError(t, err)
+		require.Len(t, claimed, 1)
+		assert.Equal(t, entity.ScheduledSending, claimed[0].Status)
+
+		again, err := repo.ClaimDueScheduledMessages(t.Context(), later, later.Add(-time.Minute), 10)
+		require.NoError(t, err)
+		assert.Empty(t, again)
+	})
+
+	t.Run("should not cancel a message being delivered", func(t *testing.T) {
+		cancelled, err := repo.CancelScheduledMessage(t.Context(), scheduled.ID)
+		require.NoError(t, err)
+		assert.False(t, cancelled)
+	})
+
+	t.Run("should record the delivery outcome", func(t *testing.T) {
+		require.NoError(t, repo.CompleteScheduledMessage(t.Context(), scheduled.ID, entity.ScheduledSent, nil))
+
+		stored, err := repo.GetScheduledMessage(t.Context(), scheduled.ID)
+		require.NoError(t, err)
+		assert.Equal(t, entity.ScheduledSent, stored.Status)
+
+		pending, err := repo.GetPendingScheduledMessages(t.Context(), chatID, senderID)
+		require.NoError(t, err)
+		assert.Empty(t, pending)
+	})
+}
+
+func TestMessageRepository_PurgeExpiredMessages(t *testing.T) {
+	setupMessageTestData(t)
+	defer cleanupMessageTestData(t)
+
+	repo := repository.NewRepository(testDB)
+	now := time.Now().UTC()
+	chatID := "01987073-0a87-7b32-9439-86868dfe9bd2"
+	retentionChatID := uuid.New().String()
+	senderID := "01959b38-b3f9-7ec5-8ac8-e353bfe08a2d"
+	rootID := "01987073-0a87-7b32-9439-86868dfe9bd4"
+
+	_, err := testDB.Exec(`
+		INSERT INTO public.chat(id, type, usergroup_id, settings, created_at)
+		VALUES ($1, 'group', 908, '{"retention_seconds": 3600}', CURRENT_TIMESTAMP)`, retentionChatID)
+	require.NoError(t, err)
+	defer func() {
+		_, _ = testDB.Exec(`DELETE FROM public.message WHERE chat_id = $1`, retentionChatID)
+		_, _ = testDB.Exec(`DELETE FROM public.chat WHERE id = $1`, retentionChatID)
+	}()
+
+	expiredReply := entity.Message{ID: uuid.New().String(), ChatID: chatID, SenderID: senderID, Content: "gone soon",
+		MessageType: entity.TypeText, CreatedAt: now.Add(-time.Hour), ThreadRootID: &rootID}
+	expiresAt := now.Add(-time.Minute)
+	expiredReply.ExpiresAt = &expiresAt
+	require.NoError(t, repo.Create(t.Context(), expiredReply))
+
+	liveReply := entity.Message{ID: uuid.New().String(), ChatID: chatID, SenderID: senderID, Content: "still here",
+		MessageType: entity.TypeText, CreatedAt: now.Add(-2 * time.Hour), ThreadRootID: &rootID}
+	require.NoError(t, repo.Create(t.Context(), liveReply))
+	defer func() {
+		_, _ = testDB.Exec(`DELETE FROM public.message WHERE id = $1`, liveReply.ID)
+	}()
+
+	oldMessage := entity.Message{ID: uuid.New().String(), ChatID: retentionChatID, SenderID: senderID, Content: "old news",
+		MessageType: entity.TypeText, CreatedAt: now.Add(-2 * time.Hour)}
+	require.NoError(t, repo.Create(t.Context(), oldMessage))
+	recentMessage := entity.Message{ID: uuid.New().String(), ChatID: retentionChatID, SenderID: senderID, Content: "fresh",
+		MessageType: entity.TypeText, CreatedAt: now.Add(-time.Minute)}
+	require.NoError(t, repo.Create(t.Context(), recentMessage))
+
+	// Reads hide expired messages before the purge deleted them.
+	thread, err := repo.GetThreadMessages(t.Context(), rootID, nil, 50, 0)
+	require.NoError(t, err)
+	require.Len(t, thread, 1)
+	assert.Equal(t, liveReply.ID, thread[0].ID)
+	timeline, err := repo.GetByChatID(t.Context(), retentionChatID, 50, 0)
+	require.NoError(t, err)
+	require.Len(t, timeline, 1)
+	assert.Equal(t, recentMessage.ID, timeline[0].ID)
+	timeline, err = repo.GetByChatIDUntil(t.Context(), retentionChatID, now, 50, 0)
+	require.NoError(t, err)
+	require.Len(t, timeline, 1)
+	assert.Equal(t, recentMessage.ID, timeline[0].ID)
+	for _, id := range []string{expiredReply.ID, oldMessage.ID} {
+		found, err := repo.GetByID(t.Context(), id)
+		require.ErrorIs(t, err, entity.ErrMessageNotFound)
+		assert.Nil(t, found)
+	}
+	results, err := repo.Search(t.Context(), senderID, entity.SearchQuery{Text: "gone soon", Limit: 20})
+	require.NoError(t, err)
+	assert.NotContains(t, searchResultIDs(results), expiredReply.ID)
+
+	result, err := repo.PurgeExpiredMessages(t.Context(), now, 100)
+	require.NoError(t, err)
+	assert.Contains(t, result.Messages, entity.ExpiredMessage{ID: expiredReply.ID, ChatID: chatID})
+	assert.Contains(t, result.Messages, entity.ExpiredMessage{ID: oldMessage.ID, ChatID: retentionChatID})
+
+	for _, id := range []string{expiredReply.ID, oldMessage.ID} {
+		found, err := repo.GetByID(t.Context(), id)
+		require.ErrorIs(t, err, entity.ErrMessageNotFound)
+		assert.Nil(t, found)
+	}
+	for _, id := range []string{liveReply.ID, recentMessage.ID} {
+		found, err := repo.GetByID(t.Context(), id)
+		require.NoError(t, err)
+		assert.Equal(t, id, found.ID)
+	}
+
+	root, err := repo.GetByID(t.Context(), rootID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, root.ThreadReplyCount)
+	require.NotNil(t, root.ThreadLastReplyAt)
+	assert.WithinDuration(t, liveReply.CreatedAt, *root.ThreadLastReplyAt, time.Millisecond)
+}
+
+func TestMessageRepository_LinkPreviews(t *testing.T) {
+	setupMessageTestData(t)
+	defer cleanupMessageTestData(t)
+
+	repo := repository.NewRepository(testDB)
+	hitURL := "https://example.com/" + uuid.New().String()
+	missURL := "https://example.com/" + uuid.New().String()
+	defer func() {
+		_, _ = testDB.Exec(`DELETE FROM public.link_preview WHERE url IN ($1, $2)`, hitURL, missURL)
+	}()
+	now := time.Now().UTC()
+
+	t.Run("should return an empty entry for unknown urls", func(t *testing.T) {
+		entry, err := repo.GetLinkPreviewCache(t.Context(), hitURL)
+		require.NoError(t, err)
+		assert.Empty(t, entry.URL)
+	})
+
+	t.Run("should cache hits and misses", func(t *testing.T) {
+		preview := &entity.LinkPreview{URL: hitURL, Title: "Example", ImageURL: "https://example.com/cover.png"}
+		require.NoError(t, repo.SaveLinkPreviewCache(t.Context(), entity.LinkPreviewCacheEntry{URL: hitURL, Preview: preview, FetchedAt: now}))
+		require.NoError(t, repo.SaveLinkPreviewCache(t.Context(), entity.LinkPreviewCacheEntry{URL: missURL, FetchedAt: now.Add(-2 * time.Hour)}))
+
+		hit, err := repo.GetLinkPreviewCache(t.Context(), hitURL)
+		require.NoError(t, err)
+		require.NotNil(t, hit.Preview)
+		assert.Equal(t, *preview, *hit.Preview)
+		assert.True(t, hit.IsFresh(now))
+
+		miss, err := repo.GetLinkPreviewCache(t.Context(), missURL)
+		require.NoError(t, err)
+		assert.Equal(t, missURL, miss.URL)
+		assert.Nil(t, miss.Preview)
+		assert.False(t, miss.IsFresh(now))
+	})
+
+	t.Run("should store previews on the message", func(t *testing.T) {
+		messageID := "01987073-0a87-7b32-9439-86868dfe9bd4"
+		previews := entity.LinkPreviews{{URL: hitURL, Title: "Example"}}
+		require.NoError(t, repo.SetLinkPreviews(t.Context(), messageID, previews))
+
+		message, err := repo.GetByID(t.Context(), messageID)
+		require.NoError(t, err)
+		assert.Equal(t, previews, message.LinkPreviews)
+	})
+}
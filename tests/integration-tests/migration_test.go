@@ -0,0 +1,124 @@
+package integration_tests
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/HappYness-Project/ChatBackendServer/dbs"
+	chatRepository "github.com/HappYness-Project/ChatBackendServer/internal/chat/repository"
+	entity "github.com/HappYness-Project/ChatBackendServer/internal/message/domain"
+	messageRepository "github.com/HappYness-Project/ChatBackendServer/internal/message/repository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrator(t *testing.T) {
+	migrator, err := dbs.NewMigrator(testDB)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	t.Run("should apply migrations once across concurrent runs", func(t *testing.T) {
+		var wg sync.WaitGroup
+		errs := make([]error, 3)
+		for i := range errs {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				errs[i] = migrator.Up(ctx)
+			}(i)
+		}
+		wg.Wait()
+		for _, err := range errs {
+			assert.NoError(t, err)
+		}
+	})
+
+	t.Run("should report every migration as applied", func(t *testing.T) {
+		statuses, err := migrator.Status(ctx)
+		require.NoError(t, err)
+		require.NotEmpty(t, statuses)
+		for _, status := range statuses {
+			assert.NotNil(t, status.AppliedAt, "%d_%s", status.Version, status.Name)
+		}
+	})
+
+	t.Run("should seed idempotently", func(t *testing.T) {
+		require.NoError(t, migrator.Seed(ctx))
+		require.NoError(t, migrator.Seed(ctx))
+
+		var participants int
+		err := testDB.QueryRow(`
+			SELECT COUNT(*) FROM public.chat_participant
+			WHERE chat_id = '01987073-0a87-7b32-9439-86868dfe9bd2' AND user_id = '01959b38-b3f9-7ec5-8ac8-e353bfe08a2d'`).Scan(&participants)
+		require.NoError(t, err)
+		assert.Equal(t, 1, participants)
+	})
+}
+
+// TestMigrator_AdoptsBaselineSchema migrates a database created by the former create_tables.sql init script,
+// kept in testdata as it was before the migrations replaced it.
+func TestMigrator_AdoptsBaselineSchema(t *testing.T) {
+	ctx := context.Background()
+	name := "baseline_" + strings.ReplaceAll(uuid.New().String(), "-", "")
+	_, err := testDB.Exec(`CREATE DATABASE ` + name)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_, err := testDB.Exec(`DROP DATABASE IF EXISTS ` + name + ` WITH (FORCE)`)
+		assert.NoError(t, err)
+	})
+	dsn := strings.Replace(testDSN, "/postgres?", "/"+name+"?", 1)
+
+	baseline, err := os.ReadFile("testdata/create_tables_baseline.sql")
+	require.NoError(t, err)
+	// The script changes settings of its session, so the migrations run on a pool of their own.
+	initDB, err := dbs.ConnectToDb(dsn)
+	require.NoError(t, err)
+	_, err = initDB.Exec(string(baseline))
+	require.NoError(t, err)
+	require.NoError(t, initDB.Close())
+
+	db, err := dbs.ConnectToDb(dsn)
+	require.NoError(t, err)
+	defer db.Close()
+	migrator, err := dbs.NewMigrator(db)
+	require.NoError(t, err)
+	require.NoError(t, migrator.Up(ctx))
+
+	t.Run("should keep the existing rows", func(t *testing.T) {
+		chat, err := chatRepository.NewRepository(db).GetChatById(ctx, "01987073-0a87-7b32-9439-86868dfe9bd2")
+		require.NoError(t, err)
+		assert.Equal(t, "", chat.Name)
+		assert.Equal(t, "", chat.Description)
+
+		participants, err := chatRepository.NewRepository(db).GetChatParticipants(ctx, chat.Id)
+		require.NoError(t, err)
+		assert.Len(t, participants, 3)
+	})
+
+	t.Run("should add the columns introduced since", func(t *testing.T) {
+		messages := messageRepository.NewRepository(db)
+		msg := entity.Message{
+			ID:          uuid.New().String(),
+			ChatID:      "01987073-0a87-7b32-9439-86868dfe9bd2",
+			SenderID:    "01959b38-b3f9-7ec5-8ac8-e353bfe08a2d",
+			Content:     "adopted schema works",
+			MessageType: "text",
+			CreatedAt:   time.Now().UTC(),
+		}
+		require.NoError(t, messages.Create(ctx, msg))
+
+		stored, err := messages.GetByID(ctx, msg.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 0, stored.ThreadReplyCount)
+		assert.Nil(t, stored.ExpiresAt)
+
+		var matches int
+		require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM public.message WHERE content_tsv @@ plainto_tsquery('english', 'adopted')`).Scan(&matches))
+		assert.Equal(t, 1, matches)
+	})
+}
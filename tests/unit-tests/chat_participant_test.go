@@ -0,0 +1,33 @@
+package unit_tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/HappYness-Project/ChatBackendServer/internal/chat/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatParticipant_LeaveAndRejoin(t *testing.T) {
+	t.Run("an active participant leaves and rejoins", func(t *testing.T) {
+		participant := domain.ChatParticipant{Status: domain.StatusActive}
+		require.NoError(t, participant.Leave(time.Now().UTC()))
+		assert.Equal(t, domain.StatusLeft, participant.Status)
+		require.NotNil(t, participant.LeftAt)
+
+		require.NoError(t, participant.Rejoin())
+		assert.Equal(t, domain.StatusActive, participant.Status)
+		assert.Nil(t, participant.LeftAt)
+	})
+
+	t.Run("a muted participant cannot lift the mute by leaving and rejoining", func(t *testing.T) {
+		participant := domain.ChatParticipant{Status: domain.StatusActive}
+		require.NoError(t, participant.ChangeStatus(domain.StatusMuted.String()))
+
+		assert.Error(t, participant.Leave(time.Now().UTC()))
+		assert.Error(t, participant.Rejoin())
+		assert.Equal(t, domain.StatusMuted, participant.Status)
+		assert.Nil(t, participant.LeftAt)
+	})
+}
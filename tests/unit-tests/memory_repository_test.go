@@ -0,0 +1,295 @@
+package unit_tests
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/HappYness-Project/ChatBackendServer/internal/chat/domain"
+	chatRepository "github.com/HappYness-Project/ChatBackendServer/internal/chat/repository"
+	entity "github.com/HappYness-Project/ChatBackendServer/internal/message/domain"
+	messageRepository "github.com/HappYness-Project/ChatBackendServer/internal/message/repository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMemoryChat(t *testing.T, chats *chatRepository.MemoryChatRepo, userIds ...string) *domain.Chat {
+	chat, err := domain.NewChat(domain.ChatTypePrivate, nil, nil)
+	require.NoError(t, err)
+	_, err = chats.CreateChat(t.Context(), chat)
+	require.NoError(t, err)
+	for _, userId := range userIds {
+		participant, err := domain.NewChatParticipant(chat.Id, userId, domain.RoleMember, domain.StatusActive)
+		require.NoError(t, err)
+		_, err = chats.AddParticipantToChat(t.Context(), participant)
+		require.NoError(t, err)
+	}
+	return chat
+}
+
+func newMemoryMessage(chatID, senderID, content string, createdAt time.Time) entity.Message {
+	return entity.Message{
+		ID:          uuid.New().String(),
+		ChatID:      chatID,
+		SenderID:    senderID,
+		Content:     content,
+		MessageType: "text",
+		CreatedAt:   createdAt,
+	}
+}
+
+func TestMemoryChatRepository(t *testing.T) {
+	chats := chatRepository.NewMemoryRepository()
+	userId := uuid.New().String()
+
+	t.Run("not found returns ErrChatNotFound", func(t *testing.T) {
+		chat, err := chats.GetChatById(t.Context(), uuid.New().String())
+		require.ErrorIs(t, err, domain.ErrChatNotFound)
+		assert.Nil(t, chat)
+	})
+
+	t.Run("participants behave like the database", func(t *testing.T) {
+		chat := newMemoryChat(t, chats, userId)
+
+		participant, err := domain.NewChatParticipant(chat.Id, userId, domain.RoleMember, domain.StatusActive)
+		require.NoError(t, err)
+		_, err = chats.AddParticipantToChat(t.Context(), participant)
+		assert.ErrorIs(t, err, domain.ErrAlreadyParticipant)
+
+		require.NoError(t, chats.LeaveChat(t.Context(), chat.Id, userId, time.Now().UTC()))
+		left, err := chats.GetChatParticipant(t.Context(), chat.Id, userId)
+		require.NoError(t, err)
+		assert.Equal(t, domain.StatusLeft, left.Status)
+		require.NotNil(t, left.LeftAt)
+
+		require.NoError(t, chats.RejoinChat(t.Context(), chat.Id, userId))
+		rejoined, err := chats.GetChatParticipant(t.Context(), chat.Id, userId)
+		require.NoError(t, err)
+		assert.Equal(t, domain.StatusActive, rejoined.Status)
+		assert.Equal(t, left.Id, rejoined.Id)
+	})
+
+	t.Run("ensure user group chat creates once and restores deleted chats", func(t *testing.T) {
+		first, err := chats.EnsureUserGroupChat(t.Context(), 4242)
+		require.NoError(t, err)
+		second, err := chats.EnsureUserGroupChat(t.Context(), 4242)
+		require.NoError(t, err)
+		assert.Equal(t, first.Id, second.Id)
+
+		require.NoError(t, chats.SoftDeleteChat(t.Context(), first.Id, time.Now().UTC()))
+		hidden, err := chats.GetChatByGroupID(t.Context(), 4242)
+		require.ErrorIs(t, err, domain.ErrChatNotFound)
+		assert.Nil(t, hidden)
+
+		restored, err := chats.EnsureUserGroupChat(t.Context(), 4242)
+		require.NoError(t, err)
+		assert.Equal(t, first.Id, restored.Id)
+		assert.Nil(t, restored.DeletedAt)
+	})
+
+	t.Run("invitations respect max uses", func(t *testing.T) {
+		chat := newMemoryChat(t, chats)
+		maxUses := 1
+		invitation, err := domain.NewChatInvitation(chat.Id, userId, nil, &maxUses)
+		require.NoError(t, err)
+		_, err = chats.CreateInvitation(t.Context(), invitation)
+		require.NoError(t, err)
+
+		first, err := domain.NewChatParticipant(chat.Id, uuid.New().String(), domain.RoleMember, domain.StatusActive)
+		require.NoError(t, err)
+		_, err = chats.RedeemInvitation(t.Context(), invitation.Id, first)
+		require.NoError(t, err)
+
+		second, err := domain.NewChatParticipant(chat.Id, uuid.New().String(), domain.RoleMember, domain.StatusActive)
+		require.NoError(t, err)
+		_, err = chats.RedeemInvitation(t.Context(), invitation.Id, second)
+		assert.ErrorIs(t, err, domain.ErrInvitationExhausted)
+	})
+
+	t.Run("cancelled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+		_, err := chats.GetChatById(ctx, uuid.New().String())
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestMemoryMessageRepository(t *testing.T) {
+	chats := chatRepository.NewMemoryRepository()
+	messages := messageRepository.NewMemoryRepository(chats)
+	senderID := uuid.New().String()
+	chat := newMemoryChat(t, chats, senderID)
+	base := time.Now().UTC().Add(-time.Hour)
+
+	t.Run("timeline excludes thread replies and updates the root", func(t *testing.T) {
+		root := newMemoryMessage(chat.Id, senderID, "root", base)
+		require.NoError(t, messages.Create(t.Context(), root))
+		reply := newMemoryMessage(chat.Id, senderID, "reply", base.Add(time.Minute))
+		reply.ThreadRootID = &root.ID
+		require.NoError(t, messages.Create(t.Context(), reply))
+
+		timeline, err := messages.GetByChatID(t.Context(), chat.Id, 50, 0)
+		require.NoError(t, err)
+		require.Len(t, timeline, 1)
+		assert.Equal(t, root.ID, timeline[0].ID)
+		assert.Equal(t, 1, timeline[0].ThreadReplyCount)
+
+		thread, err := messages.GetThreadMessages(t.Context(), root.ID, nil, 50, 0)
+		require.NoError(t, err)
+		require.Len(t, thread, 1)
+		assert.Equal(t, reply.ID, thread[0].ID)
+	})
+
+	t.Run("reactions are idempotent", func(t *testing.T) {
+		msg := newMemoryMessage(chat.Id, senderID, "react to me", base.Add(2*time.Minute))
+		require.NoError(t, messages.Create(t.Context(), msg))
+
+		reaction, err := entity.NewReaction(msg.ID, senderID, ":thumbsup:")
+		require.NoError(t, err)
+		added, err := messages.AddReaction(t.Context(), *reaction)
+		require.NoError(t, err)
+		assert.True(t, added)
+		added, err = messages.AddReaction(t.Context(), *reaction)
+		require.NoError(t, err)
+		assert.False(t, added)
+
+		summaries, err := messages.GetReactionSummaries(t.Context(), []string{msg.ID}, senderID)
+		require.NoError(t, err)
+		assert.Equal(t, []entity.ReactionSummary{{Emoji: ":thumbsup:", Count: 1, ReactedByMe: true}}, summaries[msg.ID])
+	})
+
+	t.Run("attachments are linked once", func(t *testing.T) {
+		attachment := entity.Attachment{ID: uuid.New().String(), ChatID: chat.Id, UploaderID: senderID,
+			StorageKey: "chats/" + chat.Id + "/file", FileName: "file.txt", CreatedAt: base}
+		require.NoError(t, messages.CreateAttachment(t.Context(), attachment))
+
+		first := newMemoryMessage(chat.Id, senderID, "with file", base.Add(3*time.Minute))
+		first.AttachmentIDs = []string{attachment.ID}
+		require.NoError(t, messages.Create(t.Context(), first))
+
+		second := newMemoryMessage(chat.Id, senderID, "same file", base.Add(4*time.Minute))
+		second.AttachmentIDs = []string{attachment.ID}
+		assert.ErrorIs(t, messages.Create(t.Context(), second), messageRepository.ErrAttachmentUnavailable)
+
+		stored, err := messages.GetByID(t.Context(), second.ID)
+		require.ErrorIs(t, err, entity.ErrMessageNotFound)
+		assert.Nil(t, stored)
+	})
+
+	t.Run("search only covers chats of the user", func(t *testing.T) {
+		require.NoError(t, messages.Create(t.Context(), newMemoryMessage(chat.Id, senderID, "Deploy <b>tonight</b>", base)))
+		other := newMemoryChat(t, chats, uuid.New().String())
+		require.NoError(t, messages.Create(t.Context(), newMemoryMessage(other.Id, senderID, "deploy elsewhere", base)))
+
+		results, err := messages.Search(t.Context(), senderID, entity.SearchQuery{Text: "deploy", Limit: 20})
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Equal(t, "<mark>Deploy</mark> &lt;b&gt;tonight&lt;/b&gt;", results[0].Snippet)
+	})
+
+	t.Run("search covers chats the user is muted in", func(t *testing.T) {
+		muted := newMemoryChat(t, chats, senderID)
+		require.NoError(t, chats.UpdateParticipantStatus(t.Context(), muted.Id, senderID, domain.StatusMuted))
+		require.NoError(t, messages.Create(t.Context(), newMemoryMessage(muted.Id, senderID, "release notes", base)))
+		left := newMemoryChat(t, chats, senderID)
+		require.NoError(t, chats.LeaveChat(t.Context(), left.Id, senderID, base))
+		require.NoError(t, messages.Create(t.Context(), newMemoryMessage(left.Id, senderID, "release party", base)))
+
+		results, err := messages.Search(t.Context(), senderID, entity.SearchQuery{Text: "release", Limit: 20})
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Equal(t, muted.Id, results[0].Message.ChatID)
+	})
+
+	t.Run("expired messages are hidden before the purge runs", func(t *testing.T) {
+		retentionChat := newMemoryChat(t, chats, senderID)
+		retentionChat.Settings.RetentionSeconds = domain.MinRetentionSeconds
+		require.NoError(t, chats.UpdateChat(t.Context(), retentionChat))
+		now := time.Now().UTC()
+
+		root := newMemoryMessage(retentionChat.Id, senderID, "root", now.Add(-time.Minute))
+		require.NoError(t, messages.Create(t.Context(), root))
+		tooOld := newMemoryMessage(retentionChat.Id, senderID, "past retention", now.Add(-2*time.Hour))
+		require.NoError(t, messages.Create(t.Context(), tooOld))
+		ttlReply := newMemoryMessage(retentionChat.Id, senderID, "ttl ran out", now.Add(-time.Minute))
+		ttlReply.ThreadRootID = &root.ID
+		expiresAt := now.Add(-time.Second)
+		ttlReply.ExpiresAt = &expiresAt
+		require.NoError(t, messages.Create(t.Context(), ttlReply))
+
+		timeline, err := messages.GetByChatID(t.Context(), retentionChat.Id, 50, 0)
+		require.NoError(t, err)
+		require.Len(t, timeline, 1)
+		assert.Equal(t, root.ID, timeline[0].ID)
+		timeline, err = messages.GetByChatIDUntil(t.Context(), retentionChat.Id, now, 50, 0)
+		require.NoError(t, err)
+		require.Len(t, timeline, 1)
+		assert.Equal(t, root.ID, timeline[0].ID)
+		thread, err := messages.GetThreadMessages(t.Context(), root.ID, nil, 50, 0)
+		require.NoError(t, err)
+		assert.Empty(t, thread)
+
+		// Expired thread roots and replies cannot be read, replied to or reacted to.
+		for _, id := range []string{tooOld.ID, ttlReply.ID} {
+			found, err := messages.GetByID(t.Context(), id)
+			require.ErrorIs(t, err, entity.ErrMessageNotFound)
+			assert.Nil(t, found)
+		}
+		found, err := messages.GetByID(t.Context(), root.ID)
+		require.NoError(t, err)
+		assert.Equal(t, root.ID, found.ID)
+
+		for text, expected := range map[string]int{"root": 1, "retention": 0, "ttl": 0} {
+			results, err := messages.Search(t.Context(), senderID, entity.SearchQuery{Text: text, ChatID: retentionChat.Id, Limit: 20})
+			require.NoError(t, err)
+			assert.Len(t, results, expected, text)
+		}
+	})
+
+	t.Run("purging a chat removes its messages and reports attachment keys", func(t *testing.T) {
+		doomed := newMemoryChat(t, chats, senderID)
+		msg := newMemoryMessage(doomed.Id, senderID, "gone", base)
+		require.NoError(t, messages.Create(t.Context(), msg))
+		storageKey := "chats/" + doomed.Id + "/orphan"
+		require.NoError(t, messages.CreateAttachment(t.Context(), entity.Attachment{ID: uuid.New().String(),
+			ChatID: doomed.Id, UploaderID: senderID, StorageKey: storageKey, CreatedAt: base}))
+
+		require.NoError(t, chats.SoftDeleteChat(t.Context(), doomed.Id, base.Add(-domain.ChatRestoreWindow)))
+		result, err := chats.PurgeDeletedChats(t.Context(), base, 10)
+		require.NoError(t, err)
+		assert.Equal(t, []string{doomed.Id}, result.ChatIDs)
+		assert.Equal(t, []string{storageKey}, result.StorageKeys)
+
+		stored, err := messages.GetByID(t.Context(), msg.ID)
+		require.ErrorIs(t, err, entity.ErrMessageNotFound)
+		assert.Nil(t, stored)
+	})
+
+	t.Run("concurrent pins get distinct positions", func(t *testing.T) {
+		pinChat := newMemoryChat(t, chats, senderID)
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			msg := newMemoryMessage(pinChat.Id, senderID, "pin me", base.Add(time.Duration(i)*time.Second))
+			require.NoError(t, messages.Create(t.Context(), msg))
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				pin, err := entity.NewPin(&msg, senderID)
+				assert.NoError(t, err)
+				_, err = messages.PinMessage(t.Context(), pin)
+				assert.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+
+		pins, err := messages.GetPins(t.Context(), pinChat.Id)
+		require.NoError(t, err)
+		require.Len(t, pins, 10)
+		for i, pin := range pins {
+			assert.Equal(t, i+1, pin.Position)
+			assert.NotNil(t, pin.Message)
+		}
+	})
+}
@@ -0,0 +1,73 @@
+package unit_tests
+
+import (
+	"testing"
+
+	entity "github.com/HappYness-Project/ChatBackendServer/internal/message/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMentions(t *testing.T) {
+	userID := "01959b39-febd-770d-9e1b-e5ee392fce54"
+
+	t.Run("should find user, all and admins mentions with character offsets", func(t *testing.T) {
+		mentions := entity.ParseMentions("héllo @" + userID + ", @all and @admins")
+
+		require.Len(t, mentions, 3)
+		assert.Equal(t, entity.Mention{Type: entity.MentionUser, UserID: userID, Offset: 6, Length: 37}, mentions[0])
+		assert.Equal(t, entity.MentionAll, mentions[1].Type)
+		assert.Equal(t, entity.MentionAdmins, mentions[2].Type)
+	})
+
+	t.Run("should ignore e-mail addresses and partial words", func(t *testing.T) {
+		assert.Empty(t, entity.ParseMentions("mail me at someone@all.example or @allison"))
+	})
+}
+
+func TestMessage_ResolveMentions(t *testing.T) {
+	senderID := "01959b38-b3f9-7ec5-8ac8-e353bfe08a2d"
+	memberID := "01959b39-febd-770d-9e1b-e5ee392fce54"
+	adminID := "01959b3a-405b-7591-86dd-87174e2453fd"
+	strangerID := "0195c388-d0f4-77d5-be90-971d38344c74"
+	candidates := []entity.MentionCandidate{
+		{UserID: senderID, IsAdmin: true},
+		{UserID: memberID},
+		{UserID: adminID, IsAdmin: true},
+	}
+
+	t.Run("should only notify mentioned participants", func(t *testing.T) {
+		msg := entity.Message{SenderID: senderID, Content: "@" + memberID + " @" + strangerID}
+
+		recipients := msg.ResolveMentions(candidates, true)
+
+		assert.Equal(t, []entity.MentionRecipient{{UserID: memberID, Type: entity.MentionUser}}, recipients)
+		assert.Len(t, msg.Mentions, 1)
+	})
+
+	t.Run("should expand @admins without notifying the sender", func(t *testing.T) {
+		msg := entity.Message{SenderID: senderID, Content: "ping @admins"}
+
+		recipients := msg.ResolveMentions(candidates, true)
+
+		assert.Equal(t, []entity.MentionRecipient{{UserID: adminID, Type: entity.MentionAdmins}}, recipients)
+	})
+
+	t.Run("should prefer direct mentions over @all", func(t *testing.T) {
+		msg := entity.Message{SenderID: senderID, Content: "@all especially @" + memberID}
+
+		recipients := msg.ResolveMentions(candidates, true)
+
+		assert.ElementsMatch(t, []entity.MentionRecipient{
+			{UserID: memberID, Type: entity.MentionUser},
+			{UserID: adminID, Type: entity.MentionAll},
+		}, recipients)
+	})
+
+	t.Run("should ignore @all outside group chats", func(t *testing.T) {
+		msg := entity.Message{SenderID: senderID, Content: "@all"}
+
+		assert.Empty(t, msg.ResolveMentions(candidates, false))
+		assert.Empty(t, msg.Mentions)
+	})
+}
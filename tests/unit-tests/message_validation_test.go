@@ -0,0 +1,85 @@
+package unit_tests
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	entity "github.com/HappYness-Project/ChatBackendServer/internal/message/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessage_Validate(t *testing.T) {
+	image := entity.Attachment{ID: "a1", FileName: "photo.png", ContentType: "image/png"}
+	pdf := entity.Attachment{ID: "a2", FileName: "doc.pdf", ContentType: "application/pdf"}
+
+	tests := []struct {
+		name    string
+		message entity.Message
+		code    string
+	}{
+		{name: "plain text", message: entity.Message{Content: "  hello  "}},
+		{name: "empty text", message: entity.Message{Content: "   "}, code: "EmptyContent"},
+		{name: "too long", message: entity.Message{Content: strings.Repeat("a", entity.MaxContentLength+1)}, code: "ContentTooLong"},
+		{name: "multibyte at the limit", message: entity.Message{Content: strings.Repeat("é", entity.MaxContentLength)}},
+		{name: "invalid utf-8", message: entity.Message{Content: "bad \xff byte"}, code: "InvalidEncoding"},
+		{name: "nul character", message: entity.Message{Content: "nul \x00 byte"}, code: "InvalidCharacters"},
+		{name: "unknown type", message: entity.Message{Content: "hi", MessageType: "sticker"}, code: "InvalidMessageType"},
+		{name: "image derived from attachments", message: entity.Message{Attachments: []entity.Attachment{image}}},
+		{name: "image without attachments", message: entity.Message{MessageType: entity.TypeImage}, code: "MissingAttachments"},
+		{name: "image with a pdf", message: entity.Message{MessageType: entity.TypeImage, Attachments: []entity.Attachment{pdf}}, code: "AttachmentTypeMismatch"},
+		{name: "text with attachments", message: entity.Message{Content: "hi", MessageType: entity.TypeText, Attachments: []entity.Attachment{pdf}}, code: "UnexpectedAttachments"},
+		{name: "file with mixed attachments", message: entity.Message{Content: "caption", Attachments: []entity.Attachment{image, pdf}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := tt.message
+			msg.Normalize()
+			err := msg.Validate()
+			if tt.code == "" {
+				assert.NoError(t, err)
+				return
+			}
+			var validationErr *entity.ValidationError
+			require.ErrorAs(t, err, &validationErr)
+			assert.Equal(t, tt.code, validationErr.Code)
+		})
+	}
+
+	t.Run("should trim content", func(t *testing.T) {
+		msg := entity.Message{Content: "  hello  "}
+		msg.Normalize()
+		assert.Equal(t, "hello", msg.Content)
+		assert.Equal(t, entity.TypeText, msg.MessageType)
+	})
+}
+
+func TestMessage_ApplyTTL(t *testing.T) {
+	createdAt := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	t.Run("should leave messages without a TTL unexpired", func(t *testing.T) {
+		msg := entity.Message{CreatedAt: createdAt}
+		require.NoError(t, msg.ApplyTTL())
+		assert.Nil(t, msg.ExpiresAt)
+	})
+
+	t.Run("should expire the message after the TTL", func(t *testing.T) {
+		ttl := 60
+		msg := entity.Message{CreatedAt: createdAt, TTLSeconds: &ttl}
+		require.NoError(t, msg.ApplyTTL())
+		require.NotNil(t, msg.ExpiresAt)
+		assert.Equal(t, createdAt.Add(time.Minute), *msg.ExpiresAt)
+	})
+
+	t.Run("should reject a TTL out of range", func(t *testing.T) {
+		for _, ttl := range []int{0, 1, int(entity.MaxMessageTTL.Seconds()) + 1} {
+			msg := entity.Message{CreatedAt: createdAt, TTLSeconds: &ttl}
+			var validationErr *entity.ValidationError
+			require.ErrorAs(t, msg.ApplyTTL(), &validationErr)
+			assert.Equal(t, "InvalidTTL", validationErr.Code)
+			assert.Nil(t, msg.ExpiresAt)
+		}
+	})
+}
@@ -0,0 +1,48 @@
+package unit_tests
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/HappYness-Project/ChatBackendServer/dbs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	t.Run("should order migrations by version", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"migrations/0010_add_index.up.sql":      {Data: []byte("CREATE INDEX a ON b (c);")},
+			"migrations/0002_add_table.up.sql":      {Data: []byte("CREATE TABLE b (c INT);")},
+			"migrations/0002_add_table.down.sql":    {Data: []byte("DROP TABLE b;")},
+			"migrations/README.md":                  {Data: []byte("ignored")},
+			"migrations/0001_initial_schema.up.sql": {Data: []byte("SELECT 1;")},
+		}
+
+		migrations, err := dbs.LoadMigrations(fsys)
+		require.NoError(t, err)
+		require.Len(t, migrations, 3)
+		assert.Equal(t, []int64{1, 2, 10}, []int64{migrations[0].Version, migrations[1].Version, migrations[2].Version})
+		assert.Equal(t, "add_table", migrations[1].Name)
+		assert.Equal(t, "DROP TABLE b;", migrations[1].Down)
+		assert.Empty(t, migrations[2].Down)
+		assert.Len(t, migrations[0].Checksum, 64)
+	})
+
+	t.Run("should require an up file", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"migrations/0001_initial_schema.down.sql": {Data: []byte("SELECT 1;")},
+		}
+		_, err := dbs.LoadMigrations(fsys)
+		assert.Error(t, err)
+	})
+
+	t.Run("should reject versions with different names", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"migrations/0001_initial_schema.up.sql": {Data: []byte("SELECT 1;")},
+			"migrations/0001_other.down.sql":        {Data: []byte("SELECT 1;")},
+		}
+		_, err := dbs.LoadMigrations(fsys)
+		assert.Error(t, err)
+	})
+}
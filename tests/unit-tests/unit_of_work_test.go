@@ -0,0 +1,100 @@
+package unit_tests
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/HappYness-Project/ChatBackendServer/dbs"
+	"github.com/HappYness-Project/ChatBackendServer/internal/chat/domain"
+	chatRepository "github.com/HappYness-Project/ChatBackendServer/internal/chat/repository"
+	entity "github.com/HappYness-Project/ChatBackendServer/internal/message/domain"
+	messageRepository "github.com/HappYness-Project/ChatBackendServer/internal/message/repository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errAbortUnit = errors.New("abort unit")
+
+// testUnitOfWork checks that a chat, its member and a message are stored together or not at all.
+func testUnitOfWork(t *testing.T, unit dbs.UnitOfWork, chats chatRepository.ChatRepository, messages messageRepository.MessageRepository) {
+	userId := uuid.New().String()
+	createChatWithMessage := func(t *testing.T, ctx context.Context, fail bool) (*domain.Chat, entity.Message, error) {
+		chat, err := domain.NewChat(domain.ChatTypePrivate, nil, nil)
+		require.NoError(t, err)
+		participant, err := domain.NewChatParticipant(chat.Id, userId, domain.RoleAdmin, domain.StatusActive)
+		require.NoError(t, err)
+		msg := newMemoryMessage(chat.Id, userId, "welcome", time.Now().UTC())
+
+		err = unit.Do(ctx, func(ctx context.Context) error {
+			if _, err := chats.CreateChatWithParticipant(ctx, chat, participant); err != nil {
+				return err
+			}
+			if err := messages.Create(ctx, msg); err != nil {
+				return err
+			}
+			if fail {
+				return errAbortUnit
+			}
+			return nil
+		})
+		return chat, msg, err
+	}
+
+	t.Run("commits every write", func(t *testing.T) {
+		chat, msg, err := createChatWithMessage(t, t.Context(), false)
+		require.NoError(t, err)
+
+		_, err = chats.GetChatParticipant(t.Context(), chat.Id, userId)
+		require.NoError(t, err)
+		stored, err := messages.GetByID(t.Context(), msg.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "welcome", stored.Content)
+	})
+
+	t.Run("rolls back every write when the unit fails", func(t *testing.T) {
+		chat, msg, err := createChatWithMessage(t, t.Context(), true)
+		require.ErrorIs(t, err, errAbortUnit)
+
+		_, err = chats.GetChatById(t.Context(), chat.Id)
+		assert.ErrorIs(t, err, domain.ErrChatNotFound)
+		_, err = messages.GetByID(t.Context(), msg.ID)
+		assert.ErrorIs(t, err, entity.ErrMessageNotFound)
+	})
+
+	t.Run("nested units join the outer one", func(t *testing.T) {
+		var chat *domain.Chat
+		err := unit.Do(t.Context(), func(ctx context.Context) error {
+			var err error
+			chat, _, err = createChatWithMessage(t, ctx, false)
+			require.NoError(t, err)
+			return errAbortUnit
+		})
+		require.ErrorIs(t, err, errAbortUnit)
+
+		_, err = chats.GetChatById(t.Context(), chat.Id)
+		assert.ErrorIs(t, err, domain.ErrChatNotFound)
+	})
+}
+
+// TestUnitOfWork runs against the in-memory repositories, or against Postgres when TEST_DATABASE_URL is set.
+func TestUnitOfWork(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		chats := chatRepository.NewMemoryRepository()
+		messages := messageRepository.NewMemoryRepository(chats)
+		testUnitOfWork(t, dbs.NewMemoryUnitOfWork(chats, messages), chats, messages)
+		return
+	}
+
+	db, err := dbs.ConnectToDb(dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	migrator, err := dbs.NewMigrator(db)
+	require.NoError(t, err)
+	require.NoError(t, migrator.Up(t.Context()))
+	testUnitOfWork(t, dbs.NewUnitOfWork(db), chatRepository.NewRepository(db), messageRepository.NewRepository(db))
+}